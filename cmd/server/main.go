@@ -2,12 +2,14 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"log"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // schedule timezones, the runtime image has no zoneinfo
 
 	"github.com/eve-an/splitter/internal/cache"
 	"github.com/eve-an/splitter/internal/config"
@@ -17,6 +19,7 @@ import (
 	"github.com/eve-an/splitter/internal/http/handler"
 	"github.com/eve-an/splitter/internal/logger"
 	"github.com/eve-an/splitter/internal/session"
+	"github.com/eve-an/splitter/internal/spool"
 	"github.com/joho/godotenv"
 )
 
@@ -43,17 +46,115 @@ func main() {
 	}()
 
 	featureRepo := feature.NewPostgresFeatureRepository(database.Pool, database.Queries)
-	featureCache := cache.NewMemoryCache[*feature.Feature](time.Minute)
+	featureCache, closeCache, err := newFeatureCache(config.Cache, logger)
+	if err != nil {
+		log.Fatalf("failed to initialize cache: %v", err)
+	}
+
+	defer func() {
+		logger.Info("cache closed", slog.Any("error", closeCache()))
+	}()
+
+	var eventRepo feature.EventRepository = feature.NewPostgresEventRepository(database.Pool, database.Queries)
+
+	closeSpool := func(context.Context) error { return nil }
+	if config.Events.SpoolDir != "" {
+		eventSpool, err := spool.Open(config.Events.SpoolDir)
+		if err != nil {
+			log.Fatalf("failed to open event spool: %v", err)
+		}
+
+		spooledRepo := feature.NewSpooledEventRepository(eventRepo, eventSpool, feature.SpoolOptions{
+			ReplayInterval: config.Events.SpoolReplayInterval,
+		}, logger)
+		expvar.Publish("event_spool", expvar.Func(func() any { return spooledRepo.Stats() }))
+
+		eventRepo = spooledRepo
+		closeSpool = spooledRepo.Close
+	}
+
+	closeEvents := func(context.Context) error { return nil }
+	if config.Events.Async {
+		bufferedRepo, err := feature.NewBufferedEventRepository(eventRepo, feature.BufferedEventOptions{
+			QueueSize:     config.Events.QueueSize,
+			BatchSize:     config.Events.BatchSize,
+			FlushInterval: config.Events.FlushInterval,
+			Backpressure:  feature.BackpressurePolicy(config.Events.Backpressure),
+		}, logger)
+		if err != nil {
+			log.Fatalf("failed to initialize event writer: %v", err)
+		}
+
+		eventRepo = bufferedRepo
+		closeEvents = bufferedRepo.Close
+	}
+
+	metricRepo := feature.NewPostgresMetricRepository(database.Queries)
+	auditRepo := feature.NewPostgresAuditRepository(database.Queries)
+	webhookRepo := feature.NewPostgresWebhookRepository(database.Queries)
+	segmentRepo := feature.NewPostgresSegmentRepository(database.Pool, database.Queries)
+	changeRepo := feature.NewPostgresChangeRepository(database.Pool, database.Queries)
+	notifier := feature.Notifiers{feature.NewLogNotifier(logger), feature.NewWebhookNotifier(webhookRepo)}
+
+	featureSvc := feature.NewService(featureRepo, eventRepo, metricRepo, auditRepo, webhookRepo, segmentRepo, changeRepo, featureCache, notifier, feature.EventValidation{
+		Variant:  feature.VariantValidation(config.Events.VariantValidation),
+		Mismatch: feature.VariantMismatchPolicy(config.Events.VariantMismatch),
+	})
+
+	relay := feature.NewChangeRelay(featureSvc, feature.ChangeRelayOptions{
+		Retention: config.Stream.ChangeRetention,
+	}, logger)
+
+	locker := feature.NewPostgresLocker(database.Pool)
+
+	closeSampleRatio := func(context.Context) error { return nil }
+	if config.Jobs.SampleRatioInterval > 0 {
+		monitor := feature.NewSampleRatioMonitor(featureSvc, locker, feature.SampleRatioMonitorOptions{
+			Interval: config.Jobs.SampleRatioInterval,
+		}, logger)
+		closeSampleRatio = monitor.Close
+	}
+
+	closeBandit := func(context.Context) error { return nil }
+	if config.Jobs.BanditInterval > 0 {
+		allocator := feature.NewBanditAllocator(featureSvc, locker, feature.BanditAllocatorOptions{
+			Interval: config.Jobs.BanditInterval,
+		}, logger)
+		closeBandit = allocator.Close
+	}
+
+	closeGuardrails := func(context.Context) error { return nil }
+	if config.Jobs.GuardrailInterval > 0 {
+		monitor := feature.NewGuardrailMonitor(featureSvc, locker, feature.GuardrailMonitorOptions{
+			Interval: config.Jobs.GuardrailInterval,
+		}, logger)
+		closeGuardrails = monitor.Close
+	}
+
+	closeWebhooks := func(context.Context) error { return nil }
+	if config.Webhooks.Interval > 0 {
+		dispatcher := feature.NewWebhookDispatcher(featureSvc, feature.WebhookDispatcherOptions{
+			Interval: config.Webhooks.Interval,
+		}, logger)
+		closeWebhooks = dispatcher.Close
+	}
 
-	eventRepo := feature.NewPostgresEventRepository(database.Queries)
-	featureSvc := feature.NewService(featureRepo, eventRepo, featureCache)
+	closeScheduler := func(context.Context) error { return nil }
+	if config.Jobs.ScheduleInterval > 0 {
+		scheduler := feature.NewScheduler(featureSvc, locker, feature.SchedulerOptions{
+			Interval: config.Jobs.ScheduleInterval,
+		}, logger)
+		closeScheduler = scheduler.Close
+	}
 
 	featureHandler := handler.NewFeatureHandler(logger, featureSvc)
+	streamHandler := handler.NewStreamHandler(logger, featureSvc, 15*time.Second)
 
 	sessionSvc := session.NewService(time.Hour * 12)
 
-	router := http.NewRouter(logger, featureHandler, sessionSvc, config.DefaultAuth) // TODO: support multi user auth
+	router := http.NewRouter(logger, featureHandler, streamHandler, sessionSvc, config.DefaultAuth) // TODO: support multi user auth
 	server := http.NewServer(config.ServerConifg, logger, router)
+	server.RegisterOnShutdown(streamHandler.Close)
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
@@ -72,5 +173,59 @@ func main() {
 		logger.Info("server shutdown failed", slog.Any("error", err))
 	}
 
+	if err := closeSampleRatio(ctx); err != nil {
+		logger.Error("sample ratio monitor not stopped", slog.Any("error", err))
+	}
+
+	if err := closeBandit(ctx); err != nil {
+		logger.Error("bandit allocator not stopped", slog.Any("error", err))
+	}
+
+	if err := closeGuardrails(ctx); err != nil {
+		logger.Error("guardrail monitor not stopped", slog.Any("error", err))
+	}
+
+	if err := closeScheduler(ctx); err != nil {
+		logger.Error("scheduler not stopped", slog.Any("error", err))
+	}
+
+	if err := closeWebhooks(ctx); err != nil {
+		logger.Error("webhook dispatcher not stopped", slog.Any("error", err))
+	}
+
+	if err := relay.Close(ctx); err != nil {
+		logger.Error("change relay not stopped", slog.Any("error", err))
+	}
+	changeRepo.Close()
+
+	// only drain once no handler can enqueue anymore
+	if err := closeEvents(ctx); err != nil {
+		logger.Error("event queue not drained", slog.Any("error", err))
+	}
+
+	if err := closeSpool(ctx); err != nil {
+		logger.Error("event spool not closed", slog.Any("error", err))
+	}
+
 	logger.Info("server stopped gracefully")
 }
+
+func newFeatureCache(cfg config.Cache, logger *slog.Logger) (cache.Cache[*feature.Feature], func() error, error) {
+	switch cfg.Backend {
+	case config.CacheBackendRESP:
+		client, err := cache.NewRESPClient(cache.RESPOptions{
+			Address:  cfg.Address,
+			Password: cfg.Password,
+			DB:       cfg.DB,
+			PoolSize: cfg.PoolSize,
+			Timeout:  cfg.Timeout,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return cache.NewRESPCache(client, feature.CacheCodec{}, feature.CacheKeyPrefix, logger), client.Close, nil
+	default:
+		return cache.NewMemoryCache[*feature.Feature](time.Minute), func() error { return nil }, nil
+	}
+}
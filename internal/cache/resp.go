@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// RESPCache stores values in a RESP-compatible server so that all replicas
+// share the same cache tier. Cache has no error channel, so backend failures
+// are logged and treated as misses.
+type RESPCache[T any] struct {
+	client  *RESPClient
+	codec   Codec[T]
+	prefix  string
+	timeout time.Duration
+	logger  *slog.Logger
+}
+
+func NewRESPCache[T any](
+	client *RESPClient,
+	codec Codec[T],
+	prefix string,
+	logger *slog.Logger,
+) *RESPCache[T] {
+	return &RESPCache[T]{
+		client:  client,
+		codec:   codec,
+		prefix:  prefix,
+		timeout: client.timeout,
+		logger:  logger,
+	}
+}
+
+// Delete implements Cache.
+func (c *RESPCache[T]) Delete(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := c.client.Del(ctx, c.prefix+key); err != nil {
+		c.logger.Warn("cache delete failed", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+// Get implements Cache.
+func (c *RESPCache[T]) Get(key string) (T, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	data, err := c.client.Get(ctx, c.prefix+key)
+	if err != nil {
+		if !errors.Is(err, ErrNil) {
+			c.logger.Warn("cache get failed", slog.String("key", key), slog.Any("error", err))
+		}
+
+		return *new(T), false
+	}
+
+	value, err := c.codec.Unmarshal(data)
+	if err != nil {
+		c.logger.Warn("cache decode failed", slog.String("key", key), slog.Any("error", err))
+		return *new(T), false
+	}
+
+	return value, true
+}
+
+// Set implements Cache.
+func (c *RESPCache[T]) Set(key string, value T, ttl time.Duration) {
+	data, err := c.codec.Marshal(value)
+	if err != nil {
+		c.logger.Warn("cache encode failed", slog.String("key", key), slog.Any("error", err))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := c.client.Set(ctx, c.prefix+key, data, ttl); err != nil {
+		c.logger.Warn("cache set failed", slog.String("key", key), slog.Any("error", err))
+	}
+}
+
+var _ Cache[any] = &RESPCache[any]{}
@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNil          = errors.New("resp: nil reply")
+	ErrClientClosed = errors.New("resp: client closed")
+)
+
+// RESPError is an error reply sent by the server, e.g. "-ERR unknown command".
+type RESPError string
+
+func (e RESPError) Error() string {
+	return "resp: " + string(e)
+}
+
+// RESPClient is a small pooled client for servers speaking the Redis
+// serialization protocol (RESP2), such as Redis, Valkey or KeyDB.
+type RESPClient struct {
+	address  string
+	password string
+	db       int
+	timeout  time.Duration
+	pool     chan *respConn
+	closed   chan struct{}
+}
+
+type respConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+}
+
+// RESPOptions configure a RESPClient. Zero PoolSize and Timeout default to
+// 10 connections and one second.
+type RESPOptions struct {
+	Address  string
+	Password string
+	DB       int
+	PoolSize int
+	Timeout  time.Duration
+}
+
+func NewRESPClient(opts RESPOptions) (*RESPClient, error) {
+	if opts.Address == "" {
+		return nil, errors.New("resp: address is required")
+	}
+
+	poolSize := opts.PoolSize
+	if poolSize == 0 {
+		poolSize = 10
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+
+	return &RESPClient{
+		address:  opts.Address,
+		password: opts.Password,
+		db:       opts.DB,
+		timeout:  timeout,
+		pool:     make(chan *respConn, poolSize),
+		closed:   make(chan struct{}),
+	}, nil
+}
+
+// Do sends a single command and returns the decoded reply. Replies are
+// decoded as string (simple string), int64 (integer), []byte (bulk string)
+// or []any (array). A nil bulk string or array is reported as ErrNil.
+func (c *RESPClient) Do(ctx context.Context, args ...string) (any, error) {
+	conn, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.do(ctx, c.timeout, args...)
+	if err != nil && !isReplyError(err) {
+		_ = conn.conn.Close()
+		return nil, err
+	}
+
+	c.release(conn)
+
+	return reply, err
+}
+
+func (c *RESPClient) Get(ctx context.Context, key string) ([]byte, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil {
+		return nil, err
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("resp: unexpected GET reply %T", reply)
+	}
+
+	return value, nil
+}
+
+func (c *RESPClient) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := c.Do(ctx, args...)
+	return err
+}
+
+func (c *RESPClient) Del(ctx context.Context, keys ...string) error {
+	_, err := c.Do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+func (c *RESPClient) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+func (c *RESPClient) Close() error {
+	select {
+	case <-c.closed:
+		return nil
+	default:
+		close(c.closed)
+	}
+
+	var errs []error
+	for {
+		select {
+		case conn := <-c.pool:
+			errs = append(errs, conn.conn.Close())
+		default:
+			return errors.Join(errs...)
+		}
+	}
+}
+
+func (c *RESPClient) acquire(ctx context.Context) (*respConn, error) {
+	select {
+	case <-c.closed:
+		return nil, ErrClientClosed
+	case conn := <-c.pool:
+		return conn, nil
+	default:
+	}
+
+	return c.dial(ctx)
+}
+
+func (c *RESPClient) release(conn *respConn) {
+	select {
+	case <-c.closed:
+		_ = conn.conn.Close()
+		return
+	default:
+	}
+
+	select {
+	case c.pool <- conn:
+	default:
+		_ = conn.conn.Close()
+	}
+}
+
+func (c *RESPClient) dial(ctx context.Context) (*respConn, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("resp: dial %s: %w", c.address, err)
+	}
+
+	conn := &respConn{
+		conn: netConn,
+		r:    bufio.NewReader(netConn),
+		w:    bufio.NewWriter(netConn),
+	}
+
+	if c.password != "" {
+		if _, err := conn.do(ctx, c.timeout, "AUTH", c.password); err != nil {
+			_ = netConn.Close()
+			return nil, fmt.Errorf("resp: auth: %w", err)
+		}
+	}
+
+	if c.db != 0 {
+		if _, err := conn.do(ctx, c.timeout, "SELECT", strconv.Itoa(c.db)); err != nil {
+			_ = netConn.Close()
+			return nil, fmt.Errorf("resp: select db %d: %w", c.db, err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (c *respConn) do(ctx context.Context, timeout time.Duration, args ...string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if err := writeCommand(c.w, args); err != nil {
+		return nil, fmt.Errorf("resp: write command: %w", err)
+	}
+
+	return readReply(c.r)
+}
+
+func writeCommand(w *bufio.Writer, args []string) error {
+	if _, err := fmt.Fprintf(w, "*%d\r\n", len(args)); err != nil {
+		return err
+	}
+
+	for _, arg := range args {
+		if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(arg), arg); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func readReply(r *bufio.Reader) (any, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 {
+		return nil, errors.New("resp: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RESPError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("resp: invalid bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("resp: invalid array length: %w", err)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+
+		items := make([]any, n)
+		for i := range items {
+			items[i], err = readReply(r)
+
+			var respErr RESPError
+			switch {
+			case err == nil, errors.Is(err, ErrNil):
+			case errors.As(err, &respErr):
+				items[i] = respErr
+			default:
+				return nil, err
+			}
+		}
+
+		return items, nil
+	default:
+		return nil, fmt.Errorf("resp: unexpected reply type %q", line[0])
+	}
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", errors.New("resp: malformed line")
+	}
+
+	return line[:len(line)-2], nil
+}
+
+// isReplyError reports whether err came from a well-formed server reply, in
+// which case the connection is still usable.
+func isReplyError(err error) bool {
+	var respErr RESPError
+	return errors.Is(err, ErrNil) || errors.As(err, &respErr)
+}
@@ -0,0 +1,169 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/eve-an/splitter/internal/cache"
+	"github.com/eve-an/splitter/internal/cache/resptest"
+)
+
+type cachedValue struct {
+	Name string `json:"name"`
+}
+
+func newServer(t *testing.T, password string) *resptest.Server {
+	t.Helper()
+
+	server, err := resptest.NewServer(password)
+	if err != nil {
+		t.Fatalf("start server: %v", err)
+	}
+	t.Cleanup(func() { _ = server.Close() })
+
+	return server
+}
+
+func newClient(t *testing.T, opts cache.RESPOptions) *cache.RESPClient {
+	t.Helper()
+
+	client, err := cache.NewRESPClient(opts)
+	if err != nil {
+		t.Fatalf("create client: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func newCache(client *cache.RESPClient) *cache.RESPCache[cachedValue] {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return cache.NewRESPCache(client, cache.JSONCodec[cachedValue]{}, "test:", logger)
+}
+
+func TestRESPCacheGetSetDelete(t *testing.T) {
+	server := newServer(t, "")
+	c := newCache(newClient(t, cache.RESPOptions{Address: server.Addr()}))
+
+	if _, ok := c.Get("missing"); ok {
+		t.Fatal("Get of a missing key hit")
+	}
+
+	c.Set("feature", cachedValue{Name: "checkout"}, 0)
+
+	got, ok := c.Get("feature")
+	if !ok {
+		t.Fatal("Get after Set missed")
+	}
+	if got.Name != "checkout" {
+		t.Fatalf("Get = %+v, want name checkout", got)
+	}
+
+	c.Delete("feature")
+
+	if _, ok := c.Get("feature"); ok {
+		t.Fatal("Get after Delete hit")
+	}
+}
+
+func TestRESPCacheTTL(t *testing.T) {
+	server := newServer(t, "")
+	c := newCache(newClient(t, cache.RESPOptions{Address: server.Addr()}))
+
+	c.Set("feature", cachedValue{Name: "checkout"}, 50*time.Millisecond)
+
+	if _, ok := c.Get("feature"); !ok {
+		t.Fatal("Get before expiry missed")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if _, ok := c.Get("feature"); ok {
+		t.Fatal("Get after expiry hit")
+	}
+}
+
+func TestRESPCacheAuth(t *testing.T) {
+	server := newServer(t, "secret")
+
+	c := newCache(newClient(t, cache.RESPOptions{Address: server.Addr(), Password: "secret"}))
+	c.Set("feature", cachedValue{Name: "checkout"}, 0)
+	if _, ok := c.Get("feature"); !ok {
+		t.Fatal("Get with the right password missed")
+	}
+
+	wrong := newClient(t, cache.RESPOptions{Address: server.Addr(), Password: "wrong"})
+	if err := wrong.Ping(context.Background()); err == nil {
+		t.Fatal("Ping with the wrong password succeeded")
+	}
+	if _, ok := newCache(wrong).Get("feature"); ok {
+		t.Fatal("Get with the wrong password hit")
+	}
+
+	anonymous := newClient(t, cache.RESPOptions{Address: server.Addr()})
+	var respErr cache.RESPError
+	if err := anonymous.Ping(context.Background()); !errors.As(err, &respErr) {
+		t.Fatalf("Ping without password = %v, want a RESPError", err)
+	}
+}
+
+func TestRESPCacheSelect(t *testing.T) {
+	server := newServer(t, "")
+	c := newCache(newClient(t, cache.RESPOptions{Address: server.Addr(), DB: 3}))
+
+	c.Set("feature", cachedValue{Name: "checkout"}, 0)
+
+	if n := server.Len(3); n != 1 {
+		t.Fatalf("database 3 holds %d keys, want 1", n)
+	}
+	if n := server.Len(0); n != 0 {
+		t.Fatalf("database 0 holds %d keys, want 0", n)
+	}
+
+	other := newCache(newClient(t, cache.RESPOptions{Address: server.Addr()}))
+	if _, ok := other.Get("feature"); ok {
+		t.Fatal("Get from another database hit")
+	}
+}
+
+func TestRESPClientErrorReply(t *testing.T) {
+	server := newServer(t, "")
+	client := newClient(t, cache.RESPOptions{Address: server.Addr(), PoolSize: 1})
+
+	_, err := client.Do(context.Background(), "NOSUCHCOMMAND")
+	var respErr cache.RESPError
+	if !errors.As(err, &respErr) {
+		t.Fatalf("Do of an unknown command = %v, want a RESPError", err)
+	}
+
+	// an error reply leaves the connection usable
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping after an error reply: %v", err)
+	}
+}
+
+func TestRESPCacheDroppedConnection(t *testing.T) {
+	server := newServer(t, "")
+	c := newCache(newClient(t, cache.RESPOptions{Address: server.Addr(), PoolSize: 1}))
+
+	c.Set("feature", cachedValue{Name: "checkout"}, 0)
+
+	server.CloseClientConnections()
+
+	// the pooled connection is dead, which shows as a miss
+	if _, ok := c.Get("feature"); ok {
+		t.Fatal("Get over a dropped connection hit")
+	}
+
+	got, ok := c.Get("feature")
+	if !ok {
+		t.Fatal("Get after reconnecting missed")
+	}
+	if got.Name != "checkout" {
+		t.Fatalf("Get = %+v, want name checkout", got)
+	}
+}
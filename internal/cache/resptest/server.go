@@ -0,0 +1,395 @@
+// Package resptest provides an in-process stand-in for a RESP server
+// (Redis, Valkey, ...) so the RESP cache can be exercised without external
+// infrastructure, in the spirit of net/http/httptest.
+package resptest
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Server understands the subset of commands used by the splitter RESP
+// client: PING, ECHO, AUTH, SELECT, GET, SET (EX/PX/NX/XX), DEL, EXISTS and
+// FLUSHDB.
+type Server struct {
+	password string
+	listener net.Listener
+	mu       sync.Mutex
+	dbs      map[int]map[string]entry
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer starts a server listening on a random loopback port. When
+// password is non-empty, clients must AUTH before any other command.
+func NewServer(password string) (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("resptest: listen: %w", err)
+	}
+
+	s := &Server{
+		password: password,
+		listener: listener,
+		dbs:      make(map[int]map[string]entry),
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	return s, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Len returns the number of live keys in the given database.
+func (s *Server) Len(db int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	now := time.Now()
+	for _, e := range s.dbs[db] {
+		if e.expiresAt.IsZero() || now.Before(e.expiresAt) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// CloseClientConnections drops every open client connection, as a server
+// restart or a network failure would.
+func (s *Server) CloseClientConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+type session struct {
+	db     int
+	authed bool
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	sess := &session{authed: s.password == ""}
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		s.exec(w, sess, args)
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) exec(w *bufio.Writer, sess *session, args []string) {
+	if len(args) == 0 {
+		writeError(w, "ERR empty command")
+		return
+	}
+
+	cmd := strings.ToUpper(args[0])
+	if !sess.authed && cmd != "AUTH" {
+		writeError(w, "NOAUTH Authentication required.")
+		return
+	}
+
+	switch cmd {
+	case "PING":
+		writeSimple(w, "PONG")
+	case "ECHO":
+		if len(args) != 2 {
+			writeArity(w, cmd)
+			return
+		}
+		writeBulk(w, &args[1])
+	case "AUTH":
+		if len(args) != 2 {
+			writeArity(w, cmd)
+			return
+		}
+		if args[1] != s.password {
+			writeError(w, "WRONGPASS invalid password")
+			return
+		}
+		sess.authed = true
+		writeSimple(w, "OK")
+	case "SELECT":
+		if len(args) != 2 {
+			writeArity(w, cmd)
+			return
+		}
+		db, err := strconv.Atoi(args[1])
+		if err != nil || db < 0 {
+			writeError(w, "ERR DB index is out of range")
+			return
+		}
+		sess.db = db
+		writeSimple(w, "OK")
+	case "GET":
+		if len(args) != 2 {
+			writeArity(w, cmd)
+			return
+		}
+		value, ok := s.get(sess.db, args[1])
+		if !ok {
+			writeBulk(w, nil)
+			return
+		}
+		writeBulk(w, &value)
+	case "SET":
+		s.set(w, sess.db, args)
+	case "DEL", "EXISTS":
+		if len(args) < 2 {
+			writeArity(w, cmd)
+			return
+		}
+		writeInt(w, s.count(sess.db, args[1:], cmd == "DEL"))
+	case "FLUSHDB":
+		s.mu.Lock()
+		delete(s.dbs, sess.db)
+		s.mu.Unlock()
+		writeSimple(w, "OK")
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+}
+
+func (s *Server) get(db int, key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.dbs[db][key]
+	if !ok {
+		return "", false
+	}
+
+	if !e.expiresAt.IsZero() && !time.Now().Before(e.expiresAt) {
+		delete(s.dbs[db], key)
+		return "", false
+	}
+
+	return e.value, true
+}
+
+func (s *Server) set(w *bufio.Writer, db int, args []string) {
+	if len(args) < 3 {
+		writeArity(w, "SET")
+		return
+	}
+
+	key, value := args[1], args[2]
+	var expiresAt time.Time
+	var nx, xx bool
+
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 >= len(args) {
+				writeError(w, "ERR syntax error")
+				return
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || n <= 0 {
+				writeError(w, "ERR invalid expire time in 'set' command")
+				return
+			}
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+			expiresAt = time.Now().Add(time.Duration(n) * unit)
+		default:
+			writeError(w, "ERR syntax error")
+			return
+		}
+	}
+
+	_, exists := s.get(db, key)
+	if (nx && exists) || (xx && !exists) {
+		writeBulk(w, nil)
+		return
+	}
+
+	s.mu.Lock()
+	if s.dbs[db] == nil {
+		s.dbs[db] = make(map[string]entry)
+	}
+	s.dbs[db][key] = entry{value: value, expiresAt: expiresAt}
+	s.mu.Unlock()
+
+	writeSimple(w, "OK")
+}
+
+func (s *Server) count(db int, keys []string, del bool) int64 {
+	var n int64
+	for _, key := range keys {
+		if _, ok := s.get(db, key); !ok {
+			continue
+		}
+
+		n++
+		if del {
+			s.mu.Lock()
+			delete(s.dbs[db], key)
+			s.mu.Unlock()
+		}
+	}
+
+	return n
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(line, "*") {
+		// inline command, e.g. from telnet
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, errors.New("resptest: invalid array header")
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasPrefix(header, "$") {
+			return nil, errors.New("resptest: expected bulk string")
+		}
+
+		size, err := strconv.Atoi(header[1:])
+		if err != nil || size < 0 {
+			return nil, errors.New("resptest: invalid bulk length")
+		}
+
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+
+		args[i] = string(buf[:size])
+	}
+
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func writeSimple(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "+%s\r\n", s) // nolint: errcheck
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	fmt.Fprintf(w, "-%s\r\n", msg) // nolint: errcheck
+}
+
+func writeArity(w *bufio.Writer, cmd string) {
+	writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd)))
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n) // nolint: errcheck
+}
+
+func writeBulk(w *bufio.Writer, s *string) {
+	if s == nil {
+		w.WriteString("$-1\r\n") // nolint: errcheck
+		return
+	}
+
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(*s), *s) // nolint: errcheck
+}
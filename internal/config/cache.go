@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRESP   = "resp"
+)
+
+type Cache struct {
+	Backend  string
+	Address  string
+	Password string
+	DB       int
+	PoolSize int
+	Timeout  time.Duration
+}
+
+func (c Cache) Validate() error {
+	switch c.Backend {
+	case CacheBackendMemory:
+		return nil
+	case CacheBackendRESP:
+	default:
+		return fmt.Errorf("cache: unknown backend %q", c.Backend)
+	}
+
+	var errs []error
+	if c.Address == "" {
+		errs = append(errs, errors.New("cache: address is required"))
+	}
+	if c.DB < 0 {
+		errs = append(errs, errors.New("cache: db cannot be negative"))
+	}
+	if c.PoolSize < 0 {
+		errs = append(errs, errors.New("cache: pool size cannot be negative"))
+	}
+	if c.Timeout < 0 {
+		errs = append(errs, errors.New("cache: timeout cannot be negative"))
+	}
+
+	return errors.Join(errs...)
+}
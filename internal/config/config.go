@@ -2,7 +2,9 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -35,6 +37,11 @@ type Config struct {
 	LogLevel     string
 	ServerConifg Server
 	Database     DatabaseConfig
+	Cache        Cache
+	Events       Events
+	Jobs         Jobs
+	Webhooks     Webhooks
+	Stream       Stream
 	DefaultAuth  Auth
 }
 
@@ -47,6 +54,11 @@ func (c Config) Validate() error {
 
 	errs = append(errs, c.ServerConifg.Validate())
 	errs = append(errs, c.Database.Validate())
+	errs = append(errs, c.Cache.Validate())
+	errs = append(errs, c.Events.Validate())
+	errs = append(errs, c.Jobs.Validate())
+	errs = append(errs, c.Webhooks.Validate())
+	errs = append(errs, c.Stream.Validate())
 	errs = append(errs, c.DefaultAuth.Validate())
 
 	return errors.Join(errs...)
@@ -61,6 +73,27 @@ func Load() (c Config, err error) {
 	c.Database.MaxIdleConns = 5
 	c.Database.ConnMaxLifetime = time.Minute * 5
 
+	c.Cache.Backend = CacheBackendMemory
+	c.Cache.PoolSize = 10
+	c.Cache.Timeout = time.Second
+
+	c.Events.QueueSize = 10000
+	c.Events.BatchSize = 500
+	c.Events.FlushInterval = time.Second
+	c.Events.Backpressure = "block"
+	c.Events.SpoolReplayInterval = 5 * time.Second
+	c.Events.VariantValidation = "off"
+	c.Events.VariantMismatch = "reject"
+
+	c.Jobs.SampleRatioInterval = 10 * time.Minute
+	c.Jobs.BanditInterval = time.Hour
+	c.Jobs.GuardrailInterval = 5 * time.Minute
+	c.Jobs.ScheduleInterval = 30 * time.Second
+
+	c.Webhooks.Interval = 5 * time.Second
+
+	c.Stream.ChangeRetention = 24 * time.Hour
+
 	if addr := os.Getenv("SPLITTER_ADDR"); addr != "" {
 		c.ServerConifg.Address = addr
 	}
@@ -77,5 +110,110 @@ func Load() (c Config, err error) {
 		c.DefaultAuth.Password = dbURL
 	}
 
+	if backend := os.Getenv("SPLITTER_CACHE_BACKEND"); backend != "" {
+		c.Cache.Backend = backend
+	}
+
+	if addr := os.Getenv("SPLITTER_CACHE_ADDR"); addr != "" {
+		c.Cache.Address = addr
+	}
+
+	if password := os.Getenv("SPLITTER_CACHE_PASSWORD"); password != "" {
+		c.Cache.Password = password
+	}
+
+	if db := os.Getenv("SPLITTER_CACHE_DB"); db != "" {
+		c.Cache.DB, err = strconv.Atoi(db)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_CACHE_DB: %w", err)
+		}
+	}
+
+	if async := os.Getenv("SPLITTER_EVENTS_ASYNC"); async != "" {
+		c.Events.Async, err = strconv.ParseBool(async)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_EVENTS_ASYNC: %w", err)
+		}
+	}
+
+	if queueSize := os.Getenv("SPLITTER_EVENTS_QUEUE_SIZE"); queueSize != "" {
+		c.Events.QueueSize, err = strconv.Atoi(queueSize)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_EVENTS_QUEUE_SIZE: %w", err)
+		}
+	}
+
+	if batchSize := os.Getenv("SPLITTER_EVENTS_BATCH_SIZE"); batchSize != "" {
+		c.Events.BatchSize, err = strconv.Atoi(batchSize)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_EVENTS_BATCH_SIZE: %w", err)
+		}
+	}
+
+	if interval := os.Getenv("SPLITTER_EVENTS_FLUSH_INTERVAL"); interval != "" {
+		c.Events.FlushInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_EVENTS_FLUSH_INTERVAL: %w", err)
+		}
+	}
+
+	if backpressure := os.Getenv("SPLITTER_EVENTS_BACKPRESSURE"); backpressure != "" {
+		c.Events.Backpressure = backpressure
+	}
+
+	if spoolDir := os.Getenv("SPLITTER_EVENTS_SPOOL_DIR"); spoolDir != "" {
+		c.Events.SpoolDir = spoolDir
+	}
+
+	if validation := os.Getenv("SPLITTER_EVENTS_VARIANT_VALIDATION"); validation != "" {
+		c.Events.VariantValidation = validation
+	}
+
+	if mismatch := os.Getenv("SPLITTER_EVENTS_VARIANT_MISMATCH"); mismatch != "" {
+		c.Events.VariantMismatch = mismatch
+	}
+
+	if interval := os.Getenv("SPLITTER_JOBS_SAMPLE_RATIO_INTERVAL"); interval != "" {
+		c.Jobs.SampleRatioInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_JOBS_SAMPLE_RATIO_INTERVAL: %w", err)
+		}
+	}
+
+	if interval := os.Getenv("SPLITTER_JOBS_BANDIT_INTERVAL"); interval != "" {
+		c.Jobs.BanditInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_JOBS_BANDIT_INTERVAL: %w", err)
+		}
+	}
+
+	if interval := os.Getenv("SPLITTER_JOBS_GUARDRAIL_INTERVAL"); interval != "" {
+		c.Jobs.GuardrailInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_JOBS_GUARDRAIL_INTERVAL: %w", err)
+		}
+	}
+
+	if interval := os.Getenv("SPLITTER_WEBHOOKS_INTERVAL"); interval != "" {
+		c.Webhooks.Interval, err = time.ParseDuration(interval)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_WEBHOOKS_INTERVAL: %w", err)
+		}
+	}
+
+	if interval := os.Getenv("SPLITTER_JOBS_SCHEDULE_INTERVAL"); interval != "" {
+		c.Jobs.ScheduleInterval, err = time.ParseDuration(interval)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_JOBS_SCHEDULE_INTERVAL: %w", err)
+		}
+	}
+
+	if retention := os.Getenv("SPLITTER_STREAM_CHANGE_RETENTION"); retention != "" {
+		c.Stream.ChangeRetention, err = time.ParseDuration(retention)
+		if err != nil {
+			return c, fmt.Errorf("parse SPLITTER_STREAM_CHANGE_RETENTION: %w", err)
+		}
+	}
+
 	return c, c.Validate()
 }
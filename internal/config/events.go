@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Events struct {
+	// Async queues events in memory and writes them in batches instead of
+	// inserting them within the request.
+	Async         bool
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+	// Backpressure is one of "block", "drop-oldest" or "reject".
+	Backpressure string
+	// SpoolDir enables the on-disk spool for events that cannot be written
+	// while the database is unavailable.
+	SpoolDir            string
+	SpoolReplayInterval time.Duration
+	// VariantValidation is one of "off", "exists" or "assignment".
+	VariantValidation string
+	// VariantMismatch is one of "reject" or "flag".
+	VariantMismatch string
+}
+
+func (e Events) Validate() error {
+	var errs []error
+	if e.SpoolDir != "" && e.SpoolReplayInterval <= 0 {
+		errs = append(errs, errors.New("events: spool replay interval must be positive"))
+	}
+
+	switch e.VariantValidation {
+	case "off", "exists", "assignment":
+	default:
+		errs = append(errs, fmt.Errorf("events: unknown variant validation %q", e.VariantValidation))
+	}
+
+	switch e.VariantMismatch {
+	case "reject", "flag":
+	default:
+		errs = append(errs, fmt.Errorf("events: unknown variant mismatch policy %q", e.VariantMismatch))
+	}
+
+	if !e.Async {
+		return errors.Join(errs...)
+	}
+
+	if e.QueueSize <= 0 {
+		errs = append(errs, errors.New("events: queue size must be positive"))
+	}
+	if e.BatchSize <= 0 {
+		errs = append(errs, errors.New("events: batch size must be positive"))
+	}
+	if e.FlushInterval <= 0 {
+		errs = append(errs, errors.New("events: flush interval must be positive"))
+	}
+
+	switch e.Backpressure {
+	case "block", "drop-oldest", "reject":
+	default:
+		errs = append(errs, fmt.Errorf("events: unknown backpressure policy %q", e.Backpressure))
+	}
+
+	return errors.Join(errs...)
+}
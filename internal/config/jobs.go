@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// Jobs configures the background jobs running on every replica. Each of them
+// holds a lock while it runs, so only one replica does the work at a time.
+type Jobs struct {
+	// SampleRatioInterval is the pause between sample ratio mismatch checks
+	// of all active features. Zero disables the checks.
+	SampleRatioInterval time.Duration
+	// BanditInterval is the pause between reallocations of the weights of
+	// bandit features. Zero disables them.
+	BanditInterval time.Duration
+	// GuardrailInterval is the pause between guardrail evaluations of all
+	// active features. Zero disables them.
+	GuardrailInterval time.Duration
+	// ScheduleInterval is the pause between runs applying due feature
+	// schedules. Zero disables the scheduler.
+	ScheduleInterval time.Duration
+}
+
+func (j Jobs) Validate() error {
+	var errs []error
+	if j.SampleRatioInterval < 0 {
+		errs = append(errs, errors.New("jobs: sample ratio interval cannot be negative"))
+	}
+	if j.BanditInterval < 0 {
+		errs = append(errs, errors.New("jobs: bandit interval cannot be negative"))
+	}
+	if j.GuardrailInterval < 0 {
+		errs = append(errs, errors.New("jobs: guardrail interval cannot be negative"))
+	}
+	if j.ScheduleInterval < 0 {
+		errs = append(errs, errors.New("jobs: schedule interval cannot be negative"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+type Stream struct {
+	// ChangeRetention is how long feature changes are kept so that streams
+	// can resume from them.
+	ChangeRetention time.Duration
+}
+
+func (s Stream) Validate() error {
+	if s.ChangeRetention <= 0 {
+		return errors.New("stream: change retention must be positive")
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+type Webhooks struct {
+	// Interval is the pause between sending due webhook deliveries. Zero
+	// disables webhook deliveries.
+	Interval time.Duration
+}
+
+func (w Webhooks) Validate() error {
+	if w.Interval < 0 {
+		return errors.New("webhooks: interval cannot be negative")
+	}
+
+	return nil
+}
@@ -11,17 +11,387 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const aggregateMetric = `-- name: AggregateMetric :many
+WITH exposed AS (
+  SELECT DISTINCT ON (user_id) user_id, variant, created_at AS exposed_at
+  FROM events
+  WHERE feature_id = $1
+    AND event_type = $2
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+),
+per_user AS (
+  SELECT x.variant, e.user_id, count(*) AS events, sum(e.value) AS total, avg(e.value) AS average
+  FROM events e
+  JOIN exposed x ON x.user_id = e.user_id
+  WHERE e.feature_id = $1
+    AND e.event_type = $3
+    AND e.created_at >= x.exposed_at
+    AND ($4::bigint IS NULL OR e.created_at < x.exposed_at + $4 * interval '1 second')
+  GROUP BY x.variant, e.user_id
+)
+SELECT
+  variant,
+  count(*) AS users,
+  sum(events)::float8 AS count_sum,
+  sum(events * events)::float8 AS count_sum_squares,
+  count(total) AS value_users,
+  COALESCE(sum(total), 0)::float8 AS value_sum,
+  COALESCE(sum(total * total), 0)::float8 AS value_sum_squares,
+  COALESCE(sum(average), 0)::float8 AS mean_sum,
+  COALESCE(sum(average * average), 0)::float8 AS mean_sum_squares
+FROM per_user
+GROUP BY variant
+ORDER BY variant
+`
+
+type AggregateMetricParams struct {
+	FeatureID     pgtype.Int4
+	ExposureType  pgtype.Text
+	EventType     pgtype.Text
+	WindowSeconds pgtype.Int8
+}
+
+type AggregateMetricRow struct {
+	Variant         pgtype.Text
+	Users           int64
+	CountSum        float64
+	CountSumSquares float64
+	ValueUsers      int64
+	ValueSum        float64
+	ValueSumSquares float64
+	MeanSum         float64
+	MeanSumSquares  float64
+}
+
+func (q *Queries) AggregateMetric(ctx context.Context, arg AggregateMetricParams) ([]AggregateMetricRow, error) {
+	rows, err := q.db.Query(ctx, aggregateMetric,
+		arg.FeatureID,
+		arg.ExposureType,
+		arg.EventType,
+		arg.WindowSeconds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateMetricRow
+	for rows.Next() {
+		var i AggregateMetricRow
+		if err := rows.Scan(
+			&i.Variant,
+			&i.Users,
+			&i.CountSum,
+			&i.CountSumSquares,
+			&i.ValueUsers,
+			&i.ValueSum,
+			&i.ValueSumSquares,
+			&i.MeanSum,
+			&i.MeanSumSquares,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const aggregateMetricCovariates = `-- name: AggregateMetricCovariates :many
+WITH exposed AS (
+  SELECT DISTINCT ON (user_id) user_id, variant, created_at AS exposed_at
+  FROM events
+  WHERE feature_id = $1
+    AND event_type = $2
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+),
+per_user AS (
+  SELECT
+    x.variant,
+    count(e.id) FILTER (WHERE e.created_at >= x.exposed_at AND ($3::bigint IS NULL OR e.created_at < x.exposed_at + $3 * interval '1 second')) AS post_events,
+    COALESCE(sum(e.value) FILTER (WHERE e.created_at >= x.exposed_at AND ($3::bigint IS NULL OR e.created_at < x.exposed_at + $3 * interval '1 second')), 0) AS post_total,
+    count(e.id) FILTER (WHERE e.created_at < x.exposed_at AND e.created_at >= x.exposed_at - $4::bigint * interval '1 second') AS pre_events,
+    COALESCE(sum(e.value) FILTER (WHERE e.created_at < x.exposed_at AND e.created_at >= x.exposed_at - $4::bigint * interval '1 second'), 0) AS pre_total
+  FROM exposed x
+  LEFT JOIN events e
+    ON e.user_id = x.user_id
+    AND e.feature_id = $1
+    AND e.event_type = $5
+  GROUP BY x.variant, x.user_id
+),
+observed AS (
+  SELECT
+    variant,
+    CASE $6::text
+      WHEN 'unique' THEN (pre_events > 0)::int::float8
+      WHEN 'count' THEN pre_events::float8
+      ELSE pre_total::float8
+    END AS covariate,
+    CASE $6::text
+      WHEN 'unique' THEN (post_events > 0)::int::float8
+      WHEN 'count' THEN post_events::float8
+      ELSE post_total::float8
+    END AS metric
+  FROM per_user
+)
+SELECT
+  variant,
+  count(*) AS users,
+  sum(covariate)::float8 AS covariate_sum,
+  sum(covariate * covariate)::float8 AS covariate_sum_squares,
+  sum(metric)::float8 AS metric_sum,
+  sum(metric * metric)::float8 AS metric_sum_squares,
+  sum(covariate * metric)::float8 AS cross_sum
+FROM observed
+GROUP BY variant
+ORDER BY variant
+`
+
+type AggregateMetricCovariatesParams struct {
+	FeatureID       pgtype.Int4
+	ExposureType    pgtype.Text
+	WindowSeconds   pgtype.Int8
+	LookbackSeconds int64
+	EventType       pgtype.Text
+	Aggregation     string
+}
+
+type AggregateMetricCovariatesRow struct {
+	Variant             pgtype.Text
+	Users               int64
+	CovariateSum        float64
+	CovariateSumSquares float64
+	MetricSum           float64
+	MetricSumSquares    float64
+	CrossSum            float64
+}
+
+// Pairs the metric value of every exposed user, including users without
+// events, with the same value over the lookback before their exposure.
+func (q *Queries) AggregateMetricCovariates(ctx context.Context, arg AggregateMetricCovariatesParams) ([]AggregateMetricCovariatesRow, error) {
+	rows, err := q.db.Query(ctx, aggregateMetricCovariates,
+		arg.FeatureID,
+		arg.ExposureType,
+		arg.WindowSeconds,
+		arg.LookbackSeconds,
+		arg.EventType,
+		arg.Aggregation,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateMetricCovariatesRow
+	for rows.Next() {
+		var i AggregateMetricCovariatesRow
+		if err := rows.Scan(
+			&i.Variant,
+			&i.Users,
+			&i.CovariateSum,
+			&i.CovariateSumSquares,
+			&i.MetricSum,
+			&i.MetricSumSquares,
+			&i.CrossSum,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const aggregateMetrics = `-- name: AggregateMetrics :many
+WITH exposed AS (
+  SELECT DISTINCT ON (user_id) user_id, variant, created_at AS exposed_at
+  FROM events
+  WHERE feature_id = $1
+    AND event_type = $2
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+),
+per_user AS (
+  SELECT x.variant, e.event_type, e.user_id, count(*) AS events, sum(e.value) AS total, avg(e.value) AS average
+  FROM events e
+  JOIN exposed x ON x.user_id = e.user_id
+  WHERE e.feature_id = $1
+    AND e.event_type <> $2
+    AND e.created_at >= x.exposed_at
+  GROUP BY x.variant, e.event_type, e.user_id
+)
+SELECT
+  variant,
+  event_type,
+  count(*) AS users,
+  sum(events)::float8 AS count_sum,
+  sum(events * events)::float8 AS count_sum_squares,
+  count(total) AS value_users,
+  COALESCE(sum(total), 0)::float8 AS value_sum,
+  COALESCE(sum(total * total), 0)::float8 AS value_sum_squares,
+  COALESCE(sum(average), 0)::float8 AS mean_sum,
+  COALESCE(sum(average * average), 0)::float8 AS mean_sum_squares
+FROM per_user
+GROUP BY variant, event_type
+ORDER BY event_type, variant
+`
+
+type AggregateMetricsParams struct {
+	FeatureID    pgtype.Int4
+	ExposureType pgtype.Text
+}
+
+type AggregateMetricsRow struct {
+	Variant         pgtype.Text
+	EventType       pgtype.Text
+	Users           int64
+	CountSum        float64
+	CountSumSquares float64
+	ValueUsers      int64
+	ValueSum        float64
+	ValueSumSquares float64
+	MeanSum         float64
+	MeanSumSquares  float64
+}
+
+func (q *Queries) AggregateMetrics(ctx context.Context, arg AggregateMetricsParams) ([]AggregateMetricsRow, error) {
+	rows, err := q.db.Query(ctx, aggregateMetrics, arg.FeatureID, arg.ExposureType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []AggregateMetricsRow
+	for rows.Next() {
+		var i AggregateMetricsRow
+		if err := rows.Scan(
+			&i.Variant,
+			&i.EventType,
+			&i.Users,
+			&i.CountSum,
+			&i.CountSumSquares,
+			&i.ValueUsers,
+			&i.ValueSum,
+			&i.ValueSumSquares,
+			&i.MeanSum,
+			&i.MeanSumSquares,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countExposures = `-- name: CountExposures :many
+SELECT variant, count(*) AS users
+FROM (
+  SELECT DISTINCT ON (user_id) user_id, variant
+  FROM events
+  WHERE feature_id = $1
+    AND event_type = $2
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+) exposed
+GROUP BY variant
+ORDER BY variant
+`
+
+type CountExposuresParams struct {
+	FeatureID    pgtype.Int4
+	ExposureType pgtype.Text
+}
+
+type CountExposuresRow struct {
+	Variant pgtype.Text
+	Users   int64
+}
+
+func (q *Queries) CountExposures(ctx context.Context, arg CountExposuresParams) ([]CountExposuresRow, error) {
+	rows, err := q.db.Query(ctx, countExposures, arg.FeatureID, arg.ExposureType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountExposuresRow
+	for rows.Next() {
+		var i CountExposuresRow
+		if err := rows.Scan(&i.Variant, &i.Users); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getEventByClientID = `-- name: GetEventByClientID :one
+SELECT
+  id,
+  feature_id,
+  user_id,
+  variant,
+  event_type,
+  created_at,
+  client_event_id,
+  value,
+  properties,
+  variant_mismatch
+FROM events
+WHERE feature_id = $1 AND client_event_id = $2
+`
+
+type GetEventByClientIDParams struct {
+	FeatureID     pgtype.Int4
+	ClientEventID pgtype.Text
+}
+
+func (q *Queries) GetEventByClientID(ctx context.Context, arg GetEventByClientIDParams) (Event, error) {
+	row := q.db.QueryRow(ctx, getEventByClientID, arg.FeatureID, arg.ClientEventID)
+	var i Event
+	err := row.Scan(
+		&i.ID,
+		&i.FeatureID,
+		&i.UserID,
+		&i.Variant,
+		&i.EventType,
+		&i.CreatedAt,
+		&i.ClientEventID,
+		&i.Value,
+		&i.Properties,
+		&i.VariantMismatch,
+	)
+	return i, err
+}
+
 const insertEvent = `-- name: InsertEvent :one
-INSERT INTO events (feature_id, user_id, variant, event_type)
-VALUES ($1, $2, $3, $4)
-RETURNING id, feature_id, user_id, variant, event_type, created_at
+INSERT INTO events (feature_id, user_id, variant, event_type, client_event_id, value, properties, variant_mismatch)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (feature_id, client_event_id) WHERE client_event_id IS NOT NULL DO NOTHING
+RETURNING id, feature_id, user_id, variant, event_type, created_at, client_event_id, value, properties, variant_mismatch
 `
 
 type InsertEventParams struct {
-	FeatureID pgtype.Int4
-	UserID    pgtype.Text
-	Variant   pgtype.Text
-	EventType pgtype.Text
+	FeatureID       pgtype.Int4
+	UserID          pgtype.Text
+	Variant         pgtype.Text
+	EventType       pgtype.Text
+	ClientEventID   pgtype.Text
+	Value           pgtype.Float8
+	Properties      []byte
+	VariantMismatch bool
 }
 
 func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (Event, error) {
@@ -30,6 +400,10 @@ func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (Event
 		arg.UserID,
 		arg.Variant,
 		arg.EventType,
+		arg.ClientEventID,
+		arg.Value,
+		arg.Properties,
+		arg.VariantMismatch,
 	)
 	var i Event
 	err := row.Scan(
@@ -39,25 +413,119 @@ func (q *Queries) InsertEvent(ctx context.Context, arg InsertEventParams) (Event
 		&i.Variant,
 		&i.EventType,
 		&i.CreatedAt,
+		&i.ClientEventID,
+		&i.Value,
+		&i.Properties,
+		&i.VariantMismatch,
 	)
 	return i, err
 }
 
-const listEventsByFeatureID = `-- name: ListEventsByFeatureID :many
+const listEvents = `-- name: ListEvents :many
 SELECT
   id,
   feature_id,
   user_id,
   variant,
   event_type,
-  created_at
+  created_at,
+  client_event_id,
+  value,
+  properties,
+  variant_mismatch
 FROM events
 WHERE feature_id = $1
-ORDER BY created_at DESC
+  AND ($2::timestamptz IS NULL OR created_at >= $2)
+  AND ($3::timestamptz IS NULL OR created_at < $3)
+  AND ($4::text IS NULL OR event_type = $4)
+  AND ($5::text IS NULL OR variant = $5)
+  AND ($6::text IS NULL OR user_id = $6)
+  AND (
+    $7::timestamptz IS NULL
+    OR (created_at, id) < ($7, $8::bigint)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT $9
 `
 
-func (q *Queries) ListEventsByFeatureID(ctx context.Context, featureID pgtype.Int4) ([]Event, error) {
-	rows, err := q.db.Query(ctx, listEventsByFeatureID, featureID)
+type ListEventsParams struct {
+	FeatureID       pgtype.Int4
+	CreatedFrom     pgtype.Timestamptz
+	CreatedTo       pgtype.Timestamptz
+	EventType       pgtype.Text
+	Variant         pgtype.Text
+	UserID          pgtype.Text
+	BeforeCreatedAt pgtype.Timestamptz
+	BeforeID        pgtype.Int8
+	PageSize        pgtype.Int4
+}
+
+func (q *Queries) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event, error) {
+	rows, err := q.db.Query(ctx, listEvents,
+		arg.FeatureID,
+		arg.CreatedFrom,
+		arg.CreatedTo,
+		arg.EventType,
+		arg.Variant,
+		arg.UserID,
+		arg.BeforeCreatedAt,
+		arg.BeforeID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Event
+	for rows.Next() {
+		var i Event
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeatureID,
+			&i.UserID,
+			&i.Variant,
+			&i.EventType,
+			&i.CreatedAt,
+			&i.ClientEventID,
+			&i.Value,
+			&i.Properties,
+			&i.VariantMismatch,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEventsByClientIDs = `-- name: ListEventsByClientIDs :many
+SELECT
+  id,
+  feature_id,
+  user_id,
+  variant,
+  event_type,
+  created_at,
+  client_event_id,
+  value,
+  properties,
+  variant_mismatch
+FROM events
+WHERE (feature_id, client_event_id) IN (
+  SELECT * FROM unnest($1::int[], $2::text[])
+)
+`
+
+type ListEventsByClientIDsParams struct {
+	FeatureIds     []int32
+	ClientEventIds []string
+}
+
+func (q *Queries) ListEventsByClientIDs(ctx context.Context, arg ListEventsByClientIDsParams) ([]Event, error) {
+	rows, err := q.db.Query(ctx, listEventsByClientIDs, arg.FeatureIds, arg.ClientEventIds)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +540,10 @@ func (q *Queries) ListEventsByFeatureID(ctx context.Context, featureID pgtype.In
 			&i.Variant,
 			&i.EventType,
 			&i.CreatedAt,
+			&i.ClientEventID,
+			&i.Value,
+			&i.Properties,
+			&i.VariantMismatch,
 		); err != nil {
 			return nil, err
 		}
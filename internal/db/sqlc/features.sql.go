@@ -11,6 +11,26 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const bumpFeatureVersion = `-- name: BumpFeatureVersion :one
+UPDATE features
+SET version = version + 1
+WHERE id = $1 AND version = $2
+RETURNING version
+`
+
+type BumpFeatureVersionParams struct {
+	ID      int32
+	Version int32
+}
+
+// Fails with no rows if the feature has been changed since version.
+func (q *Queries) BumpFeatureVersion(ctx context.Context, arg BumpFeatureVersionParams) (int32, error) {
+	row := q.db.QueryRow(ctx, bumpFeatureVersion, arg.ID, arg.Version)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
 const deleteFeature = `-- name: DeleteFeature :exec
 DELETE FROM features WHERE id = $1
 `
@@ -20,6 +40,41 @@ func (q *Queries) DeleteFeature(ctx context.Context, id int32) error {
 	return err
 }
 
+const deleteOverride = `-- name: DeleteOverride :execrows
+DELETE FROM feature_overrides WHERE feature_id = $1 AND user_key = $2
+`
+
+type DeleteOverrideParams struct {
+	FeatureID int32
+	UserKey   string
+}
+
+func (q *Queries) DeleteOverride(ctx context.Context, arg DeleteOverrideParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOverride, arg.FeatureID, arg.UserKey)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deletePrerequisitesByFeature = `-- name: DeletePrerequisitesByFeature :exec
+DELETE FROM feature_prerequisites WHERE feature_id = $1
+`
+
+func (q *Queries) DeletePrerequisitesByFeature(ctx context.Context, featureID int32) error {
+	_, err := q.db.Exec(ctx, deletePrerequisitesByFeature, featureID)
+	return err
+}
+
+const deleteRulesByFeature = `-- name: DeleteRulesByFeature :exec
+DELETE FROM feature_rules WHERE feature_id = $1
+`
+
+func (q *Queries) DeleteRulesByFeature(ctx context.Context, featureID int32) error {
+	_, err := q.db.Exec(ctx, deleteRulesByFeature, featureID)
+	return err
+}
+
 const deleteVariantsByFeature = `-- name: DeleteVariantsByFeature :exec
 DELETE FROM variants WHERE feature_id = $1
 `
@@ -35,24 +90,45 @@ SELECT
   f.name AS feature_name,
   f.description AS feature_description,
   f.active AS feature_active,
+  f.version AS feature_version,
+  f.tags AS feature_tags,
   f.created_at AS feature_created_at,
+  f.sample_ratio_mismatch AS feature_sample_ratio_mismatch,
+  f.analysis AS feature_analysis,
+  f.bandit_event_type AS feature_bandit_event_type,
+  f.bandit_min_weight AS feature_bandit_min_weight,
+  f.value_type AS feature_value_type,
+  f.kind AS feature_kind,
+  f.value_schema AS feature_value_schema,
   v.id AS variant_id,
   v.name AS variant_name,
-  v.weight AS variant_weight
+  v.weight AS variant_weight,
+  v.value AS variant_value
 FROM features f
 LEFT JOIN variants v ON f.id = v.feature_id
 WHERE f.id = $1
+ORDER BY v.id
 `
 
 type GetFeatureRow struct {
-	FeatureID          int32
-	FeatureName        string
-	FeatureDescription pgtype.Text
-	FeatureActive      bool
-	FeatureCreatedAt   pgtype.Timestamptz
-	VariantID          pgtype.Int4
-	VariantName        pgtype.Text
-	VariantWeight      pgtype.Int4
+	FeatureID                  int32
+	FeatureName                string
+	FeatureDescription         pgtype.Text
+	FeatureActive              bool
+	FeatureVersion             int32
+	FeatureTags                []string
+	FeatureCreatedAt           pgtype.Timestamptz
+	FeatureSampleRatioMismatch bool
+	FeatureAnalysis            string
+	FeatureBanditEventType     pgtype.Text
+	FeatureBanditMinWeight     int32
+	FeatureValueType           string
+	FeatureKind                string
+	FeatureValueSchema         []byte
+	VariantID                  pgtype.Int4
+	VariantName                pgtype.Text
+	VariantWeight              pgtype.Int4
+	VariantValue               []byte
 }
 
 func (q *Queries) GetFeature(ctx context.Context, id int32) ([]GetFeatureRow, error) {
@@ -69,10 +145,20 @@ func (q *Queries) GetFeature(ctx context.Context, id int32) ([]GetFeatureRow, er
 			&i.FeatureName,
 			&i.FeatureDescription,
 			&i.FeatureActive,
+			&i.FeatureVersion,
+			&i.FeatureTags,
 			&i.FeatureCreatedAt,
+			&i.FeatureSampleRatioMismatch,
+			&i.FeatureAnalysis,
+			&i.FeatureBanditEventType,
+			&i.FeatureBanditMinWeight,
+			&i.FeatureValueType,
+			&i.FeatureKind,
+			&i.FeatureValueSchema,
 			&i.VariantID,
 			&i.VariantName,
 			&i.VariantWeight,
+			&i.VariantValue,
 		); err != nil {
 			return nil, err
 		}
@@ -84,28 +170,126 @@ func (q *Queries) GetFeature(ctx context.Context, id int32) ([]GetFeatureRow, er
 	return items, nil
 }
 
+const incrementFeatureVersion = `-- name: IncrementFeatureVersion :one
+UPDATE features
+SET version = version + 1
+WHERE id = $1
+RETURNING version
+`
+
+func (q *Queries) IncrementFeatureVersion(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementFeatureVersion, id)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
 const insertFeature = `-- name: InsertFeature :one
-INSERT INTO features (name, description, active)
-VALUES ($1, $2, $3)
-RETURNING id
+INSERT INTO features (name, description, active, tags, analysis, bandit_event_type, bandit_min_weight, value_type, kind, value_schema)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, version
 `
 
 type InsertFeatureParams struct {
-	Name        string
-	Description pgtype.Text
-	Active      bool
+	Name            string
+	Description     pgtype.Text
+	Active          bool
+	Tags            []string
+	Analysis        string
+	BanditEventType pgtype.Text
+	BanditMinWeight int32
+	ValueType       string
+	Kind            string
+	ValueSchema     []byte
 }
 
-func (q *Queries) InsertFeature(ctx context.Context, arg InsertFeatureParams) (int32, error) {
-	row := q.db.QueryRow(ctx, insertFeature, arg.Name, arg.Description, arg.Active)
-	var id int32
-	err := row.Scan(&id)
-	return id, err
+type InsertFeatureRow struct {
+	ID      int32
+	Version int32
 }
 
-const insertVariant = `-- name: InsertVariant :one
-INSERT INTO variants (feature_id, name, weight)
+func (q *Queries) InsertFeature(ctx context.Context, arg InsertFeatureParams) (InsertFeatureRow, error) {
+	row := q.db.QueryRow(ctx, insertFeature,
+		arg.Name,
+		arg.Description,
+		arg.Active,
+		arg.Tags,
+		arg.Analysis,
+		arg.BanditEventType,
+		arg.BanditMinWeight,
+		arg.ValueType,
+		arg.Kind,
+		arg.ValueSchema,
+	)
+	var i InsertFeatureRow
+	err := row.Scan(&i.ID, &i.Version)
+	return i, err
+}
+
+const insertPrerequisite = `-- name: InsertPrerequisite :exec
+INSERT INTO feature_prerequisites (feature_id, prerequisite_id, variants)
 VALUES ($1, $2, $3)
+`
+
+type InsertPrerequisiteParams struct {
+	FeatureID      int32
+	PrerequisiteID int32
+	Variants       []string
+}
+
+func (q *Queries) InsertPrerequisite(ctx context.Context, arg InsertPrerequisiteParams) error {
+	_, err := q.db.Exec(ctx, insertPrerequisite, arg.FeatureID, arg.PrerequisiteID, arg.Variants)
+	return err
+}
+
+const insertReallocation = `-- name: InsertReallocation :one
+INSERT INTO feature_reallocations (feature_id, variants)
+VALUES ($1, $2)
+RETURNING id, created_at
+`
+
+type InsertReallocationParams struct {
+	FeatureID int32
+	Variants  []byte
+}
+
+type InsertReallocationRow struct {
+	ID        int64
+	CreatedAt pgtype.Timestamptz
+}
+
+func (q *Queries) InsertReallocation(ctx context.Context, arg InsertReallocationParams) (InsertReallocationRow, error) {
+	row := q.db.QueryRow(ctx, insertReallocation, arg.FeatureID, arg.Variants)
+	var i InsertReallocationRow
+	err := row.Scan(&i.ID, &i.CreatedAt)
+	return i, err
+}
+
+const insertRule = `-- name: InsertRule :exec
+INSERT INTO feature_rules (feature_id, position, segment_id, variant)
+VALUES ($1, $2, $3, $4)
+`
+
+type InsertRuleParams struct {
+	FeatureID int32
+	Position  int32
+	SegmentID int32
+	Variant   string
+}
+
+func (q *Queries) InsertRule(ctx context.Context, arg InsertRuleParams) error {
+	_, err := q.db.Exec(ctx, insertRule,
+		arg.FeatureID,
+		arg.Position,
+		arg.SegmentID,
+		arg.Variant,
+	)
+	return err
+}
+
+const insertVariant = `-- name: InsertVariant :one
+INSERT INTO variants (feature_id, name, weight, value)
+VALUES ($1, $2, $3, $4)
 RETURNING id
 `
 
@@ -113,43 +297,177 @@ type InsertVariantParams struct {
 	FeatureID pgtype.Int4
 	Name      string
 	Weight    int32
+	Value     []byte
 }
 
 func (q *Queries) InsertVariant(ctx context.Context, arg InsertVariantParams) (int32, error) {
-	row := q.db.QueryRow(ctx, insertVariant, arg.FeatureID, arg.Name, arg.Weight)
+	row := q.db.QueryRow(ctx, insertVariant,
+		arg.FeatureID,
+		arg.Name,
+		arg.Weight,
+		arg.Value,
+	)
 	var id int32
 	err := row.Scan(&id)
 	return id, err
 }
 
+const listDependentFeatures = `-- name: ListDependentFeatures :many
+SELECT f.name
+FROM feature_prerequisites p
+JOIN features f ON f.id = p.feature_id
+WHERE p.prerequisite_id = $1
+ORDER BY f.name
+`
+
+// Names of the features having the feature as prerequisite.
+func (q *Queries) ListDependentFeatures(ctx context.Context, prerequisiteID int32) ([]string, error) {
+	rows, err := q.db.Query(ctx, listDependentFeatures, prerequisiteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		items = append(items, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDependentPrerequisites = `-- name: ListDependentPrerequisites :many
+SELECT f.name, p.variants
+FROM feature_prerequisites p
+JOIN features f ON f.id = p.feature_id
+WHERE p.prerequisite_id = $1
+ORDER BY f.name
+`
+
+type ListDependentPrerequisitesRow struct {
+	Name     string
+	Variants []string
+}
+
+// Names of the features having the feature as prerequisite, together with
+// the variants of it they require.
+func (q *Queries) ListDependentPrerequisites(ctx context.Context, prerequisiteID int32) ([]ListDependentPrerequisitesRow, error) {
+	rows, err := q.db.Query(ctx, listDependentPrerequisites, prerequisiteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListDependentPrerequisitesRow
+	for rows.Next() {
+		var i ListDependentPrerequisitesRow
+		if err := rows.Scan(&i.Name, &i.Variants); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listFeatures = `-- name: ListFeatures :many
+WITH page AS (
+  SELECT id, name, description, active, version, tags, created_at, sample_ratio_mismatch, analysis,
+    bandit_event_type, bandit_min_weight, value_type, kind, value_schema
+  FROM features
+  WHERE ($1::boolean IS NULL OR active = $1)
+    AND ($2::text IS NULL OR starts_with(name, $2))
+    AND ($3::text IS NULL OR $3 = ANY(tags))
+    AND (
+      $4::int IS NULL
+      OR ($5::text = 'id' AND id > $4)
+      OR ($5::text = '-id' AND id < $4)
+      OR ($5::text = 'name' AND name > $6::text)
+      OR ($5::text = '-name' AND name < $6::text)
+    )
+  ORDER BY
+    CASE WHEN $5::text = 'name' THEN name END ASC,
+    CASE WHEN $5::text = '-name' THEN name END DESC,
+    CASE WHEN $5::text = '-id' THEN id END DESC,
+    id ASC
+  LIMIT $7
+)
 SELECT
   f.id AS feature_id,
   f.name AS feature_name,
   f.description AS feature_description,
   f.active AS feature_active,
+  f.version AS feature_version,
+  f.tags AS feature_tags,
   f.created_at AS feature_created_at,
+  f.sample_ratio_mismatch AS feature_sample_ratio_mismatch,
+  f.analysis AS feature_analysis,
+  f.bandit_event_type AS feature_bandit_event_type,
+  f.bandit_min_weight AS feature_bandit_min_weight,
+  f.value_type AS feature_value_type,
+  f.kind AS feature_kind,
+  f.value_schema AS feature_value_schema,
   v.id AS variant_id,
   v.name AS variant_name,
-  v.weight AS variant_weight
-FROM features f
+  v.weight AS variant_weight,
+  v.value AS variant_value
+FROM page f
 LEFT JOIN variants v ON f.id = v.feature_id
-ORDER BY f.id
+ORDER BY
+  CASE WHEN $5::text = 'name' THEN f.name END ASC,
+  CASE WHEN $5::text = '-name' THEN f.name END DESC,
+  CASE WHEN $5::text = '-id' THEN f.id END DESC,
+  f.id ASC,
+  v.id ASC
 `
 
+type ListFeaturesParams struct {
+	Active     pgtype.Bool
+	NamePrefix pgtype.Text
+	Tag        pgtype.Text
+	AfterID    pgtype.Int4
+	Sort       string
+	AfterName  pgtype.Text
+	PageSize   pgtype.Int4
+}
+
 type ListFeaturesRow struct {
-	FeatureID          int32
-	FeatureName        string
-	FeatureDescription pgtype.Text
-	FeatureActive      bool
-	FeatureCreatedAt   pgtype.Timestamptz
-	VariantID          pgtype.Int4
-	VariantName        pgtype.Text
-	VariantWeight      pgtype.Int4
-}
-
-func (q *Queries) ListFeatures(ctx context.Context) ([]ListFeaturesRow, error) {
-	rows, err := q.db.Query(ctx, listFeatures)
+	FeatureID                  int32
+	FeatureName                string
+	FeatureDescription         pgtype.Text
+	FeatureActive              bool
+	FeatureVersion             int32
+	FeatureTags                []string
+	FeatureCreatedAt           pgtype.Timestamptz
+	FeatureSampleRatioMismatch bool
+	FeatureAnalysis            string
+	FeatureBanditEventType     pgtype.Text
+	FeatureBanditMinWeight     int32
+	FeatureValueType           string
+	FeatureKind                string
+	FeatureValueSchema         []byte
+	VariantID                  pgtype.Int4
+	VariantName                pgtype.Text
+	VariantWeight              pgtype.Int4
+	VariantValue               []byte
+}
+
+func (q *Queries) ListFeatures(ctx context.Context, arg ListFeaturesParams) ([]ListFeaturesRow, error) {
+	rows, err := q.db.Query(ctx, listFeatures,
+		arg.Active,
+		arg.NamePrefix,
+		arg.Tag,
+		arg.AfterID,
+		arg.Sort,
+		arg.AfterName,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -162,10 +480,163 @@ func (q *Queries) ListFeatures(ctx context.Context) ([]ListFeaturesRow, error) {
 			&i.FeatureName,
 			&i.FeatureDescription,
 			&i.FeatureActive,
+			&i.FeatureVersion,
+			&i.FeatureTags,
 			&i.FeatureCreatedAt,
+			&i.FeatureSampleRatioMismatch,
+			&i.FeatureAnalysis,
+			&i.FeatureBanditEventType,
+			&i.FeatureBanditMinWeight,
+			&i.FeatureValueType,
+			&i.FeatureKind,
+			&i.FeatureValueSchema,
 			&i.VariantID,
 			&i.VariantName,
 			&i.VariantWeight,
+			&i.VariantValue,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOverrides = `-- name: ListOverrides :many
+SELECT feature_id, user_key, variant, created_at
+FROM feature_overrides
+WHERE feature_id = ANY($1::int[])
+ORDER BY feature_id, user_key
+`
+
+func (q *Queries) ListOverrides(ctx context.Context, featureIds []int32) ([]FeatureOverride, error) {
+	rows, err := q.db.Query(ctx, listOverrides, featureIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeatureOverride
+	for rows.Next() {
+		var i FeatureOverride
+		if err := rows.Scan(
+			&i.FeatureID,
+			&i.UserKey,
+			&i.Variant,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPrerequisites = `-- name: ListPrerequisites :many
+SELECT feature_id, prerequisite_id, variants
+FROM feature_prerequisites
+WHERE feature_id = ANY($1::int[])
+ORDER BY feature_id, prerequisite_id
+`
+
+func (q *Queries) ListPrerequisites(ctx context.Context, featureIds []int32) ([]FeaturePrerequisite, error) {
+	rows, err := q.db.Query(ctx, listPrerequisites, featureIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeaturePrerequisite
+	for rows.Next() {
+		var i FeaturePrerequisite
+		if err := rows.Scan(&i.FeatureID, &i.PrerequisiteID, &i.Variants); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReallocations = `-- name: ListReallocations :many
+SELECT id, feature_id, variants, created_at
+FROM feature_reallocations
+WHERE feature_id = $1
+ORDER BY created_at DESC, id DESC
+LIMIT $2
+`
+
+type ListReallocationsParams struct {
+	FeatureID int32
+	PageSize  pgtype.Int4
+}
+
+func (q *Queries) ListReallocations(ctx context.Context, arg ListReallocationsParams) ([]FeatureReallocation, error) {
+	rows, err := q.db.Query(ctx, listReallocations, arg.FeatureID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeatureReallocation
+	for rows.Next() {
+		var i FeatureReallocation
+		if err := rows.Scan(
+			&i.ID,
+			&i.FeatureID,
+			&i.Variants,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRules = `-- name: ListRules :many
+SELECT r.feature_id, r.position, r.variant, s.id, s.name, s.description, s.user_keys, s.conditions, s.created_at, s.updated_at
+FROM feature_rules r
+JOIN segments s ON s.id = r.segment_id
+WHERE r.feature_id = ANY($1::int[])
+ORDER BY r.feature_id, r.position
+`
+
+type ListRulesRow struct {
+	FeatureID int32
+	Position  int32
+	Variant   string
+	Segment   Segment
+}
+
+func (q *Queries) ListRules(ctx context.Context, featureIds []int32) ([]ListRulesRow, error) {
+	rows, err := q.db.Query(ctx, listRules, featureIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListRulesRow
+	for rows.Next() {
+		var i ListRulesRow
+		if err := rows.Scan(
+			&i.FeatureID,
+			&i.Position,
+			&i.Variant,
+			&i.Segment.ID,
+			&i.Segment.Name,
+			&i.Segment.Description,
+			&i.Segment.UserKeys,
+			&i.Segment.Conditions,
+			&i.Segment.CreatedAt,
+			&i.Segment.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -177,27 +648,131 @@ func (q *Queries) ListFeatures(ctx context.Context) ([]ListFeaturesRow, error) {
 	return items, nil
 }
 
-const updateFeature = `-- name: UpdateFeature :exec
+const setFeatureActive = `-- name: SetFeatureActive :one
+UPDATE features
+SET active = $1,
+    version = version + 1
+WHERE id = $2 AND version = $3
+RETURNING version
+`
+
+type SetFeatureActiveParams struct {
+	Active  bool
+	ID      int32
+	Version int32
+}
+
+// Fails with no rows if the feature has been changed since version.
+func (q *Queries) SetFeatureActive(ctx context.Context, arg SetFeatureActiveParams) (int32, error) {
+	row := q.db.QueryRow(ctx, setFeatureActive, arg.Active, arg.ID, arg.Version)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const setSampleRatioMismatch = `-- name: SetSampleRatioMismatch :one
+UPDATE features
+SET sample_ratio_mismatch = $1,
+    version = version + 1
+WHERE id = $2 AND sample_ratio_mismatch <> $1
+RETURNING version
+`
+
+type SetSampleRatioMismatchParams struct {
+	SampleRatioMismatch bool
+	ID                  int32
+}
+
+func (q *Queries) SetSampleRatioMismatch(ctx context.Context, arg SetSampleRatioMismatchParams) (int32, error) {
+	row := q.db.QueryRow(ctx, setSampleRatioMismatch, arg.SampleRatioMismatch, arg.ID)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const updateFeature = `-- name: UpdateFeature :one
 UPDATE features
 SET name = $1,
     description = $2,
-    active = $3
-WHERE id = $4
+    active = $3,
+    tags = $4,
+    analysis = $5,
+    bandit_event_type = $6,
+    bandit_min_weight = $7,
+    value_type = $8,
+    kind = $9,
+    value_schema = $10,
+    version = version + 1
+WHERE id = $11
+RETURNING version
 `
 
 type UpdateFeatureParams struct {
-	Name        string
-	Description pgtype.Text
-	Active      bool
-	ID          int32
+	Name            string
+	Description     pgtype.Text
+	Active          bool
+	Tags            []string
+	Analysis        string
+	BanditEventType pgtype.Text
+	BanditMinWeight int32
+	ValueType       string
+	Kind            string
+	ValueSchema     []byte
+	ID              int32
 }
 
-func (q *Queries) UpdateFeature(ctx context.Context, arg UpdateFeatureParams) error {
-	_, err := q.db.Exec(ctx, updateFeature,
+func (q *Queries) UpdateFeature(ctx context.Context, arg UpdateFeatureParams) (int32, error) {
+	row := q.db.QueryRow(ctx, updateFeature,
 		arg.Name,
 		arg.Description,
 		arg.Active,
+		arg.Tags,
+		arg.Analysis,
+		arg.BanditEventType,
+		arg.BanditMinWeight,
+		arg.ValueType,
+		arg.Kind,
+		arg.ValueSchema,
 		arg.ID,
 	)
+	var version int32
+	err := row.Scan(&version)
+	return version, err
+}
+
+const updateVariantWeight = `-- name: UpdateVariantWeight :execrows
+UPDATE variants
+SET weight = $1
+WHERE feature_id = $2 AND name = $3
+`
+
+type UpdateVariantWeightParams struct {
+	Weight    int32
+	FeatureID pgtype.Int4
+	Name      string
+}
+
+func (q *Queries) UpdateVariantWeight(ctx context.Context, arg UpdateVariantWeightParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateVariantWeight, arg.Weight, arg.FeatureID, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertOverride = `-- name: UpsertOverride :exec
+INSERT INTO feature_overrides (feature_id, user_key, variant)
+VALUES ($1, $2, $3)
+ON CONFLICT (feature_id, user_key) DO UPDATE SET variant = EXCLUDED.variant
+`
+
+type UpsertOverrideParams struct {
+	FeatureID int32
+	UserKey   string
+	Variant   string
+}
+
+func (q *Queries) UpsertOverride(ctx context.Context, arg UpsertOverrideParams) error {
+	_, err := q.db.Exec(ctx, upsertOverride, arg.FeatureID, arg.UserKey, arg.Variant)
 	return err
 }
@@ -8,21 +8,124 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type Event struct {
+type AuditLog struct {
 	ID        int64
 	FeatureID pgtype.Int4
-	UserID    pgtype.Text
-	Variant   pgtype.Text
-	EventType pgtype.Text
+	Actor     string
+	Action    string
+	Details   []byte
 	CreatedAt pgtype.Timestamptz
 }
 
+type Event struct {
+	ID              int64
+	FeatureID       pgtype.Int4
+	UserID          pgtype.Text
+	Variant         pgtype.Text
+	EventType       pgtype.Text
+	CreatedAt       pgtype.Timestamptz
+	ClientEventID   pgtype.Text
+	Value           pgtype.Float8
+	Properties      []byte
+	VariantMismatch bool
+}
+
 type Feature struct {
+	ID                  int32
+	Name                string
+	Description         pgtype.Text
+	Active              bool
+	CreatedAt           pgtype.Timestamptz
+	Version             int32
+	Tags                []string
+	SampleRatioMismatch bool
+	Analysis            string
+	BanditEventType     pgtype.Text
+	BanditMinWeight     int32
+	ValueType           string
+	Kind                string
+	ValueSchema         []byte
+}
+
+type FeatureChange struct {
+	ID             int64
+	Type           string
+	FeatureID      int32
+	Feature        []byte
+	CreatedAt      pgtype.Timestamptz
+	WebhooksQueued bool
+}
+
+type FeatureGuardrail struct {
+	FeatureID int32
+	MetricID  int32
+	Threshold float64
+}
+
+type FeatureMetric struct {
+	FeatureID int32
+	MetricID  int32
+}
+
+type FeatureOverride struct {
+	FeatureID int32
+	UserKey   string
+	Variant   string
+	CreatedAt pgtype.Timestamptz
+}
+
+type FeaturePrerequisite struct {
+	FeatureID      int32
+	PrerequisiteID int32
+	Variants       []string
+}
+
+type FeatureReallocation struct {
+	ID        int64
+	FeatureID int32
+	Variants  []byte
+	CreatedAt pgtype.Timestamptz
+}
+
+type FeatureRule struct {
+	FeatureID int32
+	Position  int32
+	SegmentID int32
+	Variant   string
+}
+
+type FeatureSchedule struct {
+	ID         int64
+	FeatureID  int32
+	Action     string
+	Variants   []byte
+	RunAt      pgtype.Timestamptz
+	Timezone   string
+	Status     string
+	Error      pgtype.Text
+	CreatedAt  pgtype.Timestamptz
+	ExecutedAt pgtype.Timestamptz
+}
+
+type Metric struct {
+	ID            int32
+	Name          string
+	Description   pgtype.Text
+	EventType     string
+	Aggregation   string
+	WindowSeconds pgtype.Int8
+	Direction     string
+	CreatedAt     pgtype.Timestamptz
+}
+
+type Segment struct {
 	ID          int32
 	Name        string
 	Description pgtype.Text
-	Active      bool
+	UserKeys    []string
+	Conditions  []byte
 	CreatedAt   pgtype.Timestamptz
+	UpdatedAt   pgtype.Timestamptz
 }
 
 type Variant struct {
@@ -30,4 +133,29 @@ type Variant struct {
 	FeatureID pgtype.Int4
 	Name      string
 	Weight    int32
+	Value     []byte
+}
+
+type Webhook struct {
+	ID        int32
+	Url       string
+	Secret    string
+	Events    []string
+	Active    bool
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int32
+	Event          string
+	Payload        []byte
+	Status         string
+	Attempts       int32
+	ResponseStatus pgtype.Int4
+	Error          pgtype.Text
+	NextAttemptAt  pgtype.Timestamptz
+	CreatedAt      pgtype.Timestamptz
+	CompletedAt    pgtype.Timestamptz
 }
@@ -0,0 +1,11 @@
+-- name: InsertAuditEntry :one
+INSERT INTO audit_log (feature_id, actor, action, details)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at;
+
+-- name: ListAuditEntries :many
+SELECT id, feature_id, actor, action, details, created_at
+FROM audit_log
+WHERE feature_id = @feature_id
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.narg('page_size');
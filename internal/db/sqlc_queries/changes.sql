@@ -0,0 +1,41 @@
+-- name: LockFeatureChanges :exec
+-- Transaction level, so it is held until the change is committed.
+SELECT pg_advisory_xact_lock(@key);
+
+-- name: InsertFeatureChange :one
+INSERT INTO feature_changes (type, feature_id, feature)
+VALUES ($1, $2, $3)
+RETURNING id;
+
+-- name: NotifyFeatureChange :exec
+-- Delivered to the listeners on commit.
+SELECT pg_notify('feature_changes', @payload::text);
+
+-- name: LatestFeatureChangeID :one
+SELECT COALESCE(max(id), 0)::bigint AS id FROM feature_changes;
+
+-- name: HasFeatureChangesUpTo :one
+SELECT EXISTS (SELECT 1 FROM feature_changes WHERE id <= @id);
+
+-- name: ListFeatureChanges :many
+SELECT id, type, feature_id, feature, created_at, webhooks_queued
+FROM feature_changes
+WHERE id > @after_id AND id <= @until_id
+ORDER BY id
+LIMIT @page_size;
+
+-- name: ClaimUnqueuedFeatureChanges :many
+-- Locks the oldest changes whose webhook deliveries are not queued yet,
+-- skipping those claimed by other replicas.
+SELECT id, type, feature_id, feature, created_at, webhooks_queued
+FROM feature_changes
+WHERE NOT webhooks_queued
+ORDER BY id
+LIMIT @page_size
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkFeatureChangesQueued :exec
+UPDATE feature_changes SET webhooks_queued = true WHERE id = ANY(@ids::bigint[]);
+
+-- name: DeleteFeatureChangesBefore :execrows
+DELETE FROM feature_changes WHERE created_at < @before;
@@ -1,16 +1,198 @@
 -- name: InsertEvent :one
-INSERT INTO events (feature_id, user_id, variant, event_type)
-VALUES ($1, $2, $3, $4)
-RETURNING id, feature_id, user_id, variant, event_type, created_at;
+INSERT INTO events (feature_id, user_id, variant, event_type, client_event_id, value, properties, variant_mismatch)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (feature_id, client_event_id) WHERE client_event_id IS NOT NULL DO NOTHING
+RETURNING id, feature_id, user_id, variant, event_type, created_at, client_event_id, value, properties, variant_mismatch;
 
--- name: ListEventsByFeatureID :many
+-- name: GetEventByClientID :one
 SELECT
   id,
   feature_id,
   user_id,
   variant,
   event_type,
-  created_at
+  created_at,
+  client_event_id,
+  value,
+  properties,
+  variant_mismatch
 FROM events
-WHERE feature_id = $1
-ORDER BY created_at DESC;
+WHERE feature_id = $1 AND client_event_id = $2;
+
+-- name: ListEventsByClientIDs :many
+SELECT
+  id,
+  feature_id,
+  user_id,
+  variant,
+  event_type,
+  created_at,
+  client_event_id,
+  value,
+  properties,
+  variant_mismatch
+FROM events
+WHERE (feature_id, client_event_id) IN (
+  SELECT * FROM unnest(@feature_ids::int[], @client_event_ids::text[])
+);
+
+-- name: ListEvents :many
+SELECT
+  id,
+  feature_id,
+  user_id,
+  variant,
+  event_type,
+  created_at,
+  client_event_id,
+  value,
+  properties,
+  variant_mismatch
+FROM events
+WHERE feature_id = @feature_id
+  AND (sqlc.narg('created_from')::timestamptz IS NULL OR created_at >= sqlc.narg('created_from'))
+  AND (sqlc.narg('created_to')::timestamptz IS NULL OR created_at < sqlc.narg('created_to'))
+  AND (sqlc.narg('event_type')::text IS NULL OR event_type = sqlc.narg('event_type'))
+  AND (sqlc.narg('variant')::text IS NULL OR variant = sqlc.narg('variant'))
+  AND (sqlc.narg('user_id')::text IS NULL OR user_id = sqlc.narg('user_id'))
+  AND (
+    sqlc.narg('before_created_at')::timestamptz IS NULL
+    OR (created_at, id) < (sqlc.narg('before_created_at'), sqlc.narg('before_id')::bigint)
+  )
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.narg('page_size');
+
+-- name: CountExposures :many
+SELECT variant, count(*) AS users
+FROM (
+  SELECT DISTINCT ON (user_id) user_id, variant
+  FROM events
+  WHERE feature_id = @feature_id
+    AND event_type = @exposure_type
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+) exposed
+GROUP BY variant
+ORDER BY variant;
+
+-- name: AggregateMetricCovariates :many
+-- Pairs the metric value of every exposed user, including users without
+-- events, with the same value over the lookback before their exposure.
+WITH exposed AS (
+  SELECT DISTINCT ON (user_id) user_id, variant, created_at AS exposed_at
+  FROM events
+  WHERE feature_id = @feature_id
+    AND event_type = @exposure_type
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+),
+per_user AS (
+  SELECT
+    x.variant,
+    count(e.id) FILTER (WHERE e.created_at >= x.exposed_at AND (sqlc.narg('window_seconds')::bigint IS NULL OR e.created_at < x.exposed_at + sqlc.narg('window_seconds') * interval '1 second')) AS post_events,
+    COALESCE(sum(e.value) FILTER (WHERE e.created_at >= x.exposed_at AND (sqlc.narg('window_seconds')::bigint IS NULL OR e.created_at < x.exposed_at + sqlc.narg('window_seconds') * interval '1 second')), 0) AS post_total,
+    count(e.id) FILTER (WHERE e.created_at < x.exposed_at AND e.created_at >= x.exposed_at - @lookback_seconds::bigint * interval '1 second') AS pre_events,
+    COALESCE(sum(e.value) FILTER (WHERE e.created_at < x.exposed_at AND e.created_at >= x.exposed_at - @lookback_seconds::bigint * interval '1 second'), 0) AS pre_total
+  FROM exposed x
+  LEFT JOIN events e
+    ON e.user_id = x.user_id
+    AND e.feature_id = @feature_id
+    AND e.event_type = @event_type
+  GROUP BY x.variant, x.user_id
+),
+observed AS (
+  SELECT
+    variant,
+    CASE @aggregation::text
+      WHEN 'unique' THEN (pre_events > 0)::int::float8
+      WHEN 'count' THEN pre_events::float8
+      ELSE pre_total::float8
+    END AS covariate,
+    CASE @aggregation::text
+      WHEN 'unique' THEN (post_events > 0)::int::float8
+      WHEN 'count' THEN post_events::float8
+      ELSE post_total::float8
+    END AS metric
+  FROM per_user
+)
+SELECT
+  variant,
+  count(*) AS users,
+  sum(covariate)::float8 AS covariate_sum,
+  sum(covariate * covariate)::float8 AS covariate_sum_squares,
+  sum(metric)::float8 AS metric_sum,
+  sum(metric * metric)::float8 AS metric_sum_squares,
+  sum(covariate * metric)::float8 AS cross_sum
+FROM observed
+GROUP BY variant
+ORDER BY variant;
+
+-- name: AggregateMetrics :many
+WITH exposed AS (
+  SELECT DISTINCT ON (user_id) user_id, variant, created_at AS exposed_at
+  FROM events
+  WHERE feature_id = @feature_id
+    AND event_type = @exposure_type
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+),
+per_user AS (
+  SELECT x.variant, e.event_type, e.user_id, count(*) AS events, sum(e.value) AS total, avg(e.value) AS average
+  FROM events e
+  JOIN exposed x ON x.user_id = e.user_id
+  WHERE e.feature_id = @feature_id
+    AND e.event_type <> @exposure_type
+    AND e.created_at >= x.exposed_at
+  GROUP BY x.variant, e.event_type, e.user_id
+)
+SELECT
+  variant,
+  event_type,
+  count(*) AS users,
+  sum(events)::float8 AS count_sum,
+  sum(events * events)::float8 AS count_sum_squares,
+  count(total) AS value_users,
+  COALESCE(sum(total), 0)::float8 AS value_sum,
+  COALESCE(sum(total * total), 0)::float8 AS value_sum_squares,
+  COALESCE(sum(average), 0)::float8 AS mean_sum,
+  COALESCE(sum(average * average), 0)::float8 AS mean_sum_squares
+FROM per_user
+GROUP BY variant, event_type
+ORDER BY event_type, variant;
+
+-- name: AggregateMetric :many
+WITH exposed AS (
+  SELECT DISTINCT ON (user_id) user_id, variant, created_at AS exposed_at
+  FROM events
+  WHERE feature_id = @feature_id
+    AND event_type = @exposure_type
+    AND user_id IS NOT NULL
+    AND NOT variant_mismatch
+  ORDER BY user_id, created_at
+),
+per_user AS (
+  SELECT x.variant, e.user_id, count(*) AS events, sum(e.value) AS total, avg(e.value) AS average
+  FROM events e
+  JOIN exposed x ON x.user_id = e.user_id
+  WHERE e.feature_id = @feature_id
+    AND e.event_type = @event_type
+    AND e.created_at >= x.exposed_at
+    AND (sqlc.narg('window_seconds')::bigint IS NULL OR e.created_at < x.exposed_at + sqlc.narg('window_seconds') * interval '1 second')
+  GROUP BY x.variant, e.user_id
+)
+SELECT
+  variant,
+  count(*) AS users,
+  sum(events)::float8 AS count_sum,
+  sum(events * events)::float8 AS count_sum_squares,
+  count(total) AS value_users,
+  COALESCE(sum(total), 0)::float8 AS value_sum,
+  COALESCE(sum(total * total), 0)::float8 AS value_sum_squares,
+  COALESCE(sum(average), 0)::float8 AS mean_sum,
+  COALESCE(sum(average * average), 0)::float8 AS mean_sum_squares
+FROM per_user
+GROUP BY variant
+ORDER BY variant;
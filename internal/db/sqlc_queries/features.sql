@@ -1,16 +1,52 @@
 -- name: ListFeatures :many
+WITH page AS (
+  SELECT id, name, description, active, version, tags, created_at, sample_ratio_mismatch, analysis,
+    bandit_event_type, bandit_min_weight, value_type, kind, value_schema
+  FROM features
+  WHERE (sqlc.narg('active')::boolean IS NULL OR active = sqlc.narg('active'))
+    AND (sqlc.narg('name_prefix')::text IS NULL OR starts_with(name, sqlc.narg('name_prefix')))
+    AND (sqlc.narg('tag')::text IS NULL OR sqlc.narg('tag') = ANY(tags))
+    AND (
+      sqlc.narg('after_id')::int IS NULL
+      OR (@sort::text = 'id' AND id > sqlc.narg('after_id'))
+      OR (@sort::text = '-id' AND id < sqlc.narg('after_id'))
+      OR (@sort::text = 'name' AND name > sqlc.narg('after_name')::text)
+      OR (@sort::text = '-name' AND name < sqlc.narg('after_name')::text)
+    )
+  ORDER BY
+    CASE WHEN @sort::text = 'name' THEN name END ASC,
+    CASE WHEN @sort::text = '-name' THEN name END DESC,
+    CASE WHEN @sort::text = '-id' THEN id END DESC,
+    id ASC
+  LIMIT sqlc.narg('page_size')
+)
 SELECT
   f.id AS feature_id,
   f.name AS feature_name,
   f.description AS feature_description,
   f.active AS feature_active,
+  f.version AS feature_version,
+  f.tags AS feature_tags,
   f.created_at AS feature_created_at,
+  f.sample_ratio_mismatch AS feature_sample_ratio_mismatch,
+  f.analysis AS feature_analysis,
+  f.bandit_event_type AS feature_bandit_event_type,
+  f.bandit_min_weight AS feature_bandit_min_weight,
+  f.value_type AS feature_value_type,
+  f.kind AS feature_kind,
+  f.value_schema AS feature_value_schema,
   v.id AS variant_id,
   v.name AS variant_name,
-  v.weight AS variant_weight
-FROM features f
+  v.weight AS variant_weight,
+  v.value AS variant_value
+FROM page f
 LEFT JOIN variants v ON f.id = v.feature_id
-ORDER BY f.id;
+ORDER BY
+  CASE WHEN @sort::text = 'name' THEN f.name END ASC,
+  CASE WHEN @sort::text = '-name' THEN f.name END DESC,
+  CASE WHEN @sort::text = '-id' THEN f.id END DESC,
+  f.id ASC,
+  v.id ASC;
 
 -- name: GetFeature :many
 SELECT
@@ -18,33 +54,156 @@ SELECT
   f.name AS feature_name,
   f.description AS feature_description,
   f.active AS feature_active,
+  f.version AS feature_version,
+  f.tags AS feature_tags,
   f.created_at AS feature_created_at,
+  f.sample_ratio_mismatch AS feature_sample_ratio_mismatch,
+  f.analysis AS feature_analysis,
+  f.bandit_event_type AS feature_bandit_event_type,
+  f.bandit_min_weight AS feature_bandit_min_weight,
+  f.value_type AS feature_value_type,
+  f.kind AS feature_kind,
+  f.value_schema AS feature_value_schema,
   v.id AS variant_id,
   v.name AS variant_name,
-  v.weight AS variant_weight
+  v.weight AS variant_weight,
+  v.value AS variant_value
 FROM features f
 LEFT JOIN variants v ON f.id = v.feature_id
-WHERE f.id = $1;
+WHERE f.id = $1
+ORDER BY v.id;
 
 -- name: InsertFeature :one
-INSERT INTO features (name, description, active)
-VALUES ($1, $2, $3)
-RETURNING id;
+INSERT INTO features (name, description, active, tags, analysis, bandit_event_type, bandit_min_weight, value_type, kind, value_schema)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, version;
+
+-- name: InsertReallocation :one
+INSERT INTO feature_reallocations (feature_id, variants)
+VALUES ($1, $2)
+RETURNING id, created_at;
+
+-- name: ListReallocations :many
+SELECT id, feature_id, variants, created_at
+FROM feature_reallocations
+WHERE feature_id = @feature_id
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.narg('page_size');
 
 -- name: InsertVariant :one
-INSERT INTO variants (feature_id, name, weight)
-VALUES ($1, $2, $3)
+INSERT INTO variants (feature_id, name, weight, value)
+VALUES ($1, $2, $3, $4)
 RETURNING id;
 
--- name: UpdateFeature :exec
+-- name: UpdateFeature :one
 UPDATE features
 SET name = $1,
     description = $2,
-    active = $3
-WHERE id = $4;
+    active = $3,
+    tags = $4,
+    analysis = $5,
+    bandit_event_type = $6,
+    bandit_min_weight = $7,
+    value_type = $8,
+    kind = $9,
+    value_schema = $10,
+    version = version + 1
+WHERE id = $11
+RETURNING version;
+
+-- name: SetSampleRatioMismatch :one
+UPDATE features
+SET sample_ratio_mismatch = $1,
+    version = version + 1
+WHERE id = $2 AND sample_ratio_mismatch <> $1
+RETURNING version;
 
 -- name: DeleteVariantsByFeature :exec
 DELETE FROM variants WHERE feature_id = $1;
 
 -- name: DeleteFeature :exec
 DELETE FROM features WHERE id = $1;
+
+-- name: ListPrerequisites :many
+SELECT feature_id, prerequisite_id, variants
+FROM feature_prerequisites
+WHERE feature_id = ANY(@feature_ids::int[])
+ORDER BY feature_id, prerequisite_id;
+
+-- name: ListDependentFeatures :many
+-- Names of the features having the feature as prerequisite.
+SELECT f.name
+FROM feature_prerequisites p
+JOIN features f ON f.id = p.feature_id
+WHERE p.prerequisite_id = $1
+ORDER BY f.name;
+
+-- name: ListDependentPrerequisites :many
+-- Names of the features having the feature as prerequisite, together with
+-- the variants of it they require.
+SELECT f.name, p.variants
+FROM feature_prerequisites p
+JOIN features f ON f.id = p.feature_id
+WHERE p.prerequisite_id = $1
+ORDER BY f.name;
+
+-- name: InsertPrerequisite :exec
+INSERT INTO feature_prerequisites (feature_id, prerequisite_id, variants)
+VALUES ($1, $2, $3);
+
+-- name: DeletePrerequisitesByFeature :exec
+DELETE FROM feature_prerequisites WHERE feature_id = $1;
+
+-- name: ListOverrides :many
+SELECT feature_id, user_key, variant, created_at
+FROM feature_overrides
+WHERE feature_id = ANY(@feature_ids::int[])
+ORDER BY feature_id, user_key;
+
+-- name: UpsertOverride :exec
+INSERT INTO feature_overrides (feature_id, user_key, variant)
+VALUES ($1, $2, $3)
+ON CONFLICT (feature_id, user_key) DO UPDATE SET variant = EXCLUDED.variant;
+
+-- name: DeleteOverride :execrows
+DELETE FROM feature_overrides WHERE feature_id = $1 AND user_key = $2;
+
+-- name: IncrementFeatureVersion :one
+UPDATE features
+SET version = version + 1
+WHERE id = $1
+RETURNING version;
+
+-- name: BumpFeatureVersion :one
+-- Fails with no rows if the feature has been changed since version.
+UPDATE features
+SET version = version + 1
+WHERE id = $1 AND version = $2
+RETURNING version;
+
+-- name: SetFeatureActive :one
+-- Fails with no rows if the feature has been changed since version.
+UPDATE features
+SET active = $1,
+    version = version + 1
+WHERE id = $2 AND version = $3
+RETURNING version;
+
+-- name: UpdateVariantWeight :execrows
+UPDATE variants
+SET weight = $1
+WHERE feature_id = $2 AND name = $3;
+
+-- name: ListRules :many
+SELECT r.feature_id, r.position, r.variant, sqlc.embed(s)
+FROM feature_rules r
+JOIN segments s ON s.id = r.segment_id
+WHERE r.feature_id = ANY(@feature_ids::int[])
+ORDER BY r.feature_id, r.position;
+
+-- name: InsertRule :exec
+INSERT INTO feature_rules (feature_id, position, segment_id, variant)
+VALUES ($1, $2, $3, $4);
+
+-- name: DeleteRulesByFeature :exec
+DELETE FROM feature_rules WHERE feature_id = $1;
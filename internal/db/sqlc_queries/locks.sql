@@ -0,0 +1,6 @@
+-- name: TryAdvisoryLock :one
+-- Session level, so it has to be released on the same connection.
+SELECT pg_try_advisory_lock(@key);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock(@key);
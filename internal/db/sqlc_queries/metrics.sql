@@ -0,0 +1,57 @@
+-- name: GetMetric :one
+SELECT id, name, description, event_type, aggregation, window_seconds, direction, created_at
+FROM metrics
+WHERE id = $1;
+
+-- name: ListMetrics :many
+SELECT id, name, description, event_type, aggregation, window_seconds, direction, created_at
+FROM metrics
+ORDER BY name;
+
+-- name: ListMetricsByFeature :many
+SELECT m.id, m.name, m.description, m.event_type, m.aggregation, m.window_seconds, m.direction, m.created_at
+FROM metrics m
+JOIN feature_metrics fm ON fm.metric_id = m.id
+WHERE fm.feature_id = $1
+ORDER BY m.name;
+
+-- name: InsertMetric :one
+INSERT INTO metrics (name, description, event_type, aggregation, window_seconds, direction)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id;
+
+-- name: UpdateMetric :execrows
+UPDATE metrics
+SET name = $1,
+    description = $2,
+    event_type = $3,
+    aggregation = $4,
+    window_seconds = $5,
+    direction = $6
+WHERE id = $7;
+
+-- name: DeleteMetric :execrows
+DELETE FROM metrics WHERE id = $1;
+
+-- name: AttachMetric :exec
+INSERT INTO feature_metrics (feature_id, metric_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: DetachMetric :execrows
+DELETE FROM feature_metrics WHERE feature_id = $1 AND metric_id = $2;
+
+-- name: ListGuardrailsByFeature :many
+SELECT m.id, m.name, m.description, m.event_type, m.aggregation, m.window_seconds, m.direction, m.created_at, g.threshold
+FROM metrics m
+JOIN feature_guardrails g ON g.metric_id = m.id
+WHERE g.feature_id = $1
+ORDER BY m.name;
+
+-- name: SetGuardrail :exec
+INSERT INTO feature_guardrails (feature_id, metric_id, threshold)
+VALUES ($1, $2, $3)
+ON CONFLICT (feature_id, metric_id) DO UPDATE SET threshold = EXCLUDED.threshold;
+
+-- name: RemoveGuardrail :execrows
+DELETE FROM feature_guardrails WHERE feature_id = $1 AND metric_id = $2;
@@ -0,0 +1,28 @@
+-- name: ListSchedules :many
+SELECT id, feature_id, action, variants, run_at, timezone, status, error, created_at, executed_at
+FROM feature_schedules
+WHERE feature_id = $1
+ORDER BY run_at, id;
+
+-- name: InsertSchedule :one
+INSERT INTO feature_schedules (feature_id, action, variants, run_at, timezone)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, status, created_at;
+
+-- name: DeletePendingSchedule :execrows
+DELETE FROM feature_schedules
+WHERE id = $1 AND feature_id = $2 AND status = 'pending';
+
+-- name: ListDueSchedules :many
+SELECT id, feature_id, action, variants, run_at, timezone, status, error, created_at, executed_at
+FROM feature_schedules
+WHERE status = 'pending' AND run_at <= now()
+ORDER BY run_at, id
+LIMIT $1;
+
+-- name: CompleteSchedule :exec
+UPDATE feature_schedules
+SET status = $1,
+    error = $2,
+    executed_at = now()
+WHERE id = $3;
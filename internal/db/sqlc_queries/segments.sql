@@ -0,0 +1,43 @@
+-- name: ListSegments :many
+SELECT id, name, description, user_keys, conditions, created_at, updated_at
+FROM segments
+ORDER BY name;
+
+-- name: GetSegment :one
+SELECT id, name, description, user_keys, conditions, created_at, updated_at
+FROM segments
+WHERE id = $1;
+
+-- name: InsertSegment :one
+INSERT INTO segments (name, description, user_keys, conditions)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, updated_at;
+
+-- name: UpdateSegment :one
+UPDATE segments
+SET name = $1,
+    description = $2,
+    user_keys = $3,
+    conditions = $4,
+    updated_at = now()
+WHERE id = $5
+RETURNING created_at, updated_at;
+
+-- name: DeleteSegment :execrows
+DELETE FROM segments WHERE id = $1;
+
+-- name: ListSegmentFeatures :many
+-- Features with rules targeting the segment.
+SELECT DISTINCT f.id, f.name
+FROM feature_rules r
+JOIN features f ON f.id = r.feature_id
+WHERE r.segment_id = $1
+ORDER BY f.name;
+
+-- name: TouchSegmentFeatures :many
+-- Increments the version of the features targeting the segment, so that
+-- caches and subscribers pick up its changes.
+UPDATE features
+SET version = version + 1
+WHERE id IN (SELECT feature_id FROM feature_rules WHERE segment_id = $1)
+RETURNING id;
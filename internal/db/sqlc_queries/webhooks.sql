@@ -0,0 +1,73 @@
+-- name: GetWebhook :one
+SELECT id, url, secret, events, active, created_at, updated_at
+FROM webhooks
+WHERE id = $1;
+
+-- name: ListWebhooks :many
+SELECT id, url, secret, events, active, created_at, updated_at
+FROM webhooks
+ORDER BY id;
+
+-- name: InsertWebhook :one
+INSERT INTO webhooks (url, secret, events, active)
+VALUES ($1, $2, $3, $4)
+RETURNING id, created_at, updated_at;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = $1,
+    secret = $2,
+    events = $3,
+    active = $4,
+    updated_at = now()
+WHERE id = $5
+RETURNING created_at, updated_at;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+-- Queues a delivery of the event to every active webhook subscribed to it.
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+SELECT id, @event::text, @payload
+FROM webhooks
+WHERE active AND (cardinality(events) = 0 OR @event::text = ANY(events));
+
+-- name: InsertWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event, payload)
+VALUES ($1, $2, $3)
+RETURNING id, webhook_id, event, payload, status, attempts, response_status, error, next_attempt_at, created_at, completed_at;
+
+-- name: ClaimWebhookDeliveries :many
+-- Leases due deliveries by moving their next attempt to lease_until, so that
+-- concurrent dispatchers skip them while they are being sent.
+UPDATE webhook_deliveries d
+SET next_attempt_at = @lease_until
+FROM webhooks w
+WHERE w.id = d.webhook_id
+  AND d.id IN (
+    SELECT id
+    FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= now()
+    ORDER BY next_attempt_at
+    LIMIT @batch_size
+    FOR UPDATE SKIP LOCKED
+  )
+RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_status, d.error, d.next_attempt_at, d.created_at, d.completed_at, w.url, w.secret;
+
+-- name: RecordWebhookAttempt :exec
+UPDATE webhook_deliveries
+SET status = $1,
+    attempts = $2,
+    response_status = $3,
+    error = $4,
+    next_attempt_at = $5,
+    completed_at = $6
+WHERE id = $7;
+
+-- name: ListWebhookDeliveries :many
+SELECT id, webhook_id, event, payload, status, attempts, response_status, error, next_attempt_at, created_at, completed_at
+FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.narg('page_size');
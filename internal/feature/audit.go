@@ -0,0 +1,58 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	// AuditGuardrailKill is the deactivation of a feature by a breached
+	// guardrail.
+	AuditGuardrailKill AuditAction = "guardrail.kill"
+	// AuditScheduleApplied and AuditScheduleFailed record the outcome of a
+	// schedule applied by the Scheduler.
+	AuditScheduleApplied AuditAction = "schedule.applied"
+	AuditScheduleFailed  AuditAction = "schedule.failed"
+	// AuditFeatureDeleted is the deletion of a feature through the API. Its
+	// entries stay in the audit log after the feature is gone.
+	AuditFeatureDeleted AuditAction = "feature.deleted"
+)
+
+// apiActor is the actor of audit entries written for requests to the API.
+const apiActor = "api"
+
+// AuditEntry records an action taken on a feature. Actor is the user or
+// component that took it.
+type AuditEntry struct {
+	ID        int64
+	FeatureID int32
+	Actor     string
+	Action    AuditAction
+	Details   map[string]any
+	CreatedAt time.Time
+}
+
+type AuditRepository interface {
+	Record(ctx context.Context, entry *AuditEntry) error
+	// List returns the newest entries of a feature first. A zero limit
+	// returns all of them.
+	List(ctx context.Context, featureID int32, limit int) ([]*AuditEntry, error)
+}
+
+// ListAuditEntries returns the audit log of a feature, newest first. A zero
+// limit returns all of it.
+func (s *Service) ListAuditEntries(ctx context.Context, featureID int32, limit int) ([]*AuditEntry, error) {
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.auditRepo.List(ctx, featureID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit entries: %w", err)
+	}
+
+	return entries, nil
+}
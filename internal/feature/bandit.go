@@ -0,0 +1,294 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/eve-an/splitter/internal/stats"
+)
+
+var (
+	ErrBanditEventTypeRequired = errors.New("bandit event type is required")
+	ErrBanditMinWeightTooHigh  = errors.New("bandit minimum weight exceeds an equal share of the total weight")
+)
+
+// thompsonDraws is the number of joint posterior samples used to estimate
+// the probability of each variant being best.
+const thompsonDraws = 10000
+
+// banditLockKey identifies the lock held by the replica reallocating bandit
+// weights; it is "splitbnd" in ASCII.
+const banditLockKey int64 = 0x73706c6974626e64
+
+// Bandit makes the weights of a feature follow Thompson sampling over the
+// conversions to EventType of its exposed users: every variant gets
+// MinWeight plus a share of the remaining weight equal to its probability
+// of converting best. The total weight of the feature stays the same.
+type Bandit struct {
+	EventType string
+	MinWeight uint8
+}
+
+func (b *Bandit) validate(variants Variants) error {
+	var errs []error
+	if b.EventType == "" {
+		errs = append(errs, ErrBanditEventTypeRequired)
+	}
+	if uint32(b.MinWeight)*uint32(len(variants)) > variants.TotalWeight() {
+		errs = append(errs, ErrBanditMinWeightTooHigh)
+	}
+
+	return errors.Join(errs...)
+}
+
+// ReallocatedVariant records the data a variant's new weight was based on.
+type ReallocatedVariant struct {
+	Variant         string  `json:"variant"`
+	PreviousWeight  uint8   `json:"previous_weight"`
+	Weight          uint8   `json:"weight"`
+	Exposures       int64   `json:"exposures"`
+	Conversions     int64   `json:"conversions"`
+	ProbabilityBest float64 `json:"probability_best"`
+}
+
+// Reallocation records a change of weights made by the bandit of a feature.
+type Reallocation struct {
+	ID        int64
+	FeatureID int32
+	Variants  []ReallocatedVariant
+	CreatedAt time.Time
+}
+
+// Reallocate recomputes the weights of an active bandit feature, stores them
+// if they changed and records the reallocation. It returns nil if the
+// feature is inactive, has no bandit or its weights stay the same. Only the
+// weights are stored, and only if the feature has not been changed in the
+// meantime; otherwise ErrFeatureVersionConflict is returned.
+func (s *Service) Reallocate(ctx context.Context, id int32, r *rand.Rand) (*Reallocation, error) {
+	feature, err := s.featureRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get feature: %w", err)
+	}
+
+	if !feature.Active || feature.Bandit == nil || len(feature.Variants) == 0 {
+		return nil, nil
+	}
+
+	conversion := &Metric{
+		Name:        feature.Bandit.EventType,
+		EventType:   feature.Bandit.EventType,
+		Aggregation: AggregationUnique,
+		Direction:   DirectionIncrease,
+	}
+	aggregate, err := s.eventRepo.Aggregate(ctx, feature.ID, []*Metric{conversion})
+	if err != nil {
+		return nil, fmt.Errorf("aggregate conversions: %w", err)
+	}
+
+	exposures := make(map[string]int64, len(aggregate.Exposures))
+	for _, exposure := range aggregate.Exposures {
+		exposures[exposure.Variant] = exposure.Users
+	}
+	conversions := make(map[string]int64, len(aggregate.Metrics))
+	for _, m := range aggregate.Metrics {
+		conversions[m.Variant] = m.Users
+	}
+
+	posteriors := make([]stats.BetaDistribution, len(feature.Variants))
+	for i, variant := range feature.Variants {
+		posteriors[i] = stats.BetaPosterior(conversions[variant.Name], exposures[variant.Name])
+	}
+
+	probabilities := stats.ProbabilityBest(r, posteriors, thompsonDraws)
+	weights := thompsonWeights(probabilities, feature.Variants.TotalWeight(), feature.Bandit.MinWeight)
+
+	updated := *feature
+	updated.Variants = slices.Clone(feature.Variants)
+	reallocation := &Reallocation{FeatureID: feature.ID, Variants: make([]ReallocatedVariant, len(weights))}
+	changed := false
+	for i := range updated.Variants {
+		variant := &updated.Variants[i]
+		reallocation.Variants[i] = ReallocatedVariant{
+			Variant:         variant.Name,
+			PreviousWeight:  variant.Weight,
+			Weight:          weights[i],
+			Exposures:       exposures[variant.Name],
+			Conversions:     conversions[variant.Name],
+			ProbabilityBest: probabilities[i],
+		}
+
+		changed = changed || variant.Weight != weights[i]
+		variant.Weight = weights[i]
+	}
+
+	if !changed {
+		return nil, nil
+	}
+
+	if err := s.featureRepo.UpdateWeights(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("update weights: %w", err)
+	}
+	s.featureCache.Delete(featureCacheKey(feature.ID))
+
+	if err := s.featureRepo.RecordReallocation(ctx, reallocation); err != nil {
+		return nil, fmt.Errorf("record reallocation: %w", err)
+	}
+
+	return reallocation, nil
+}
+
+// ListReallocations returns the reallocations of a feature, newest first. A
+// zero limit returns all of them.
+func (s *Service) ListReallocations(ctx context.Context, featureID int32, limit int) ([]*Reallocation, error) {
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return nil, err
+	}
+
+	reallocations, err := s.featureRepo.ListReallocations(ctx, featureID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list reallocations: %w", err)
+	}
+
+	return reallocations, nil
+}
+
+// thompsonWeights gives every variant minWeight plus its probability's
+// share of the rest of total, rounded so that the weights add up to total.
+func thompsonWeights(probabilities []float64, total uint32, minWeight uint8) []uint8 {
+	weights := make([]uint8, len(probabilities))
+	free := float64(total) - float64(minWeight)*float64(len(probabilities))
+
+	remainders := make([]float64, len(probabilities))
+	assigned := uint32(0)
+	for i, p := range probabilities {
+		share := free * p
+		whole := uint32(share)
+		weights[i] = minWeight + uint8(whole)
+		remainders[i] = share - float64(whole)
+		assigned += uint32(weights[i])
+	}
+
+	// hand out what rounding down left over, largest remainder first
+	order := make([]int, len(probabilities))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case remainders[a] > remainders[b]:
+			return -1
+		case remainders[a] < remainders[b]:
+			return 1
+		default:
+			return 0
+		}
+	})
+	for i := 0; assigned < total && len(order) > 0; i = (i + 1) % len(order) {
+		weights[order[i]]++
+		assigned++
+	}
+
+	return weights
+}
+
+type BanditAllocatorOptions struct {
+	Interval time.Duration
+	// Timeout bounds the reallocation of a single feature.
+	Timeout time.Duration
+}
+
+// BanditAllocator periodically reallocates the weights of all active bandit
+// features. Only one replica reallocates at a time, holding a lock handed
+// out by its Locker.
+type BanditAllocator struct {
+	*periodic
+
+	svc    *Service
+	locker Locker
+	opts   BanditAllocatorOptions
+	logger *slog.Logger
+	rand   *rand.Rand
+}
+
+func NewBanditAllocator(svc *Service, locker Locker, opts BanditAllocatorOptions, logger *slog.Logger) *BanditAllocator {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	a := &BanditAllocator{
+		svc:    svc,
+		locker: locker,
+		opts:   opts,
+		logger: logger,
+		rand:   rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())),
+	}
+	a.periodic = startPeriodic("bandit allocator", opts.Interval, a.reallocate)
+
+	return a
+}
+
+func (a *BanditAllocator) reallocate(stop <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.Timeout)
+	unlock, locked, err := a.locker.TryLock(ctx, banditLockKey)
+	cancel()
+	if err != nil {
+		a.logger.Error("bandit allocation failed to take lock", slog.Any("error", err))
+		return
+	}
+	if !locked {
+		// another replica is reallocating
+		return
+	}
+	defer unlock()
+
+	active := true
+	page, err := a.svc.ListFeatures(context.Background(), FeatureFilter{Active: &active})
+	if err != nil {
+		a.logger.Error("bandit allocation failed to list features", slog.Any("error", err))
+		return
+	}
+
+	for _, feature := range page.Features {
+		if feature.Bandit == nil {
+			continue
+		}
+
+		if stopped(stop) {
+			return
+		}
+
+		a.reallocateFeature(feature)
+	}
+}
+
+func (a *BanditAllocator) reallocateFeature(feature *Feature) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.opts.Timeout)
+	defer cancel()
+
+	reallocation, err := a.svc.Reallocate(ctx, feature.ID, a.rand)
+	if err != nil {
+		a.logger.Error("bandit allocation failed", slog.Int("feature_id", int(feature.ID)), slog.Any("error", err))
+		return
+	}
+
+	if reallocation == nil {
+		return
+	}
+
+	attrs := []any{slog.Int("feature_id", int(feature.ID)), slog.String("feature", feature.Name)}
+	for _, variant := range reallocation.Variants {
+		attrs = append(attrs, slog.Group(variant.Variant,
+			slog.Int("previous_weight", int(variant.PreviousWeight)),
+			slog.Int("weight", int(variant.Weight)),
+		))
+	}
+	a.logger.Info("bandit reallocated weights", attrs...)
+}
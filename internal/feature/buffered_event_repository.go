@@ -0,0 +1,369 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrEventQueueFull      = errors.New("event queue is full")
+	ErrEventWriterClosed   = errors.New("event writer is closed")
+	ErrInvalidBackpressure = errors.New("invalid backpressure policy")
+)
+
+// BackpressurePolicy decides what happens to new events while the queue of
+// the buffered writer is full.
+type BackpressurePolicy string
+
+const (
+	// BackpressureBlock waits for free space or for the request to be cancelled.
+	BackpressureBlock BackpressurePolicy = "block"
+	// BackpressureDropOldest evicts the oldest queued event.
+	BackpressureDropOldest BackpressurePolicy = "drop-oldest"
+	// BackpressureReject fails with ErrEventQueueFull.
+	BackpressureReject BackpressurePolicy = "reject"
+)
+
+const (
+	// flushBackoff is the pause after the first failed flush, doubling with
+	// every further attempt up to maxFlushBackoff.
+	flushBackoff    = 100 * time.Millisecond
+	maxFlushBackoff = 10 * time.Second
+)
+
+type BufferedEventOptions struct {
+	QueueSize     int
+	BatchSize     int
+	FlushInterval time.Duration
+	FlushTimeout  time.Duration
+	// FlushAttempts bounds how often a batch is written before its events
+	// are given up.
+	FlushAttempts int
+	Backpressure  BackpressurePolicy
+}
+
+// bufferedEventRepository decouples request latency from insert latency. Events
+// are accepted into a bounded in-memory queue and written to the wrapped
+// repository in batches, either once BatchSize events are queued or every
+// FlushInterval. A batch that fails because the database cannot be reached
+// is retried with exponential backoff, during which no further batches are
+// written and the queue fills up under the backpressure policy; once the
+// repository is closed, it is retried without pausing. A batch rejected by
+// the database is written event by event, so only the offending events are
+// dropped. Reads go straight to the wrapped repository, so
+// events are only listed after they have been flushed.
+type bufferedEventRepository struct {
+	next    EventRepository
+	opts    BufferedEventOptions
+	logger  *slog.Logger
+	queue   chan *Event
+	stop    chan struct{}
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Uint64
+}
+
+var _ EventRepository = (*bufferedEventRepository)(nil)
+
+func NewBufferedEventRepository(
+	next EventRepository,
+	opts BufferedEventOptions,
+	logger *slog.Logger,
+) (*bufferedEventRepository, error) {
+	switch opts.Backpressure {
+	case BackpressureBlock, BackpressureDropOldest, BackpressureReject:
+	case "":
+		opts.Backpressure = BackpressureBlock
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBackpressure, opts.Backpressure)
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 10000
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.FlushTimeout <= 0 {
+		opts.FlushTimeout = 10 * time.Second
+	}
+	if opts.FlushAttempts <= 0 {
+		opts.FlushAttempts = 10
+	}
+
+	b := &bufferedEventRepository{
+		next:   next,
+		opts:   opts,
+		logger: logger,
+		queue:  make(chan *Event, opts.QueueSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	go b.run()
+
+	return b, nil
+}
+
+// Create implements EventRepository. The event is only queued; ID stays
+// unset until it is listed from the database. A client id that has already
+// been written is looked up before queueing and reported with
+// ErrEventAlreadyRecorded. Retries of an event that is still queued, or
+// that arrive while the database cannot be reached, are queued as well and
+// only skipped when the queue is flushed.
+func (b *bufferedEventRepository) Create(ctx context.Context, event *Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return ErrEventWriterClosed
+	}
+
+	if event.ClientID != "" {
+		recorded, err := b.next.GetByClientID(ctx, event.FeatureID, event.ClientID)
+		switch {
+		case err == nil:
+			*event = *recorded
+			return ErrEventAlreadyRecorded
+		case errors.Is(err, ErrEventNotFound), isUnavailable(err):
+		default:
+			return fmt.Errorf("looking up recorded event: %w", err)
+		}
+	}
+
+	return b.enqueue(ctx, event)
+}
+
+// CreateBatch implements EventRepository. With BackpressureReject the batch
+// is rejected as a whole when the queue cannot take all of it. Duplicates are
+// only detected when the queue is flushed, so none are reported.
+func (b *bufferedEventRepository) CreateBatch(ctx context.Context, events []*Event) ([]*Event, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.closed {
+		return nil, ErrEventWriterClosed
+	}
+
+	if b.opts.Backpressure == BackpressureReject && cap(b.queue)-len(b.queue) < len(events) {
+		return nil, ErrEventQueueFull
+	}
+
+	for _, event := range events {
+		if err := b.enqueue(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// GetByClientID implements EventRepository. Queued events are not found
+// until they have been flushed.
+func (b *bufferedEventRepository) GetByClientID(ctx context.Context, featureID int32, clientID string) (*Event, error) {
+	return b.next.GetByClientID(ctx, featureID, clientID)
+}
+
+// List implements EventRepository.
+func (b *bufferedEventRepository) List(ctx context.Context, filter EventFilter) ([]*Event, error) {
+	return b.next.List(ctx, filter)
+}
+
+// Aggregate implements EventRepository.
+func (b *bufferedEventRepository) Aggregate(ctx context.Context, featureID int32, metrics []*Metric) (*EventAggregate, error) {
+	return b.next.Aggregate(ctx, featureID, metrics)
+}
+
+// AggregateCovariates implements EventRepository.
+func (b *bufferedEventRepository) AggregateCovariates(
+	ctx context.Context,
+	featureID int32,
+	metrics []*Metric,
+	lookback time.Duration,
+) ([]CovariateAggregate, error) {
+	return b.next.AggregateCovariates(ctx, featureID, metrics, lookback)
+}
+
+// CountExposures implements EventRepository.
+func (b *bufferedEventRepository) CountExposures(ctx context.Context, featureID int32) ([]ExposureAggregate, error) {
+	return b.next.CountExposures(ctx, featureID)
+}
+
+// Close stops accepting events and flushes everything still queued. It
+// returns early with the context error if ctx expires before the queue is
+// drained.
+func (b *bufferedEventRepository) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.queue)
+		close(b.stop)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("draining event queue: %w", ctx.Err())
+	}
+}
+
+func (b *bufferedEventRepository) enqueue(ctx context.Context, event *Event) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	select {
+	case b.queue <- event:
+		return nil
+	default:
+	}
+
+	switch b.opts.Backpressure {
+	case BackpressureReject:
+		return ErrEventQueueFull
+	case BackpressureDropOldest:
+		for {
+			select {
+			case b.queue <- event:
+				return nil
+			default:
+			}
+
+			select {
+			case <-b.queue:
+				b.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case b.queue <- event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *bufferedEventRepository) run() {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.opts.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Event, 0, b.opts.BatchSize)
+	for {
+		select {
+		case event, ok := <-b.queue:
+			if !ok {
+				b.flush(batch)
+				return
+			}
+
+			batch = append(batch, event)
+			if len(batch) >= b.opts.BatchSize {
+				b.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			b.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (b *bufferedEventRepository) flush(batch []*Event) {
+	if dropped := b.dropped.Swap(0); dropped > 0 {
+		b.logger.Warn("event queue full, dropped oldest events", slog.Uint64("dropped", dropped))
+	}
+
+	if len(batch) == 0 {
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		var err error
+		if batch, err = b.write(batch); err == nil {
+			return
+		}
+
+		if attempt >= b.opts.FlushAttempts {
+			b.logger.Error("failed to flush events, giving them up",
+				slog.Int("events", len(batch)),
+				slog.Int("attempts", attempt),
+				slog.Any("error", err),
+			)
+			return
+		}
+
+		delay := flushRetryDelay(attempt)
+		b.logger.Warn("failed to flush events, retrying",
+			slog.Int("events", len(batch)),
+			slog.Duration("delay", delay),
+			slog.Any("error", err),
+		)
+
+		select {
+		case <-time.After(delay):
+		case <-b.stop:
+			// shutting down: drain without waiting
+		}
+	}
+}
+
+// write returns the events of batch that are still to be written if the
+// database cannot be reached. If the batch is rejected by the database,
+// events are written one by one and those rejected are dropped.
+func (b *bufferedEventRepository) write(batch []*Event) ([]*Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.FlushTimeout)
+	defer cancel()
+
+	duplicates, err := b.next.CreateBatch(ctx, batch)
+	if err == nil {
+		if len(duplicates) > 0 {
+			b.logger.Debug("skipped already recorded events", slog.Int("events", len(duplicates)))
+		}
+		return nil, nil
+	}
+
+	if isUnavailable(err) {
+		return batch, err
+	}
+
+	for i, event := range batch {
+		err := b.next.Create(ctx, event)
+		if isUnavailable(err) {
+			return batch[i:], err
+		}
+
+		if err != nil && !errors.Is(err, ErrEventAlreadyRecorded) {
+			b.logger.Error("dropping event rejected by database",
+				slog.Int("feature_id", int(event.FeatureID)),
+				slog.Any("error", err),
+			)
+		}
+	}
+
+	return nil, nil
+}
+
+// flushRetryDelay is the pause after the given number of failed flushes.
+func flushRetryDelay(attempts int) time.Duration {
+	delay := flushBackoff
+	for i := 1; i < attempts && delay < maxFlushBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxFlushBackoff)
+}
@@ -0,0 +1,164 @@
+package feature
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/eve-an/splitter/internal/cache"
+)
+
+// CacheKeyPrefix namespaces features in shared caches. Its version must be
+// bumped whenever cachedFeature changes incompatibly, so replicas of a new
+// release never read entries written by an old one.
+const CacheKeyPrefix = "splitter:feature:v2:"
+
+// CacheCodec encodes features for shared caches through cachedFeature, so
+// cached entries do not depend on the untagged field names of Feature.
+type CacheCodec struct{}
+
+var _ cache.Codec[*Feature] = CacheCodec{}
+
+type cachedFeature struct {
+	ID                  int32                `json:"id"`
+	Name                string               `json:"name"`
+	Description         string               `json:"description"`
+	Active              bool                 `json:"active"`
+	Kind                Kind                 `json:"kind"`
+	Variants            []cachedVariant      `json:"variants"`
+	Tags                []string             `json:"tags"`
+	Analysis            Analysis             `json:"analysis"`
+	ValueType           ValueType            `json:"value_type"`
+	ValueSchema         json.RawMessage      `json:"value_schema,omitempty"`
+	Bandit              *cachedBandit        `json:"bandit"`
+	Prerequisites       []cachedPrerequisite `json:"prerequisites"`
+	Rules               []cachedRule         `json:"rules"`
+	Overrides           map[string]string    `json:"overrides"`
+	Version             int32                `json:"version"`
+	SampleRatioMismatch bool                 `json:"sample_ratio_mismatch"`
+}
+
+type cachedVariant struct {
+	ID     int32           `json:"id"`
+	Name   string          `json:"name"`
+	Weight uint8           `json:"weight"`
+	Value  json.RawMessage `json:"value,omitempty"`
+}
+
+type cachedBandit struct {
+	EventType string `json:"event_type"`
+	MinWeight uint8  `json:"min_weight"`
+}
+
+type cachedPrerequisite struct {
+	FeatureID int32    `json:"feature_id"`
+	Variants  []string `json:"variants"`
+}
+
+type cachedRule struct {
+	SegmentID int32          `json:"segment_id"`
+	Variant   string         `json:"variant"`
+	Segment   *cachedSegment `json:"segment"`
+}
+
+type cachedSegment struct {
+	ID          int32       `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	UserKeys    []string    `json:"user_keys"`
+	Conditions  []Condition `json:"conditions"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func (CacheCodec) Marshal(feature *Feature) ([]byte, error) {
+	cached := cachedFeature{
+		ID:                  feature.ID,
+		Name:                feature.Name,
+		Description:         feature.Descritption,
+		Active:              feature.Active,
+		Kind:                feature.Kind,
+		Variants:            make([]cachedVariant, len(feature.Variants)),
+		Tags:                feature.Tags,
+		Analysis:            feature.Analysis,
+		ValueType:           feature.ValueType,
+		ValueSchema:         feature.ValueSchema,
+		Prerequisites:       make([]cachedPrerequisite, len(feature.Prerequisites)),
+		Rules:               make([]cachedRule, len(feature.Rules)),
+		Overrides:           feature.Overrides,
+		Version:             feature.Version,
+		SampleRatioMismatch: feature.SampleRatioMismatch,
+	}
+	for i, variant := range feature.Variants {
+		cached.Variants[i] = cachedVariant{ID: variant.ID, Name: variant.Name, Weight: variant.Weight, Value: variant.Value}
+	}
+	if feature.Bandit != nil {
+		cached.Bandit = &cachedBandit{EventType: feature.Bandit.EventType, MinWeight: feature.Bandit.MinWeight}
+	}
+	for i, prerequisite := range feature.Prerequisites {
+		cached.Prerequisites[i] = cachedPrerequisite{FeatureID: prerequisite.FeatureID, Variants: prerequisite.Variants}
+	}
+	for i, rule := range feature.Rules {
+		cached.Rules[i] = cachedRule{SegmentID: rule.SegmentID, Variant: rule.Variant}
+		if segment := rule.Segment; segment != nil {
+			cached.Rules[i].Segment = &cachedSegment{
+				ID:          segment.ID,
+				Name:        segment.Name,
+				Description: segment.Description,
+				UserKeys:    segment.UserKeys,
+				Conditions:  segment.Conditions,
+				CreatedAt:   segment.CreatedAt,
+				UpdatedAt:   segment.UpdatedAt,
+			}
+		}
+	}
+
+	return json.Marshal(cached)
+}
+
+func (CacheCodec) Unmarshal(data []byte) (*Feature, error) {
+	var cached cachedFeature
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, err
+	}
+
+	feature := &Feature{
+		ID:                  cached.ID,
+		Name:                cached.Name,
+		Descritption:        cached.Description,
+		Active:              cached.Active,
+		Kind:                cached.Kind,
+		Tags:                cached.Tags,
+		Analysis:            cached.Analysis,
+		ValueType:           cached.ValueType,
+		ValueSchema:         cached.ValueSchema,
+		Overrides:           cached.Overrides,
+		Version:             cached.Version,
+		SampleRatioMismatch: cached.SampleRatioMismatch,
+	}
+	for _, variant := range cached.Variants {
+		feature.Variants = append(feature.Variants, Variant{ID: variant.ID, Name: variant.Name, Weight: variant.Weight, Value: variant.Value})
+	}
+	if cached.Bandit != nil {
+		feature.Bandit = &Bandit{EventType: cached.Bandit.EventType, MinWeight: cached.Bandit.MinWeight}
+	}
+	for _, prerequisite := range cached.Prerequisites {
+		feature.Prerequisites = append(feature.Prerequisites, Prerequisite{FeatureID: prerequisite.FeatureID, Variants: prerequisite.Variants})
+	}
+	for _, rule := range cached.Rules {
+		decoded := Rule{SegmentID: rule.SegmentID, Variant: rule.Variant}
+		if segment := rule.Segment; segment != nil {
+			decoded.Segment = &Segment{
+				ID:          segment.ID,
+				Name:        segment.Name,
+				Description: segment.Description,
+				UserKeys:    segment.UserKeys,
+				Conditions:  segment.Conditions,
+				CreatedAt:   segment.CreatedAt,
+				UpdatedAt:   segment.UpdatedAt,
+			}
+		}
+		feature.Rules = append(feature.Rules, decoded)
+	}
+
+	return feature, nil
+}
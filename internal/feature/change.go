@@ -0,0 +1,220 @@
+package feature
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+type ChangeType string
+
+const (
+	ChangeCreated ChangeType = "created"
+	ChangeUpdated ChangeType = "updated"
+	ChangeToggled ChangeType = "toggled"
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// Change describes a mutation of a feature. Feature holds the state after the
+// change, or the last known state for deletions. Seq is the id of the change
+// in the ChangeRepository and is shared by all replicas.
+type Change struct {
+	Seq       uint64
+	Type      ChangeType
+	FeatureID int32
+	Feature   *Feature
+	At        time.Time
+}
+
+// ChangeRepository is the log of feature changes of all replicas. The
+// feature repositories record the changes in the transaction of the write,
+// so sequence numbers grow in commit order, possibly with gaps.
+type ChangeRepository interface {
+	// Latest returns the sequence number of the most recent change, or zero.
+	Latest(ctx context.Context) (uint64, error)
+	// List returns up to limit changes after afterSeq and up to untilSeq,
+	// oldest first. Feature is nil for changes recorded without a state.
+	List(ctx context.Context, afterSeq, untilSeq uint64, limit int) ([]Change, error)
+	// Retains reports whether no change after seq has been pruned yet.
+	Retains(ctx context.Context, seq uint64) (bool, error)
+	// Wait blocks until a change is recorded or ctx is done.
+	Wait(ctx context.Context) error
+	// QueueWebhooks claims up to limit changes whose webhook deliveries are
+	// not queued yet and queues the deliveries built by payload in the same
+	// transaction that marks the changes as queued. It returns the number of
+	// claimed changes.
+	QueueWebhooks(ctx context.Context, limit int, payload WebhookPayloadFunc) (int, error)
+	// Prune deletes the changes recorded before the given time, whether
+	// their webhook deliveries have been queued or not.
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookPayloadFunc builds the webhook event and payload of a change.
+type WebhookPayloadFunc func(ctx context.Context, change Change) (WebhookEvent, []byte, error)
+
+// ChangeFeed fans out feature changes to in-process subscribers and keeps a
+// bounded history so that subscribers can resume after a reconnect. It is
+// fed by the ChangeRelay.
+type ChangeFeed struct {
+	mu  sync.Mutex
+	seq uint64
+	// since is the sequence number after which history holds every change.
+	since       uint64
+	history     []Change
+	historySize int
+	subscribers map[chan Change]struct{}
+}
+
+func NewChangeFeed(historySize int) *ChangeFeed {
+	if historySize <= 0 {
+		historySize = 256
+	}
+
+	return &ChangeFeed{
+		history:     make([]Change, 0, historySize),
+		historySize: historySize,
+		subscribers: make(map[chan Change]struct{}),
+	}
+}
+
+// Advance moves the feed to seq without publishing anything, so that the
+// changes up to seq are not replayed from the history.
+func (f *ChangeFeed) Advance(seq uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if seq <= f.seq {
+		return
+	}
+
+	f.seq = seq
+	f.since = seq
+	f.history = f.history[:0]
+}
+
+// Publish delivers c to all subscribers. Changes must be published in the
+// order of their sequence numbers; those not after the last one are
+// ignored. Subscribers that cannot keep up are dropped; their channel is
+// closed and they are expected to resubscribe from their last sequence.
+func (f *ChangeFeed) Publish(c Change) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if c.Seq <= f.seq {
+		return
+	}
+
+	f.seq = c.Seq
+	if c.At.IsZero() {
+		c.At = time.Now()
+	}
+
+	if len(f.history) == f.historySize {
+		f.since = f.history[0].Seq
+		f.history = slices.Delete(f.history, 0, 1)
+	}
+	f.history = append(f.history, c)
+
+	for ch := range f.subscribers {
+		select {
+		case ch <- c:
+		default:
+			delete(f.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscription is a live view on a ChangeFeed.
+type Subscription struct {
+	// Seq is the sequence number of the last change published before the
+	// subscription was created.
+	Seq uint64
+	// Backlog holds the changes after the requested sequence number.
+	Backlog []Change
+	// Complete is false if part of the requested backlog has already been
+	// evicted from the history; the subscriber should start from a snapshot.
+	Complete bool
+	// C receives all changes published after Seq. It is closed when the
+	// subscriber falls behind or the subscription is closed.
+	C <-chan Change
+
+	close func()
+}
+
+func (s *Subscription) Close() {
+	s.close()
+}
+
+// Subscribe registers a new subscriber that resumes after lastSeq. A lastSeq
+// of zero never yields a complete backlog.
+func (f *ChangeFeed) Subscribe(lastSeq uint64) *Subscription {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan Change, 64)
+	f.subscribers[ch] = struct{}{}
+
+	sub := &Subscription{
+		Seq: f.seq,
+		C:   ch,
+		close: func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+
+			if _, ok := f.subscribers[ch]; ok {
+				delete(f.subscribers, ch)
+				close(ch)
+			}
+		},
+	}
+
+	if lastSeq == 0 || lastSeq > f.seq || lastSeq < f.since {
+		return sub
+	}
+
+	for _, c := range f.history {
+		if c.Seq > lastSeq {
+			sub.Backlog = append(sub.Backlog, c)
+		}
+	}
+	sub.Complete = true
+
+	return sub
+}
+
+// Seq returns the sequence number of the most recent change.
+func (f *ChangeFeed) Seq() uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.seq
+}
+
+func changeTypeFor(previous, current *Feature) ChangeType {
+	if previous.Active == current.Active {
+		return ChangeUpdated
+	}
+
+	if previous.Name != current.Name || previous.Descritption != current.Descritption {
+		return ChangeUpdated
+	}
+
+	if !slices.Equal(previous.Tags, current.Tags) {
+		return ChangeUpdated
+	}
+
+	if previous.Kind != current.Kind || previous.ValueType != current.ValueType || !bytes.Equal(previous.ValueSchema, current.ValueSchema) {
+		return ChangeUpdated
+	}
+
+	if !slices.EqualFunc(previous.Variants, current.Variants, func(a, b Variant) bool {
+		return a.Name == b.Name && a.Weight == b.Weight && bytes.Equal(a.Value, b.Value)
+	}) {
+		return ChangeUpdated
+	}
+
+	return ChangeToggled
+}
@@ -0,0 +1,205 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+type ChangeRelayOptions struct {
+	// PollInterval bounds the delay of changes whose notification was lost
+	// and the pause before listening again after a failure.
+	PollInterval time.Duration
+	// Retention is how long recorded changes stay available to resuming
+	// subscribers.
+	Retention time.Duration
+	// BatchSize is the number of changes read at once.
+	BatchSize int
+}
+
+// ChangeRelay publishes the changes recorded by all replicas to the change
+// feed of its service, evicting the changed features from the cache on the
+// way. It runs on every replica and prunes the changes past the retention.
+type ChangeRelay struct {
+	*periodic
+
+	svc      *Service
+	opts     ChangeRelayOptions
+	logger   *slog.Logger
+	cancel   context.CancelFunc
+	relaying chan struct{}
+}
+
+func NewChangeRelay(svc *Service, opts ChangeRelayOptions, logger *slog.Logger) *ChangeRelay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = 24 * time.Hour
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &ChangeRelay{
+		svc:      svc,
+		opts:     opts,
+		logger:   logger,
+		cancel:   cancel,
+		relaying: make(chan struct{}),
+	}
+
+	r.periodic = startPeriodic("change pruning", time.Hour, r.prune)
+	go r.relay(ctx)
+
+	return r
+}
+
+// Close stops relaying and pruning changes.
+func (r *ChangeRelay) Close(ctx context.Context) error {
+	r.cancel()
+	if err := r.periodic.Close(ctx); err != nil {
+		return err
+	}
+
+	select {
+	case <-r.relaying:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stopping change relay: %w", ctx.Err())
+	}
+}
+
+func (r *ChangeRelay) relay(ctx context.Context) {
+	defer close(r.relaying)
+
+	lastSeq, err := r.latest(ctx)
+	if err != nil {
+		return
+	}
+	r.svc.changes.Advance(lastSeq)
+
+	notified := make(chan struct{}, 1)
+	listening := make(chan struct{})
+	go r.listen(ctx, notified, listening)
+	defer func() { <-listening }()
+
+	// the poll picks up changes whose notification was lost
+	ticker := time.NewTicker(r.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		lastSeq = r.relayFrom(ctx, lastSeq)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-notified:
+		case <-ticker.C:
+		}
+	}
+}
+
+// listen keeps waiting for notifications of recorded changes on a single
+// connection until ctx is done, signalling each one on notified.
+func (r *ChangeRelay) listen(ctx context.Context, notified chan<- struct{}, listening chan<- struct{}) {
+	defer close(listening)
+
+	for {
+		err := r.svc.changeRepo.Wait(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			r.logger.Warn("waiting for feature changes failed", slog.Any("error", err))
+			sleep(ctx, r.opts.PollInterval)
+			continue
+		}
+
+		select {
+		case notified <- struct{}{}:
+		default:
+			// a relay is pending already
+		}
+	}
+}
+
+// latest returns the most recent recorded change, retrying until it can be
+// read or ctx is done.
+func (r *ChangeRelay) latest(ctx context.Context) (uint64, error) {
+	for {
+		seq, err := r.svc.changeRepo.Latest(ctx)
+		if err == nil {
+			return seq, nil
+		}
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		r.logger.Error("reading latest feature change failed", slog.Any("error", err))
+		sleep(ctx, r.opts.PollInterval)
+	}
+}
+
+// relayFrom publishes the changes after lastSeq and returns the sequence
+// number of the last one published.
+func (r *ChangeRelay) relayFrom(ctx context.Context, lastSeq uint64) uint64 {
+	for {
+		changes, err := r.svc.changeRepo.List(ctx, lastSeq, math.MaxUint64, r.opts.BatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Error("reading feature changes failed", slog.Any("error", err))
+			}
+			return lastSeq
+		}
+
+		for _, change := range changes {
+			r.svc.featureCache.Delete(featureCacheKey(change.FeatureID))
+
+			if err := r.svc.loadChangedFeature(ctx, &change); err != nil {
+				r.logger.Error("loading changed feature failed",
+					slog.Int("feature_id", int(change.FeatureID)),
+					slog.Any("error", err),
+				)
+				return lastSeq
+			}
+
+			r.svc.changes.Publish(change)
+			lastSeq = change.Seq
+		}
+
+		if len(changes) < r.opts.BatchSize {
+			return lastSeq
+		}
+	}
+}
+
+func (r *ChangeRelay) prune(stop <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	deleted, err := r.svc.changeRepo.Prune(ctx, time.Now().Add(-r.opts.Retention))
+	if err != nil {
+		r.logger.Error("pruning feature changes failed", slog.Any("error", err))
+		return
+	}
+
+	if deleted > 0 {
+		r.logger.Info("pruned feature changes", slog.Int64("deleted", deleted))
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
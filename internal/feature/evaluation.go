@@ -0,0 +1,263 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"slices"
+)
+
+// EvaluationReason tells why a user was or was not assigned a variant.
+type EvaluationReason string
+
+const (
+	// ReasonInactive means the feature is switched off.
+	ReasonInactive EvaluationReason = "inactive"
+	// ReasonOverride means the user is forced into a variant.
+	ReasonOverride EvaluationReason = "override"
+	// ReasonPrerequisiteFailed means the user was not assigned one of the
+	// required variants of a prerequisite.
+	ReasonPrerequisiteFailed EvaluationReason = "prerequisite_failed"
+	// ReasonRule means the user is in the segment of a rule.
+	ReasonRule EvaluationReason = "rule"
+	// ReasonRolloutExcluded means the bucket of the user is beyond the total
+	// weight of the variants, so the feature is not rolled out to them.
+	ReasonRolloutExcluded EvaluationReason = "rollout_excluded"
+	// ReasonBucket means the bucket of the user fell in a variant.
+	ReasonBucket EvaluationReason = "bucket"
+)
+
+// EvaluationCheck names a step of an evaluation.
+type EvaluationCheck string
+
+const (
+	CheckActive       EvaluationCheck = "active"
+	CheckOverride     EvaluationCheck = "override"
+	CheckPrerequisite EvaluationCheck = "prerequisite"
+	CheckRule         EvaluationCheck = "rule"
+	CheckBucket       EvaluationCheck = "bucket"
+	CheckFallback     EvaluationCheck = "fallback"
+)
+
+// EvaluationStep is one check made while evaluating a feature. Steps of
+// prerequisites are recorded between the step announcing the prerequisite
+// and the step telling whether it was met.
+type EvaluationStep struct {
+	FeatureID int32
+	Feature   string
+	Check     EvaluationCheck
+	// Matched reports whether the check applied to the user: the feature is
+	// active, the user has an override, the prerequisite is met, the user is
+	// in the segment of the rule or their bucket fell in a variant.
+	Matched bool
+	Detail  string
+}
+
+// evaluationTrace collects the steps of an evaluation. A nil trace records
+// nothing, so plain evaluations do not pay for formatting the details.
+type evaluationTrace struct {
+	steps []EvaluationStep
+}
+
+func (t *evaluationTrace) record(feature *Feature, check EvaluationCheck, matched bool, format string, args ...any) {
+	if t == nil {
+		return
+	}
+
+	t.steps = append(t.steps, EvaluationStep{
+		FeatureID: feature.ID,
+		Feature:   feature.Name,
+		Check:     check,
+		Matched:   matched,
+		Detail:    fmt.Sprintf(format, args...),
+	})
+}
+
+// EvaluationContext describes the user a feature is evaluated for. Key is
+// the user id overrides, segments and bucketing use; Attributes are matched
+// by the conditions of segments.
+type EvaluationContext struct {
+	Key        string
+	Attributes map[string]string
+}
+
+// Evaluation is the outcome of evaluating a feature for a user.
+type Evaluation struct {
+	FeatureID int32
+	Feature   string
+	Reason    EvaluationReason
+	// Variant is only set for ReasonOverride, ReasonRule and ReasonBucket,
+	// except for boolean features, which fall back to their variant off.
+	Variant *Variant
+	// Bucket is the bucket of the user, from 0 to 99, for
+	// ReasonRolloutExcluded and ReasonBucket.
+	Bucket *uint8
+	// Prerequisite is the prerequisite that failed for
+	// ReasonPrerequisiteFailed.
+	Prerequisite *PrerequisiteResult
+	// Rule is the matching rule for ReasonRule.
+	Rule *RuleMatch
+}
+
+// RuleMatch identifies the rule of a feature a user matched.
+type RuleMatch struct {
+	// Index is the position of the rule, starting at 0.
+	Index     int
+	SegmentID int32
+	Segment   string
+}
+
+// PrerequisiteResult explains a failed prerequisite with the evaluation of
+// the prerequisite feature for the same user.
+type PrerequisiteResult struct {
+	Prerequisite
+	Evaluation *Evaluation
+}
+
+// Evaluate assigns a user a variant of a feature. Overrides of active
+// features take precedence over everything else. Otherwise the
+// prerequisites are evaluated first, and the first failing one is reported,
+// then the rules and finally the user is bucketed. Boolean features serve
+// their variant off to users they would otherwise serve no variant.
+func (s *Service) Evaluate(ctx context.Context, featureID int32, user EvaluationContext) (*Evaluation, error) {
+	feature, err := s.GetFeature(ctx, featureID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.evaluate(ctx, feature, user, make(map[int32]struct{}), nil)
+}
+
+// ExplainEvaluation evaluates a feature like Evaluate and also returns every
+// check made on the way to the result, in order.
+func (s *Service) ExplainEvaluation(ctx context.Context, featureID int32, user EvaluationContext) (*Evaluation, []EvaluationStep, error) {
+	feature, err := s.GetFeature(ctx, featureID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trace := &evaluationTrace{}
+	evaluation, err := s.evaluate(ctx, feature, user, make(map[int32]struct{}), trace)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return evaluation, trace.steps, nil
+}
+
+func (s *Service) evaluate(ctx context.Context, feature *Feature, user EvaluationContext, evaluating map[int32]struct{}, trace *evaluationTrace) (*Evaluation, error) {
+	evaluation, err := s.assign(ctx, feature, user, evaluating, trace)
+	if err != nil || evaluation.Variant != nil || feature.Kind != KindBoolean {
+		return evaluation, err
+	}
+
+	if off, found := variantByName(feature.Variants, BooleanOff); found {
+		trace.record(feature, CheckFallback, true, "boolean feature serves variant %q", off.Name)
+		evaluation.Variant = off
+	}
+
+	return evaluation, nil
+}
+
+// assign runs the checks of an evaluation until one decides the variant of
+// the user, if any.
+func (s *Service) assign(ctx context.Context, feature *Feature, user EvaluationContext, evaluating map[int32]struct{}, trace *evaluationTrace) (*Evaluation, error) {
+	evaluation := &Evaluation{FeatureID: feature.ID, Feature: feature.Name}
+	if !feature.Active {
+		trace.record(feature, CheckActive, false, "feature is inactive")
+		evaluation.Reason = ReasonInactive
+		return evaluation, nil
+	}
+	trace.record(feature, CheckActive, true, "feature is active")
+
+	if variant, found := overrideFor(feature, user.Key); found {
+		trace.record(feature, CheckOverride, true, "user %q is overridden to variant %q", user.Key, variant.Name)
+		evaluation.Reason = ReasonOverride
+		evaluation.Variant = variant
+		return evaluation, nil
+	}
+	trace.record(feature, CheckOverride, false, "user %q has no override", user.Key)
+
+	evaluating[feature.ID] = struct{}{}
+	defer delete(evaluating, feature.ID)
+
+	for _, prerequisite := range feature.Prerequisites {
+		// cycles are rejected on write, but concurrent writes can still close one
+		if _, found := evaluating[prerequisite.FeatureID]; found {
+			return nil, ErrPrerequisiteCycle
+		}
+
+		required, err := s.GetFeature(ctx, prerequisite.FeatureID)
+		if err != nil {
+			return nil, fmt.Errorf("get prerequisite: %w", err)
+		}
+
+		trace.record(feature, CheckPrerequisite, false, "evaluating prerequisite %q, requiring one of %v", required.Name, prerequisite.Variants)
+		result, err := s.evaluate(ctx, required, user, evaluating, trace)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.Variant == nil || !slices.Contains(prerequisite.Variants, result.Variant.Name) {
+			trace.record(feature, CheckPrerequisite, false, "prerequisite %q failed with reason %s", required.Name, result.Reason)
+			evaluation.Reason = ReasonPrerequisiteFailed
+			evaluation.Prerequisite = &PrerequisiteResult{Prerequisite: prerequisite, Evaluation: result}
+			return evaluation, nil
+		}
+		trace.record(feature, CheckPrerequisite, true, "prerequisite %q is met with variant %q", required.Name, result.Variant.Name)
+	}
+
+	for i, rule := range feature.Rules {
+		if rule.Segment == nil {
+			continue
+		}
+
+		contained, why := rule.Segment.explain(user)
+		if !contained {
+			trace.record(feature, CheckRule, false, "rule %d: %s", i, why)
+			continue
+		}
+
+		variant, found := variantByName(feature.Variants, rule.Variant)
+		if !found {
+			trace.record(feature, CheckRule, false, "rule %d: variant %q no longer exists", i, rule.Variant)
+			continue
+		}
+
+		trace.record(feature, CheckRule, true, "rule %d: %s, assigning variant %q", i, why, variant.Name)
+		evaluation.Reason = ReasonRule
+		evaluation.Variant = variant
+		evaluation.Rule = &RuleMatch{Index: i, SegmentID: rule.SegmentID, Segment: rule.Segment.Name}
+		return evaluation, nil
+	}
+
+	bucketed := UserFromString(user.Key)
+	bucket := bucketForUser(&bucketed, feature)
+	evaluation.Bucket = &bucket
+
+	variant, found := variantForBucket(feature.Variants, bucket)
+	if !found {
+		trace.record(feature, CheckBucket, false, "bucket %d is beyond the total weight %d", bucket, feature.Variants.TotalWeight())
+		evaluation.Reason = ReasonRolloutExcluded
+		return evaluation, nil
+	}
+
+	trace.record(feature, CheckBucket, true, "bucket %d fell in variant %q", bucket, variant.Name)
+	evaluation.Reason = ReasonBucket
+	evaluation.Variant = variant
+
+	return evaluation, nil
+}
+
+// variantForBucket walks the cumulative weights of the variants. The buckets
+// past the total weight of a partial rollout fall in no variant.
+func variantForBucket(variants Variants, bucket uint8) (*Variant, bool) {
+	var cumulative uint32
+	for i := range variants {
+		cumulative += uint32(variants[i].Weight)
+		if uint32(bucket) < cumulative {
+			return &variants[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -2,12 +2,25 @@ package feature
 
 import (
 	"errors"
+	"math"
 	"time"
 )
 
+const (
+	// maxClientIDLength bounds client supplied event ids, which are indexed.
+	maxClientIDLength = 128
+	// maxEventProperties bounds the number of top level event properties.
+	maxEventProperties = 64
+)
+
 var (
 	ErrEventFeatureIDRequired = errors.New("event feature id is required")
 	ErrEventTypeRequired      = errors.New("event type is required")
+	ErrEventClientIDTooLong   = errors.New("event id is too long")
+	ErrEventAlreadyRecorded   = errors.New("event already recorded")
+	ErrEventNotFound          = errors.New("event not found")
+	ErrEventValueInvalid      = errors.New("event value must be a finite number")
+	ErrEventTooManyProperties = errors.New("event has too many properties")
 )
 
 type Event struct {
@@ -17,6 +30,17 @@ type Event struct {
 	Variant   string
 	Type      string
 	CreatedAt time.Time
+	// ClientID is an optional id chosen by the client. It is unique per
+	// feature, so retried requests do not record the same event twice.
+	ClientID string
+	// Value is an optional amount carried by the event, such as the revenue
+	// of a purchase. Results sum it per user.
+	Value *float64
+	// Properties holds arbitrary attributes of the event.
+	Properties map[string]any
+	// VariantMismatch is set on events recorded even though their variant
+	// failed validation, see EventValidation.
+	VariantMismatch bool
 }
 
 func NewEvent(featureID int32, userID, variant, eventType string) (*Event, error) {
@@ -41,5 +65,17 @@ func (e *Event) Validate() error {
 		errs = append(errs, ErrEventTypeRequired)
 	}
 
+	if len(e.ClientID) > maxClientIDLength {
+		errs = append(errs, ErrEventClientIDTooLong)
+	}
+
+	if e.Value != nil && (math.IsNaN(*e.Value) || math.IsInf(*e.Value, 0)) {
+		errs = append(errs, ErrEventValueInvalid)
+	}
+
+	if len(e.Properties) > maxEventProperties {
+		errs = append(errs, ErrEventTooManyProperties)
+	}
+
 	return errors.Join(errs...)
 }
@@ -0,0 +1,39 @@
+package feature
+
+import "errors"
+
+const MaxEventBatchSize = 5000
+
+var (
+	ErrEventBatchEmpty    = errors.New("event batch is empty")
+	ErrEventBatchTooLarge = errors.New("event batch is too large")
+)
+
+// BatchItemError reports why the event at Index of a batch was rejected.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+// BatchDuplicate reports that the event at Index has a client id that was
+// already recorded. Event is the originally recorded event.
+type BatchDuplicate struct {
+	Index int
+	Event *Event
+}
+
+type BatchResult struct {
+	Accepted   int
+	Errors     []BatchItemError
+	Duplicates []BatchDuplicate
+}
+
+// clientEventKey identifies an event by its client id.
+type clientEventKey struct {
+	featureID int32
+	clientID  string
+}
+
+func clientKeyOf(e *Event) clientEventKey {
+	return clientEventKey{featureID: e.FeatureID, clientID: e.ClientID}
+}
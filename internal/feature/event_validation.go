@@ -0,0 +1,129 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrUnknownVariant  = errors.New("variant does not exist on feature")
+	ErrVariantMismatch = errors.New("variant does not match the user's assignment")
+)
+
+// VariantValidation decides how strictly the variant of a recorded event is
+// checked against its feature.
+type VariantValidation string
+
+const (
+	// VariantValidationOff accepts any variant.
+	VariantValidationOff VariantValidation = "off"
+	// VariantValidationExists requires the variant to exist on the feature.
+	VariantValidationExists VariantValidation = "exists"
+	// VariantValidationAssignment additionally requires the variant to be
+	// the one the evaluation of the feature serves the user of the event,
+	// taking overrides, prerequisites and rules into account.
+	VariantValidationAssignment VariantValidation = "assignment"
+)
+
+// VariantMismatchPolicy decides what happens to events failing validation.
+type VariantMismatchPolicy string
+
+const (
+	// VariantMismatchReject fails the event with ErrUnknownVariant or
+	// ErrVariantMismatch.
+	VariantMismatchReject VariantMismatchPolicy = "reject"
+	// VariantMismatchFlag records the event with VariantMismatch set.
+	// Flagged exposures are left out of results.
+	VariantMismatchFlag VariantMismatchPolicy = "flag"
+)
+
+// EventValidation configures the checks RecordEvent and RecordEvents apply
+// on top of Event.Validate. The zero value disables them.
+type EventValidation struct {
+	Variant  VariantValidation
+	Mismatch VariantMismatchPolicy
+}
+
+func (v EventValidation) enabled() bool {
+	return v.Variant == VariantValidationExists || v.Variant == VariantValidationAssignment
+}
+
+// validateEvent checks the variant of event against feature and, depending
+// on the mismatch policy of the EventValidation, returns the failure or
+// flags the event. Events without a variant are not checked, and neither
+// are assignments of events without a user, of inactive features or of
+// features whose evaluation depends on user attributes, which events do not
+// carry.
+func (s *Service) validateEvent(ctx context.Context, feature *Feature, event *Event) error {
+	if !s.validation.enabled() || event.Variant == "" {
+		return nil
+	}
+
+	err := s.checkVariant(ctx, feature, event)
+	if err == nil || !(errors.Is(err, ErrUnknownVariant) || errors.Is(err, ErrVariantMismatch)) {
+		return err
+	}
+
+	if s.validation.Mismatch == VariantMismatchFlag {
+		event.VariantMismatch = true
+		return nil
+	}
+
+	return err
+}
+
+func (s *Service) checkVariant(ctx context.Context, feature *Feature, event *Event) error {
+	if !slices.Contains(feature.Variants.Names(), event.Variant) {
+		return ErrUnknownVariant
+	}
+
+	if s.validation.Variant != VariantValidationAssignment || event.UserID == "" || !feature.Active {
+		return nil
+	}
+
+	attributed, err := s.dependsOnAttributes(ctx, feature, make(map[int32]struct{}))
+	if err != nil || attributed {
+		return err
+	}
+
+	evaluation, err := s.evaluate(ctx, feature, EvaluationContext{Key: event.UserID}, make(map[int32]struct{}), nil)
+	if err != nil {
+		return fmt.Errorf("evaluate feature: %w", err)
+	}
+
+	if evaluation.Variant == nil || evaluation.Variant.Name != event.Variant {
+		return ErrVariantMismatch
+	}
+
+	return nil
+}
+
+// dependsOnAttributes reports whether a rule of feature or of one of its
+// prerequisites targets a segment with conditions on user attributes.
+func (s *Service) dependsOnAttributes(ctx context.Context, feature *Feature, seen map[int32]struct{}) (bool, error) {
+	if _, found := seen[feature.ID]; found {
+		return false, nil
+	}
+	seen[feature.ID] = struct{}{}
+
+	for _, rule := range feature.Rules {
+		if rule.Segment != nil && len(rule.Segment.Conditions) > 0 {
+			return true, nil
+		}
+	}
+
+	for _, prerequisite := range feature.Prerequisites {
+		required, err := s.GetFeature(ctx, prerequisite.FeatureID)
+		if err != nil {
+			return false, fmt.Errorf("get prerequisite: %w", err)
+		}
+
+		if attributed, err := s.dependsOnAttributes(ctx, required, seen); err != nil || attributed {
+			return attributed, err
+		}
+	}
+
+	return false, nil
+}
@@ -2,10 +2,14 @@ package feature
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"slices"
 	"strconv"
+	"strings"
 )
 
 const maximumWeight = 100
@@ -13,14 +17,55 @@ const maximumWeight = 100
 var (
 	ErrMaximumWeightExceeded = errors.New("maximum weight exceeded")
 	ErrVariantAlreadyExist   = errors.New("variant with the same name exist")
+	ErrEmptyTag              = errors.New("tags must not be empty")
+	ErrInvalidAnalysis       = errors.New("invalid analysis mode")
 )
 
+// Analysis decides how the results of a feature compare its variants.
+type Analysis string
+
+const (
+	// AnalysisFixed uses fixed horizon tests, which are only valid when
+	// looked at once, after a sample size decided up front.
+	AnalysisFixed Analysis = "fixed"
+	// AnalysisSequential uses mixture sequential probability ratio tests,
+	// whose p-values and intervals stay valid however often results are
+	// looked at, at the cost of some power.
+	AnalysisSequential Analysis = "sequential"
+)
+
+func (a Analysis) Valid() bool {
+	return a == AnalysisFixed || a == AnalysisSequential
+}
+
 type Feature struct {
 	ID           int32
 	Name         string
 	Descritption string
 	Active       bool
+	Kind         Kind
 	Variants     Variants
+	Tags         []string
+	Analysis     Analysis
+	// ValueType is the type of the values of the variants.
+	ValueType ValueType
+	// ValueSchema is the JSON schema the values of json features match, if
+	// any.
+	ValueSchema json.RawMessage
+	// Bandit, if set, lets the BanditAllocator manage the variant weights.
+	Bandit *Bandit
+	// Prerequisites must all pass before a user is bucketed.
+	Prerequisites []Prerequisite
+	// Rules serve variants to the users of segments.
+	Rules []Rule
+	// Overrides maps user keys to the name of the variant they are forced
+	// into. They are managed on their own and kept on updates.
+	Overrides map[string]string
+	// Version is incremented by the repository on every update.
+	Version int32
+	// SampleRatioMismatch is set by the periodic sample ratio check while the
+	// exposures of the feature do not match its variant weights.
+	SampleRatioMismatch bool
 }
 
 func NewFeature(
@@ -38,7 +83,9 @@ func NewFeature(
 		Name:         name,
 		Descritption: description,
 		Active:       active,
+		Kind:         KindExperiment,
 		Variants:     variantList,
+		Analysis:     AnalysisFixed,
 	}
 
 	return f, f.Validate()
@@ -68,6 +115,29 @@ func (f *Feature) Validate() error {
 		errs = append(errs, ErrMaximumWeightExceeded)
 	}
 
+	if slices.Contains(f.Tags, "") {
+		errs = append(errs, ErrEmptyTag)
+	}
+
+	if !f.Analysis.Valid() {
+		errs = append(errs, ErrInvalidAnalysis)
+	}
+
+	errs = append(errs, validateValues(f.ValueType, f.ValueSchema, f.Variants))
+	errs = append(errs, f.validateKind())
+
+	if f.Bandit != nil {
+		errs = append(errs, f.Bandit.validate(f.Variants))
+	}
+
+	if len(f.Prerequisites) > 0 {
+		errs = append(errs, validatePrerequisites(f.ID, f.Prerequisites))
+	}
+
+	if len(f.Rules) > 0 {
+		errs = append(errs, validateRules(f.Variants, f.Rules))
+	}
+
 	uniqueNames := make(map[string]struct{}, len(f.Variants))
 	for _, name := range f.Variants.Names() {
 		if _, found := uniqueNames[name]; !found {
@@ -82,8 +152,23 @@ func (f *Feature) Validate() error {
 	return errors.Join(errs...)
 }
 
+// Hash identifies the persisted state of the feature. It changes whenever
+// the feature is updated.
 func (f *Feature) Hash() string {
-	return strconv.FormatInt(int64(f.ID), 10)
+	return strconv.FormatInt(int64(f.ID), 10) + "." + strconv.FormatInt(int64(f.Version), 10)
+}
+
+// HashFeatures combines the hashes of all features into a single value that
+// does not depend on their order.
+func HashFeatures(features []*Feature) string {
+	hashes := make([]string, len(features))
+	for i, f := range features {
+		hashes[i] = f.Hash()
+	}
+	slices.Sort(hashes)
+
+	sum := sha256.Sum256([]byte(strings.Join(hashes, ",")))
+	return hex.EncodeToString(sum[:16])
 }
 
 func featureHashForUser(u *User, f *Feature) uint64 {
@@ -96,22 +181,7 @@ func featureHashForUser(u *User, f *Feature) uint64 {
 	return binary.LittleEndian.Uint64(sum[:8])
 }
 
-// VariantForUser determines the deterministically computed variant assignment for a user
-func VariantForUser(u *User, feature *Feature) *Variant {
-	bucket := uint8(featureHashForUser(u, feature) % 100)
-
-	var cumulative uint8
-	for i, v := range feature.Variants {
-		if v.Weight == 0 {
-			continue
-		}
-
-		cumulative += v.Weight
-
-		if bucket < cumulative || i == len(feature.Variants)-1 {
-			return &v
-		}
-	}
-
-	panic("variants dont add up to " + strconv.FormatInt(maximumWeight, 10))
+// bucketForUser places a user in one of the 100 buckets of a feature.
+func bucketForUser(u *User, f *Feature) uint8 {
+	return uint8(featureHashForUser(u, f) % maximumWeight)
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/eve-an/splitter/internal/cache"
@@ -17,37 +20,216 @@ var (
 
 type FeatureRepository interface {
 	GetByID(ctx context.Context, id int32) (*Feature, error)
-	List(ctx context.Context) ([]*Feature, error)
+	List(ctx context.Context, filter FeatureFilter) ([]*Feature, error)
+	// Create, Update, Delete, SetOverride and RemoveOverride record their
+	// change in the ChangeRepository as part of the write.
 	Create(ctx context.Context, feature *Feature) error
-	Update(ctx context.Context, feature *Feature) error
-	Delete(ctx context.Context, id int32) error
+	Update(ctx context.Context, feature *Feature, change ChangeType) error
+	// Delete deletes a feature, recording feature as its last state.
+	Delete(ctx context.Context, feature *Feature) error
+	// UpdateWeights stores only the weights of the variants of feature,
+	// provided it is still at feature.Version, and increments the version.
+	// It returns ErrFeatureVersionConflict if the feature has been changed
+	// or deleted since.
+	UpdateWeights(ctx context.Context, feature *Feature) error
+	// SetActive stores only whether feature is active, under the same
+	// version check as UpdateWeights.
+	SetActive(ctx context.Context, feature *Feature) error
+	// SetSampleRatioMismatch flags or unflags a feature and reports whether
+	// the flag changed. Changing it increments the version and records a
+	// change.
+	SetSampleRatioMismatch(ctx context.Context, id int32, mismatch bool) (bool, error)
+	RecordReallocation(ctx context.Context, reallocation *Reallocation) error
+	// ListReallocations returns the newest reallocations first. A zero limit
+	// returns all of them.
+	ListReallocations(ctx context.Context, featureID int32, limit int) ([]*Reallocation, error)
+	ListSchedules(ctx context.Context, featureID int32) ([]*Schedule, error)
+	CreateSchedule(ctx context.Context, schedule *Schedule) error
+	// CancelSchedule deletes a pending schedule of a feature.
+	CancelSchedule(ctx context.Context, featureID int32, scheduleID int64) error
+	// ListDueSchedules returns up to limit pending schedules whose time has
+	// passed, oldest first.
+	ListDueSchedules(ctx context.Context, limit int) ([]*Schedule, error)
+	// CompleteSchedule stores the status and error of an executed schedule.
+	CompleteSchedule(ctx context.Context, schedule *Schedule) error
+	// ListDependents returns the names of the features having the feature
+	// as prerequisite.
+	ListDependents(ctx context.Context, id int32) ([]string, error)
+	// ListDependentPrerequisites returns the features having the feature as
+	// prerequisite, by name, with the variants of it they require.
+	ListDependentPrerequisites(ctx context.Context, id int32) ([]Dependent, error)
+	// SetOverride forces a user into a variant and increments the version.
+	SetOverride(ctx context.Context, featureID int32, userKey, variant string) error
+	// RemoveOverride deletes the override of a user and increments the
+	// version.
+	RemoveOverride(ctx context.Context, featureID int32, userKey string) error
 }
 
 type EventRepository interface {
+	// Create returns ErrEventAlreadyRecorded and fills event with the stored
+	// one if its client id has already been recorded.
 	Create(ctx context.Context, event *Event) error
-	ListByFeatureID(ctx context.Context, featureID int32) ([]*Event, error)
+	// CreateBatch returns the stored events for all events whose client id
+	// had already been recorded.
+	CreateBatch(ctx context.Context, events []*Event) ([]*Event, error)
+	// GetByClientID returns the event of a feature recorded with the client
+	// id, or ErrEventNotFound.
+	GetByClientID(ctx context.Context, featureID int32, clientID string) (*Event, error)
+	// Aggregate summarises the events of a feature for its results, either
+	// per metric or, without metrics, per event type.
+	Aggregate(ctx context.Context, featureID int32, metrics []*Metric) (*EventAggregate, error)
+	// AggregateCovariates pairs the values of every metric that is not a
+	// mean metric with the same values over lookback before the exposure.
+	AggregateCovariates(ctx context.Context, featureID int32, metrics []*Metric, lookback time.Duration) ([]CovariateAggregate, error)
+	// CountExposures counts the exposed users of a feature per variant.
+	CountExposures(ctx context.Context, featureID int32) ([]ExposureAggregate, error)
+	List(ctx context.Context, filter EventFilter) ([]*Event, error)
 }
 
 type Service struct {
 	featureRepo  FeatureRepository
 	featureCache cache.Cache[*Feature]
 	eventRepo    EventRepository
+	metricRepo   MetricRepository
+	auditRepo    AuditRepository
+	webhookRepo  WebhookRepository
+	segmentRepo  SegmentRepository
+	changeRepo   ChangeRepository
+	notifier     Notifier
+	validation   EventValidation
+	changes      *ChangeFeed
+	catalog      catalog
+
+	webhookClient *http.Client
+}
+
+// catalog is the list of all features as of the change seq, shared by the
+// streams sending the whole catalog on every change.
+type catalog struct {
+	mu       sync.Mutex
+	loaded   bool
+	seq      uint64
+	features []*Feature
 }
 
 func NewService(
 	featureRepo FeatureRepository,
 	eventRepo EventRepository,
+	metricRepo MetricRepository,
+	auditRepo AuditRepository,
+	webhookRepo WebhookRepository,
+	segmentRepo SegmentRepository,
+	changeRepo ChangeRepository,
 	featureCache cache.Cache[*Feature],
+	notifier Notifier,
+	validation EventValidation,
 ) *Service {
 	return &Service{
 		featureRepo:  featureRepo,
 		featureCache: featureCache,
 		eventRepo:    eventRepo,
+		metricRepo:   metricRepo,
+		auditRepo:    auditRepo,
+		webhookRepo:  webhookRepo,
+		segmentRepo:  segmentRepo,
+		changeRepo:   changeRepo,
+		notifier:     notifier,
+		validation:   validation,
+		changes:      NewChangeFeed(256),
+
+		webhookClient: &http.Client{},
+	}
+}
+
+// maxChangeBacklog bounds the changes replayed to a resuming subscriber;
+// longer backlogs are replaced by a snapshot.
+const maxChangeBacklog = 1000
+
+// SubscribeChanges streams the feature changes of all replicas, resuming
+// after lastSeq from the in-memory history or, when it no longer holds
+// lastSeq, from the ChangeRepository.
+func (s *Service) SubscribeChanges(ctx context.Context, lastSeq uint64) (*Subscription, error) {
+	sub := s.changes.Subscribe(lastSeq)
+	if sub.Complete || lastSeq == 0 || lastSeq > sub.Seq {
+		return sub, nil
+	}
+
+	retained, err := s.changeRepo.Retains(ctx, lastSeq)
+	if err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("get changes: %w", err)
+	}
+	if !retained {
+		return sub, nil
+	}
+
+	backlog, err := s.changeRepo.List(ctx, lastSeq, sub.Seq, maxChangeBacklog+1)
+	if err != nil {
+		sub.Close()
+		return nil, fmt.Errorf("get changes: %w", err)
 	}
+	if len(backlog) > maxChangeBacklog {
+		return sub, nil
+	}
+
+	for i := range backlog {
+		if err := s.loadChangedFeature(ctx, &backlog[i]); err != nil {
+			sub.Close()
+			return nil, err
+		}
+	}
+
+	sub.Backlog = backlog
+	sub.Complete = true
+
+	return sub, nil
+}
+
+// Catalog returns all features as of the change seq or a later one. The
+// features are listed once per change and shared by all callers, which must
+// not modify them.
+func (s *Service) Catalog(ctx context.Context, seq uint64) ([]*Feature, error) {
+	s.catalog.mu.Lock()
+	defer s.catalog.mu.Unlock()
+
+	if s.catalog.loaded && s.catalog.seq >= seq {
+		return s.catalog.features, nil
+	}
+
+	page, err := s.ListFeatures(ctx, FeatureFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	s.catalog.loaded = true
+	s.catalog.seq = seq
+	s.catalog.features = page.Features
+
+	return page.Features, nil
+}
+
+// loadChangedFeature fills in the current state of a feature whose change
+// was recorded without one. The feature stays nil if it has been deleted
+// since.
+func (s *Service) loadChangedFeature(ctx context.Context, change *Change) error {
+	if change.Feature != nil || change.Type == ChangeDeleted {
+		return nil
+	}
+
+	feature, err := s.featureRepo.GetByID(ctx, change.FeatureID)
+	if errors.Is(err, ErrFeatureNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get feature: %w", err)
+	}
+
+	change.Feature = feature
+	return nil
 }
 
 func (s *Service) GetFeature(ctx context.Context, id int32) (*Feature, error) {
-	featureKey := strconv.Itoa(int(id))
+	featureKey := featureCacheKey(id)
 	if feature, ok := s.featureCache.Get(featureKey); ok {
 		return feature, nil
 	}
@@ -62,13 +244,31 @@ func (s *Service) GetFeature(ctx context.Context, id int32) (*Feature, error) {
 	return feature, nil
 }
 
-func (s *Service) ListFeatures(ctx context.Context) ([]*Feature, error) {
-	features, err := s.featureRepo.List(ctx)
+// ListFeatures returns a page of features matching filter. Next is set when
+// more features follow.
+func (s *Service) ListFeatures(ctx context.Context, filter FeatureFilter) (*FeaturePage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("validate filter: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit++
+	}
+
+	features, err := s.featureRepo.List(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("list features: %w", err)
 	}
 
-	return features, nil
+	page := &FeaturePage{Features: features}
+	if limit > 0 && len(features) > limit {
+		page.Features = features[:limit]
+		last := page.Features[limit-1]
+		page.Next = &FeatureCursor{ID: last.ID, Name: last.Name}
+	}
+
+	return page, nil
 }
 
 func (s *Service) CreateFeature(ctx context.Context, feature *Feature) error {
@@ -76,6 +276,14 @@ func (s *Service) CreateFeature(ctx context.Context, feature *Feature) error {
 		return fmt.Errorf("validate feature: %w", err)
 	}
 
+	if err := s.checkPrerequisites(ctx, feature); err != nil {
+		return err
+	}
+
+	if err := s.loadRuleSegments(ctx, feature); err != nil {
+		return err
+	}
+
 	if err := s.featureRepo.Create(ctx, feature); err != nil {
 		return fmt.Errorf("create feature: %w", err)
 	}
@@ -88,10 +296,38 @@ func (s *Service) UpdateFeature(ctx context.Context, feature *Feature) error {
 		return fmt.Errorf("validate feature: %w", err)
 	}
 
-	if err := s.featureRepo.Update(ctx, feature); err != nil {
+	previous, err := s.featureRepo.GetByID(ctx, feature.ID)
+	if err != nil {
+		return fmt.Errorf("get feature: %w", err)
+	}
+
+	// overrides are not part of updates
+	feature.Overrides = previous.Overrides
+
+	// unchanged prerequisites were checked when they were set
+	if !equalPrerequisites(previous.Prerequisites, feature.Prerequisites) {
+		if err := s.checkPrerequisites(ctx, feature); err != nil {
+			return err
+		}
+	}
+
+	// dependents only require variants by name
+	if !slices.Equal(previous.Variants.Names(), feature.Variants.Names()) {
+		if err := s.checkDependents(ctx, feature); err != nil {
+			return err
+		}
+	}
+
+	if err := s.loadRuleSegments(ctx, feature); err != nil {
+		return err
+	}
+
+	if err := s.featureRepo.Update(ctx, feature, changeTypeFor(previous, feature)); err != nil {
 		return fmt.Errorf("update feature: %w", err)
 	}
 
+	s.featureCache.Delete(featureCacheKey(feature.ID))
+
 	return nil
 }
 
@@ -100,6 +336,17 @@ func (s *Service) RecordEvent(ctx context.Context, event *Event) error {
 		return fmt.Errorf("validate event: %w", err)
 	}
 
+	if s.validation.enabled() {
+		feature, err := s.GetFeature(ctx, event.FeatureID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.validateEvent(ctx, feature, event); err != nil {
+			return fmt.Errorf("validate event: %w", err)
+		}
+	}
+
 	if err := s.eventRepo.Create(ctx, event); err != nil {
 		return fmt.Errorf("create event: %w", err)
 	}
@@ -107,19 +354,149 @@ func (s *Service) RecordEvent(ctx context.Context, event *Event) error {
 	return nil
 }
 
-func (s *Service) ListEventsByFeature(ctx context.Context, featureID int32) ([]*Event, error) {
-	events, err := s.eventRepo.ListByFeatureID(ctx, featureID)
+// RecordEvents stores a batch of events, possibly spanning several features.
+// Invalid events, events of unknown features and events rejected by the
+// EventValidation are reported per item and do not prevent the rest of the
+// batch from being stored. Events whose client id
+// was already recorded, before or earlier in the same batch, are reported as
+// duplicates.
+func (s *Service) RecordEvents(ctx context.Context, events []*Event) (*BatchResult, error) {
+	if len(events) == 0 {
+		return nil, ErrEventBatchEmpty
+	}
+
+	if len(events) > MaxEventBatchSize {
+		return nil, ErrEventBatchTooLarge
+	}
+
+	result := &BatchResult{}
+	valid := make([]*Event, 0, len(events))
+	validIndex := make(map[clientEventKey]int)
+	features := make(map[int32]*Feature)
+
+	for i, event := range events {
+		if err := event.Validate(); err != nil {
+			result.Errors = append(result.Errors, BatchItemError{Index: i, Err: err})
+			continue
+		}
+
+		feature, seen := features[event.FeatureID]
+		if !seen {
+			var lookupErr error
+			feature, lookupErr = s.GetFeature(ctx, event.FeatureID)
+			if lookupErr != nil && !errors.Is(lookupErr, ErrFeatureNotFound) {
+				return nil, lookupErr
+			}
+			// unknown features are remembered as nil
+			features[event.FeatureID] = feature
+		}
+
+		if feature == nil {
+			result.Errors = append(result.Errors, BatchItemError{Index: i, Err: ErrFeatureNotFound})
+			continue
+		}
+
+		if err := s.validateEvent(ctx, feature, event); err != nil {
+			if !errors.Is(err, ErrUnknownVariant) && !errors.Is(err, ErrVariantMismatch) {
+				return nil, fmt.Errorf("validate event: %w", err)
+			}
+
+			result.Errors = append(result.Errors, BatchItemError{Index: i, Err: err})
+			continue
+		}
+
+		if event.ClientID != "" {
+			key := clientKeyOf(event)
+			if first, seen := validIndex[key]; seen {
+				result.Duplicates = append(result.Duplicates, BatchDuplicate{Index: i, Event: events[first]})
+				continue
+			}
+			validIndex[key] = i
+		}
+
+		valid = append(valid, event)
+	}
+
+	if len(valid) == 0 {
+		return result, nil
+	}
+
+	originals, err := s.eventRepo.CreateBatch(ctx, valid)
+	if err != nil {
+		return nil, fmt.Errorf("create events: %w", err)
+	}
+
+	for _, original := range originals {
+		result.Duplicates = append(result.Duplicates, BatchDuplicate{
+			Index: validIndex[clientKeyOf(original)],
+			Event: original,
+		})
+	}
+	slices.SortFunc(result.Duplicates, func(a, b BatchDuplicate) int { return a.Index - b.Index })
+
+	result.Accepted = len(valid) - len(originals)
+
+	return result, nil
+}
+
+// ListEventsByFeature returns a page of events matching filter, newest
+// first. Next is set when more events follow.
+func (s *Service) ListEventsByFeature(ctx context.Context, filter EventFilter) (*EventPage, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, fmt.Errorf("validate filter: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit++
+	}
+
+	events, err := s.eventRepo.List(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("list events: %w", err)
 	}
 
-	return events, nil
+	page := &EventPage{Events: events}
+	if limit > 0 && len(events) > limit {
+		page.Events = events[:limit]
+		last := page.Events[limit-1]
+		page.Next = &EventCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	return page, nil
 }
 
 func (s *Service) DeleteFeature(ctx context.Context, id int32) error {
-	if err := s.featureRepo.Delete(ctx, id); err != nil {
+	previous, err := s.GetFeature(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkNotPrerequisite(ctx, id); err != nil {
+		return err
+	}
+
+	if err := s.featureRepo.Delete(ctx, previous); err != nil {
 		return fmt.Errorf("delete feature: %w", err)
 	}
 
+	s.featureCache.Delete(featureCacheKey(id))
+
+	entry := &AuditEntry{
+		FeatureID: id,
+		Actor:     apiActor,
+		Action:    AuditFeatureDeleted,
+		Details:   map[string]any{"name": previous.Name, "version": previous.Version},
+	}
+	if err := s.auditRepo.Record(ctx, entry); err != nil {
+		return fmt.Errorf("record audit entry: %w", err)
+	}
+
 	return nil
 }
+
+// featureCacheKey is shared by all replicas when the cache is distributed,
+// so updates and deletes must evict it rather than wait for the TTL.
+func featureCacheKey(id int32) string {
+	return strconv.Itoa(int(id))
+}
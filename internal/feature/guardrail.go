@@ -0,0 +1,300 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+)
+
+var (
+	ErrGuardrailNotFound         = errors.New("guardrail not found")
+	ErrInvalidGuardrailThreshold = errors.New("guardrail threshold must be a positive number")
+)
+
+// guardrailActor is the actor of audit entries written by the guardrail
+// evaluation.
+const guardrailActor = "guardrail"
+
+// guardrailLockKey identifies the lock held by the replica evaluating
+// guardrails; it is "splitgrd" in ASCII.
+const guardrailLockKey int64 = 0x73706c6974677264
+
+// Guardrail turns its feature off once a variant is significantly worse than
+// the control on Metric and the relative degradation of the variant's mean
+// exceeds Threshold, so 0.1 tolerates a variant being up to 10% worse.
+type Guardrail struct {
+	Metric    Metric
+	Threshold float64
+}
+
+func (g *Guardrail) Validate() error {
+	if !(g.Threshold > 0) || math.IsInf(g.Threshold, 0) {
+		return ErrInvalidGuardrailThreshold
+	}
+
+	return nil
+}
+
+// GuardrailBreach is a variant that breached a guardrail. Degradation is the
+// change of the variant's mean relative to the control, positive when the
+// variant is worse. A control mean of zero makes every regression a breach,
+// with the absolute change as Degradation.
+type GuardrailBreach struct {
+	Metric      string  `json:"metric"`
+	Variant     string  `json:"variant"`
+	Threshold   float64 `json:"threshold"`
+	Degradation float64 `json:"degradation"`
+	PValue      float64 `json:"p_value"`
+}
+
+// ListGuardrails returns the guardrails of a feature.
+func (s *Service) ListGuardrails(ctx context.Context, featureID int32) ([]*Guardrail, error) {
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return nil, err
+	}
+
+	guardrails, err := s.metricRepo.ListGuardrails(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("list guardrails: %w", err)
+	}
+
+	return guardrails, nil
+}
+
+// SetGuardrail adds a guardrail on a metric to a feature or changes its
+// threshold.
+func (s *Service) SetGuardrail(ctx context.Context, featureID int32, guardrail *Guardrail) error {
+	if err := guardrail.Validate(); err != nil {
+		return fmt.Errorf("validate guardrail: %w", err)
+	}
+
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return err
+	}
+
+	metric, err := s.GetMetric(ctx, guardrail.Metric.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.metricRepo.SetGuardrail(ctx, featureID, metric.ID, guardrail.Threshold); err != nil {
+		return fmt.Errorf("set guardrail: %w", err)
+	}
+
+	guardrail.Metric = *metric
+
+	return nil
+}
+
+func (s *Service) RemoveGuardrail(ctx context.Context, featureID, metricID int32) error {
+	if err := s.metricRepo.RemoveGuardrail(ctx, featureID, metricID); err != nil {
+		return fmt.Errorf("remove guardrail: %w", err)
+	}
+
+	return nil
+}
+
+// EvaluateGuardrails compares the variants of a feature on its guardrail
+// metrics and deactivates the feature if any of them is breached, recording
+// the breaches in the audit log and notifying about them. As guardrails are
+// checked over and over, variants are always compared with sequential tests.
+// Only the active flag is stored, and only if the feature has not been
+// changed in the meantime; otherwise ErrFeatureVersionConflict is returned.
+func (s *Service) EvaluateGuardrails(ctx context.Context, id int32) ([]GuardrailBreach, error) {
+	feature, err := s.featureRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get feature: %w", err)
+	}
+
+	guardrails, err := s.metricRepo.ListGuardrails(ctx, feature.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list guardrails: %w", err)
+	}
+	if len(guardrails) == 0 {
+		return nil, nil
+	}
+
+	metrics := make([]*Metric, len(guardrails))
+	for i, guardrail := range guardrails {
+		metrics[i] = &guardrail.Metric
+	}
+
+	aggregate, err := s.eventRepo.Aggregate(ctx, feature.ID, metrics)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate events: %w", err)
+	}
+
+	sequential := *feature
+	sequential.Analysis = AnalysisSequential
+	results := buildResults(&sequential, ResultsFrequentist, metrics, aggregate, nil)
+
+	breaches := guardrailBreaches(guardrails, results)
+	if len(breaches) == 0 || !feature.Active {
+		return breaches, nil
+	}
+
+	deactivated := *feature
+	deactivated.Active = false
+	if err := s.featureRepo.SetActive(ctx, &deactivated); err != nil {
+		return nil, fmt.Errorf("deactivate feature: %w", err)
+	}
+	s.featureCache.Delete(featureCacheKey(feature.ID))
+
+	entry := &AuditEntry{
+		FeatureID: feature.ID,
+		Actor:     guardrailActor,
+		Action:    AuditGuardrailKill,
+		Details:   map[string]any{"breaches": breaches},
+	}
+	if err := s.auditRepo.Record(ctx, entry); err != nil {
+		return nil, fmt.Errorf("record audit entry: %w", err)
+	}
+
+	err = s.notifier.Notify(ctx, Notification{
+		Type:      NotificationGuardrailBreached,
+		FeatureID: feature.ID,
+		Feature:   &deactivated,
+		At:        entry.CreatedAt,
+		Data:      breaches,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("notify guardrail breach: %w", err)
+	}
+
+	return breaches, nil
+}
+
+// guardrailBreaches finds the variants whose regression on a guardrail
+// metric exceeds its threshold. results holds one metric per guardrail.
+func guardrailBreaches(guardrails []*Guardrail, results *Results) []GuardrailBreach {
+	var breaches []GuardrailBreach
+	for i, guardrail := range guardrails {
+		variants := results.Metrics[i].Variants
+		if len(variants) == 0 {
+			continue
+		}
+
+		control := variants[0]
+		for _, vm := range variants[1:] {
+			if vm.Verdict != VerdictRegression {
+				continue
+			}
+
+			test := vm.MeanTest
+			if guardrail.Metric.Aggregation == AggregationUnique {
+				test = vm.ConversionTest
+			}
+
+			degradation := test.Difference
+			if control.Mean != 0 {
+				degradation /= math.Abs(control.Mean)
+			}
+			if guardrail.Metric.Direction == DirectionIncrease {
+				degradation = -degradation
+			}
+
+			if control.Mean == 0 || degradation > guardrail.Threshold {
+				breaches = append(breaches, GuardrailBreach{
+					Metric:      guardrail.Metric.Name,
+					Variant:     vm.Variant,
+					Threshold:   guardrail.Threshold,
+					Degradation: degradation,
+					PValue:      test.PValue,
+				})
+			}
+		}
+	}
+
+	return breaches
+}
+
+type GuardrailMonitorOptions struct {
+	Interval time.Duration
+	// Timeout bounds the evaluation of a single feature.
+	Timeout time.Duration
+}
+
+// GuardrailMonitor periodically evaluates the guardrails of all active
+// features, turning off the ones that breach them. Only one replica
+// evaluates them at a time, holding a lock handed out by its Locker.
+type GuardrailMonitor struct {
+	*periodic
+
+	svc    *Service
+	locker Locker
+	opts   GuardrailMonitorOptions
+	logger *slog.Logger
+}
+
+func NewGuardrailMonitor(svc *Service, locker Locker, opts GuardrailMonitorOptions, logger *slog.Logger) *GuardrailMonitor {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	m := &GuardrailMonitor{
+		svc:    svc,
+		locker: locker,
+		opts:   opts,
+		logger: logger,
+	}
+	m.periodic = startPeriodic("guardrail monitor", opts.Interval, m.evaluate)
+
+	return m
+}
+
+func (m *GuardrailMonitor) evaluate(stop <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	unlock, locked, err := m.locker.TryLock(ctx, guardrailLockKey)
+	cancel()
+	if err != nil {
+		m.logger.Error("guardrail evaluation failed to take lock", slog.Any("error", err))
+		return
+	}
+	if !locked {
+		// another replica is evaluating the guardrails
+		return
+	}
+	defer unlock()
+
+	active := true
+	page, err := m.svc.ListFeatures(context.Background(), FeatureFilter{Active: &active})
+	if err != nil {
+		m.logger.Error("guardrail evaluation failed to list features", slog.Any("error", err))
+		return
+	}
+
+	for _, feature := range page.Features {
+		if stopped(stop) {
+			return
+		}
+
+		m.evaluateFeature(feature)
+	}
+}
+
+func (m *GuardrailMonitor) evaluateFeature(feature *Feature) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	defer cancel()
+
+	breaches, err := m.svc.EvaluateGuardrails(ctx, feature.ID)
+	if err != nil {
+		m.logger.Error("guardrail evaluation failed", slog.Int("feature_id", int(feature.ID)), slog.Any("error", err))
+		return
+	}
+
+	for _, breach := range breaches {
+		m.logger.Warn("guardrail breached, feature deactivated",
+			slog.Int("feature_id", int(feature.ID)),
+			slog.String("metric", breach.Metric),
+			slog.String("variant", breach.Variant),
+			slog.Float64("degradation", breach.Degradation),
+			slog.Float64("threshold", breach.Threshold),
+		)
+	}
+}
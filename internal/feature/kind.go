@@ -0,0 +1,98 @@
+package feature
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+var (
+	ErrInvalidKind                   = errors.New("invalid feature kind")
+	ErrBooleanVariants               = errors.New("boolean features have exactly the variants off and on")
+	ErrBooleanValues                 = errors.New("boolean features serve false for off and true for on")
+	ErrMultivariateValueTypeRequired = errors.New("multivariate features require a value type")
+	ErrBanditRequiresExperiment      = errors.New("only experiments take a bandit")
+)
+
+// Kind tells what a feature is used for.
+type Kind string
+
+const (
+	// KindBoolean is an on/off flag, such as a kill switch. It serves the
+	// variant off whenever it would serve no variant, so its weights only
+	// roll the variant on out to a share of the users.
+	KindBoolean Kind = "boolean"
+	// KindMultivariate serves one of several typed values.
+	KindMultivariate Kind = "multivariate"
+	// KindExperiment compares its variants. Only experiments are checked for
+	// sample ratio mismatches and take a bandit.
+	KindExperiment Kind = "experiment"
+)
+
+const (
+	BooleanOff = "off"
+	BooleanOn  = "on"
+)
+
+func (k Kind) Valid() bool {
+	return k == KindBoolean || k == KindMultivariate || k == KindExperiment
+}
+
+// BooleanVariants are the variants of a boolean feature that is on for
+// every user while active.
+func BooleanVariants() Variants {
+	return Variants{
+		{Name: BooleanOff, Weight: 0, Value: json.RawMessage("false")},
+		{Name: BooleanOn, Weight: maximumWeight, Value: json.RawMessage("true")},
+	}
+}
+
+// ApplyKindDefaults gives boolean features without variants the
+// BooleanVariants and the boolean value type.
+func (f *Feature) ApplyKindDefaults() {
+	if f.Kind != KindBoolean {
+		return
+	}
+
+	if len(f.Variants) == 0 {
+		f.Variants = BooleanVariants()
+	}
+	if f.ValueType == ValueTypeNone {
+		f.ValueType = ValueTypeBoolean
+	}
+}
+
+func (f *Feature) validateKind() error {
+	if !f.Kind.Valid() {
+		return ErrInvalidKind
+	}
+
+	var errs []error
+	if f.Kind != KindExperiment && f.Bandit != nil {
+		errs = append(errs, ErrBanditRequiresExperiment)
+	}
+
+	switch f.Kind {
+	case KindBoolean:
+		off, hasOff := variantByName(f.Variants, BooleanOff)
+		on, hasOn := variantByName(f.Variants, BooleanOn)
+		if len(f.Variants) != 2 || !hasOff || !hasOn {
+			errs = append(errs, ErrBooleanVariants)
+			break
+		}
+
+		if f.ValueType != ValueTypeBoolean || !booleanValue(off.Value, false) || !booleanValue(on.Value, true) {
+			errs = append(errs, ErrBooleanValues)
+		}
+	case KindMultivariate:
+		if f.ValueType == ValueTypeNone {
+			errs = append(errs, ErrMultivariateValueTypeRequired)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func booleanValue(value json.RawMessage, want bool) bool {
+	var decoded bool
+	return json.Unmarshal(value, &decoded) == nil && decoded == want
+}
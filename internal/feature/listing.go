@@ -0,0 +1,143 @@
+package feature
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+var (
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidSort     = errors.New("invalid sort")
+	ErrInvalidPageSize = errors.New("invalid page size")
+	ErrInvalidRange    = errors.New("invalid time range")
+)
+
+type FeatureSort string
+
+const (
+	FeatureSortID       FeatureSort = "id"
+	FeatureSortIDDesc   FeatureSort = "-id"
+	FeatureSortName     FeatureSort = "name"
+	FeatureSortNameDesc FeatureSort = "-name"
+)
+
+func (s FeatureSort) Valid() bool {
+	switch s {
+	case FeatureSortID, FeatureSortIDDesc, FeatureSortName, FeatureSortNameDesc:
+		return true
+	default:
+		return false
+	}
+}
+
+// FeatureCursor points at the last feature of a page. Since both id and name
+// are unique, it identifies the position for every FeatureSort.
+type FeatureCursor struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+}
+
+// FeatureFilter selects a page of features. A zero Limit returns all
+// matching features.
+type FeatureFilter struct {
+	Active     *bool
+	NamePrefix string
+	Tag        string
+	Sort       FeatureSort
+	After      *FeatureCursor
+	Limit      int
+}
+
+func (f FeatureFilter) Validate() error {
+	var errs []error
+	if f.Sort != "" && !f.Sort.Valid() {
+		errs = append(errs, ErrInvalidSort)
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		errs = append(errs, ErrInvalidPageSize)
+	}
+
+	return errors.Join(errs...)
+}
+
+type FeaturePage struct {
+	Features []*Feature
+	Next     *FeatureCursor
+}
+
+// EventCursor points at the last event of a page; events are listed newest
+// first.
+type EventCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        int64     `json:"id"`
+}
+
+// EventFilter selects a page of events of a single feature. From is
+// inclusive, To is exclusive. A zero Limit returns all matching events.
+type EventFilter struct {
+	FeatureID int32
+	From      time.Time
+	To        time.Time
+	Type      string
+	Variant   string
+	UserID    string
+	Before    *EventCursor
+	Limit     int
+}
+
+func (f EventFilter) Validate() error {
+	var errs []error
+	if f.FeatureID <= 0 {
+		errs = append(errs, ErrInvalidFeatureID)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		errs = append(errs, ErrInvalidRange)
+	}
+	if f.Limit < 0 || f.Limit > MaxPageSize {
+		errs = append(errs, ErrInvalidPageSize)
+	}
+
+	return errors.Join(errs...)
+}
+
+type EventPage struct {
+	Events []*Event
+	Next   *EventCursor
+}
+
+// EncodeCursor turns a cursor into the opaque token handed out to clients.
+func EncodeCursor[C FeatureCursor | EventCursor](cursor *C) string {
+	if cursor == nil {
+		return ""
+	}
+
+	data, _ := json.Marshal(cursor) // nolint: errchkjson
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor. An empty token
+// yields a nil cursor.
+func DecodeCursor[C FeatureCursor | EventCursor](token string) (*C, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := new(C)
+	if err := json.Unmarshal(data, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return cursor, nil
+}
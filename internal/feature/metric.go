@@ -0,0 +1,196 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrMetricNotFound          = errors.New("metric not found")
+	ErrMetricAlreadyExists     = errors.New("metric already exists")
+	ErrMetricNotAttached       = errors.New("metric is not attached to feature")
+	ErrMetricNameRequired      = errors.New("metric name is required")
+	ErrMetricEventTypeRequired = errors.New("metric event type is required")
+	ErrInvalidAggregation      = errors.New("invalid metric aggregation")
+	ErrInvalidDirection        = errors.New("invalid metric direction")
+	ErrInvalidMetricWindow     = errors.New("metric window must be a non-negative number of seconds")
+)
+
+// Aggregation decides how the events of a metric are turned into a value
+// per exposed user.
+type Aggregation string
+
+const (
+	// AggregationUnique is whether the user had at least one event, so the
+	// metric is a conversion rate.
+	AggregationUnique Aggregation = "unique"
+	// AggregationCount is the number of events per exposed user.
+	AggregationCount Aggregation = "count"
+	// AggregationSum is the sum of the event values per exposed user.
+	AggregationSum Aggregation = "sum"
+	// AggregationMean is the mean event value of users with valued events,
+	// such as the average order value.
+	AggregationMean Aggregation = "mean"
+)
+
+func (a Aggregation) Valid() bool {
+	switch a {
+	case AggregationUnique, AggregationCount, AggregationSum, AggregationMean:
+		return true
+	default:
+		return false
+	}
+}
+
+// Direction tells whether an increase or a decrease of a metric is good.
+type Direction string
+
+const (
+	DirectionIncrease Direction = "increase"
+	DirectionDecrease Direction = "decrease"
+)
+
+func (d Direction) Valid() bool {
+	return d == DirectionIncrease || d == DirectionDecrease
+}
+
+// Metric defines how experiment results measure one outcome. Only events of
+// EventType recorded within Window after the user's first exposure count; a
+// zero Window counts all later events.
+type Metric struct {
+	ID          int32
+	Name        string
+	Description string
+	EventType   string
+	Aggregation Aggregation
+	Window      time.Duration
+	Direction   Direction
+}
+
+func (m *Metric) Validate() error {
+	var errs []error
+	if m.Name == "" {
+		errs = append(errs, ErrMetricNameRequired)
+	}
+	if m.EventType == "" {
+		errs = append(errs, ErrMetricEventTypeRequired)
+	}
+	if !m.Aggregation.Valid() {
+		errs = append(errs, ErrInvalidAggregation)
+	}
+	if !m.Direction.Valid() {
+		errs = append(errs, ErrInvalidDirection)
+	}
+	if m.Window < 0 || m.Window%time.Second != 0 {
+		errs = append(errs, ErrInvalidMetricWindow)
+	}
+
+	return errors.Join(errs...)
+}
+
+type MetricRepository interface {
+	GetByID(ctx context.Context, id int32) (*Metric, error)
+	List(ctx context.Context) ([]*Metric, error)
+	ListByFeature(ctx context.Context, featureID int32) ([]*Metric, error)
+	Create(ctx context.Context, metric *Metric) error
+	Update(ctx context.Context, metric *Metric) error
+	Delete(ctx context.Context, id int32) error
+	Attach(ctx context.Context, featureID, metricID int32) error
+	Detach(ctx context.Context, featureID, metricID int32) error
+	ListGuardrails(ctx context.Context, featureID int32) ([]*Guardrail, error)
+	// SetGuardrail adds a guardrail or changes the threshold of an existing
+	// one.
+	SetGuardrail(ctx context.Context, featureID, metricID int32, threshold float64) error
+	RemoveGuardrail(ctx context.Context, featureID, metricID int32) error
+}
+
+func (s *Service) GetMetric(ctx context.Context, id int32) (*Metric, error) {
+	metric, err := s.metricRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get metric: %w", err)
+	}
+
+	return metric, nil
+}
+
+func (s *Service) ListMetrics(ctx context.Context) ([]*Metric, error) {
+	metrics, err := s.metricRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+func (s *Service) CreateMetric(ctx context.Context, metric *Metric) error {
+	if err := metric.Validate(); err != nil {
+		return fmt.Errorf("validate metric: %w", err)
+	}
+
+	if err := s.metricRepo.Create(ctx, metric); err != nil {
+		return fmt.Errorf("create metric: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) UpdateMetric(ctx context.Context, metric *Metric) error {
+	if err := metric.Validate(); err != nil {
+		return fmt.Errorf("validate metric: %w", err)
+	}
+
+	if err := s.metricRepo.Update(ctx, metric); err != nil {
+		return fmt.Errorf("update metric: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) DeleteMetric(ctx context.Context, id int32) error {
+	if err := s.metricRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete metric: %w", err)
+	}
+
+	return nil
+}
+
+// ListFeatureMetrics returns the metrics attached to a feature.
+func (s *Service) ListFeatureMetrics(ctx context.Context, featureID int32) ([]*Metric, error) {
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.metricRepo.ListByFeature(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("list feature metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// AttachMetric adds a metric to the results of a feature.
+func (s *Service) AttachMetric(ctx context.Context, featureID, metricID int32) error {
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return err
+	}
+
+	if _, err := s.GetMetric(ctx, metricID); err != nil {
+		return err
+	}
+
+	if err := s.metricRepo.Attach(ctx, featureID, metricID); err != nil {
+		return fmt.Errorf("attach metric: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Service) DetachMetric(ctx context.Context, featureID, metricID int32) error {
+	if err := s.metricRepo.Detach(ctx, featureID, metricID); err != nil {
+		return fmt.Errorf("detach metric: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+type NotificationType string
+
+const (
+	NotificationGuardrailBreached NotificationType = "guardrail.breached"
+)
+
+// Notification tells operators about something the service did on its own.
+// Data holds the details of the notification type.
+type Notification struct {
+	Type      NotificationType
+	FeatureID int32
+	Feature   *Feature
+	At        time.Time
+	Data      any
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Notifiers sends every notification to each of its notifiers.
+type Notifiers []Notifier
+
+// Notify implements Notifier.
+func (n Notifiers) Notify(ctx context.Context, notification Notification) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type logNotifier struct {
+	logger *slog.Logger
+}
+
+var _ Notifier = (*logNotifier)(nil)
+
+// NewLogNotifier returns a Notifier that writes notifications to logger as
+// warnings.
+func NewLogNotifier(logger *slog.Logger) *logNotifier {
+	return &logNotifier{logger: logger}
+}
+
+// Notify implements Notifier.
+func (n *logNotifier) Notify(_ context.Context, notification Notification) error {
+	n.logger.Warn("notification",
+		slog.String("type", string(notification.Type)),
+		slog.Int("feature_id", int(notification.FeatureID)),
+		slog.Any("data", notification.Data),
+	)
+
+	return nil
+}
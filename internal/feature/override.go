@@ -0,0 +1,71 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrOverrideNotFound        = errors.New("override not found")
+	ErrOverrideUserKeyRequired = errors.New("override user key is required")
+)
+
+// SetOverride forces the user evaluated with userKey into a variant of a
+// feature, replacing an earlier override of the user. It returns the
+// updated feature.
+func (s *Service) SetOverride(ctx context.Context, featureID int32, userKey, variant string) (*Feature, error) {
+	if userKey == "" {
+		return nil, ErrOverrideUserKeyRequired
+	}
+
+	feature, err := s.featureRepo.GetByID(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("get feature: %w", err)
+	}
+
+	if !slices.Contains(feature.Variants.Names(), variant) {
+		return nil, ErrUnknownVariant
+	}
+
+	if err := s.featureRepo.SetOverride(ctx, featureID, userKey, variant); err != nil {
+		return nil, fmt.Errorf("set override: %w", err)
+	}
+
+	return s.overridesChanged(ctx, featureID)
+}
+
+// RemoveOverride lets the user evaluated with userKey be bucketed again.
+func (s *Service) RemoveOverride(ctx context.Context, featureID int32, userKey string) error {
+	if err := s.featureRepo.RemoveOverride(ctx, featureID, userKey); err != nil {
+		return fmt.Errorf("remove override: %w", err)
+	}
+
+	_, err := s.overridesChanged(ctx, featureID)
+	return err
+}
+
+// overridesChanged evicts the cached feature and returns its new state,
+// which carries the overrides.
+func (s *Service) overridesChanged(ctx context.Context, featureID int32) (*Feature, error) {
+	s.featureCache.Delete(featureCacheKey(featureID))
+
+	feature, err := s.featureRepo.GetByID(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("get feature: %w", err)
+	}
+
+	return feature, nil
+}
+
+// overrideFor returns the variant the user is forced into, if any. Overrides
+// of variants the feature no longer has are ignored.
+func overrideFor(feature *Feature, userKey string) (*Variant, bool) {
+	name, found := feature.Overrides[userKey]
+	if !found {
+		return nil, false
+	}
+
+	return variantByName(feature.Variants, name)
+}
@@ -0,0 +1,65 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// periodic runs a task at a fixed interval until it is closed. The task
+// gets the stop channel to return early from long runs.
+type periodic struct {
+	name     string
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func startPeriodic(name string, interval time.Duration, task func(stop <-chan struct{})) *periodic {
+	p := &periodic{
+		name: name,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				task(p.stop)
+			}
+		}
+	}()
+
+	return p
+}
+
+// Close stops the task, waiting for a running one to finish.
+func (p *periodic) Close(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("stopping %s: %w", p.name, ctx.Err())
+	}
+}
+
+// stopped reports whether stop has been closed.
+func stopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
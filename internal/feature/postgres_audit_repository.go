@@ -0,0 +1,75 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
+)
+
+type postgresAuditRepository struct {
+	queries *dbsqlc.Queries
+}
+
+var _ AuditRepository = (*postgresAuditRepository)(nil)
+
+func NewPostgresAuditRepository(queries *dbsqlc.Queries) *postgresAuditRepository {
+	return &postgresAuditRepository{queries: queries}
+}
+
+// Record implements AuditRepository.
+func (p *postgresAuditRepository) Record(ctx context.Context, entry *AuditEntry) error {
+	details := []byte("{}")
+	if entry.Details != nil {
+		var err error
+		if details, err = json.Marshal(entry.Details); err != nil {
+			return fmt.Errorf("encoding audit details: %w", err)
+		}
+	}
+
+	inserted, err := p.queries.InsertAuditEntry(ctx, dbsqlc.InsertAuditEntryParams{
+		FeatureID: pgInt4FromInt32(entry.FeatureID),
+		Actor:     entry.Actor,
+		Action:    string(entry.Action),
+		Details:   details,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting audit entry: %w", err)
+	}
+
+	entry.ID = inserted.ID
+	entry.CreatedAt = inserted.CreatedAt.Time
+
+	return nil
+}
+
+// List implements AuditRepository.
+func (p *postgresAuditRepository) List(ctx context.Context, featureID int32, limit int) ([]*AuditEntry, error) {
+	params := dbsqlc.ListAuditEntriesParams{FeatureID: pgInt4FromInt32(featureID)}
+	if limit > 0 {
+		params.PageSize = pgInt4FromInt32(int32(limit))
+	}
+
+	rows, err := p.queries.ListAuditEntries(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("selecting audit entries: %w", err)
+	}
+
+	entries := make([]*AuditEntry, len(rows))
+	for i, row := range rows {
+		entry := &AuditEntry{
+			ID:        row.ID,
+			FeatureID: row.FeatureID.Int32,
+			Actor:     row.Actor,
+			Action:    AuditAction(row.Action),
+			CreatedAt: row.CreatedAt.Time,
+		}
+		if err := json.Unmarshal(row.Details, &entry.Details); err != nil {
+			return nil, fmt.Errorf("decoding audit details: %w", err)
+		}
+		entries[i] = entry
+	}
+
+	return entries, nil
+}
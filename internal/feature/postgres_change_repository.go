@@ -0,0 +1,261 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// changesLockKey identifies the lock writers of feature changes hold until
+// they commit; it is "splitchg" in ASCII.
+const changesLockKey int64 = 0x73706c6974636867
+
+// changesChannel is notified of every recorded change.
+const changesChannel = "feature_changes"
+
+// recordChange records a change of a feature in the transaction of queries.
+// A nil feature makes readers load its current state. It has to be the last
+// write before the commit, since it serializes all writers of changes.
+func recordChange(ctx context.Context, queries *dbsqlc.Queries, changeType ChangeType, featureID int32, feature *Feature) error {
+	var snapshot []byte
+	if feature != nil {
+		var err error
+		if snapshot, err = (CacheCodec{}).Marshal(feature); err != nil {
+			return fmt.Errorf("encoding changed feature: %w", err)
+		}
+	}
+
+	if err := queries.LockFeatureChanges(ctx, changesLockKey); err != nil {
+		return fmt.Errorf("locking feature changes: %w", err)
+	}
+
+	id, err := queries.InsertFeatureChange(ctx, dbsqlc.InsertFeatureChangeParams{
+		Type:      string(changeType),
+		FeatureID: featureID,
+		Feature:   snapshot,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting feature change: %w", err)
+	}
+
+	if err := queries.NotifyFeatureChange(ctx, strconv.FormatInt(id, 10)); err != nil {
+		return fmt.Errorf("notifying feature change: %w", err)
+	}
+
+	return nil
+}
+
+type postgresChangeRepository struct {
+	pool    *pgxpool.Pool
+	queries *dbsqlc.Queries
+
+	// listener is the connection listening for notifications. It is only
+	// used by Wait, which is not called concurrently.
+	mu       sync.Mutex
+	listener *pgxpool.Conn
+}
+
+var _ ChangeRepository = (*postgresChangeRepository)(nil)
+
+func NewPostgresChangeRepository(pool *pgxpool.Pool, queries *dbsqlc.Queries) *postgresChangeRepository {
+	return &postgresChangeRepository{
+		pool:    pool,
+		queries: queries,
+	}
+}
+
+// Latest implements ChangeRepository.
+func (p *postgresChangeRepository) Latest(ctx context.Context) (uint64, error) {
+	id, err := p.queries.LatestFeatureChangeID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("selecting latest feature change: %w", err)
+	}
+
+	return uint64(id), nil
+}
+
+// List implements ChangeRepository.
+func (p *postgresChangeRepository) List(ctx context.Context, afterSeq, untilSeq uint64, limit int) ([]Change, error) {
+	if untilSeq > math.MaxInt64 {
+		untilSeq = math.MaxInt64
+	}
+
+	rows, err := p.queries.ListFeatureChanges(ctx, dbsqlc.ListFeatureChangesParams{
+		AfterID:  int64(afterSeq),
+		UntilID:  int64(untilSeq),
+		PageSize: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("selecting feature changes: %w", err)
+	}
+
+	changes := make([]Change, len(rows))
+	for i, row := range rows {
+		if changes[i], err = mapChangeRow(row); err != nil {
+			return nil, err
+		}
+	}
+
+	return changes, nil
+}
+
+// QueueWebhooks implements ChangeRepository.
+func (p *postgresChangeRepository) QueueWebhooks(ctx context.Context, limit int, payload WebhookPayloadFunc) (queued int, err error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	rows, err := queries.ClaimUnqueuedFeatureChanges(ctx, int32(limit))
+	if err != nil {
+		return 0, fmt.Errorf("claiming feature changes: %w", err)
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, len(rows))
+	for i, row := range rows {
+		change, err := mapChangeRow(row)
+		if err != nil {
+			return 0, err
+		}
+
+		event, body, err := payload(ctx, change)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = queries.EnqueueWebhookDeliveries(ctx, dbsqlc.EnqueueWebhookDeliveriesParams{
+			Event:   string(event),
+			Payload: body,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("inserting webhook deliveries: %w", err)
+		}
+
+		ids[i] = row.ID
+	}
+
+	if err := queries.MarkFeatureChangesQueued(ctx, ids); err != nil {
+		return 0, fmt.Errorf("marking feature changes queued: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return len(rows), nil
+}
+
+// Retains implements ChangeRepository.
+func (p *postgresChangeRepository) Retains(ctx context.Context, seq uint64) (bool, error) {
+	retained, err := p.queries.HasFeatureChangesUpTo(ctx, int64(seq))
+	if err != nil {
+		return false, fmt.Errorf("selecting retained feature changes: %w", err)
+	}
+
+	return retained, nil
+}
+
+// Prune implements ChangeRepository.
+func (p *postgresChangeRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	deleted, err := p.queries.DeleteFeatureChangesBefore(ctx, pgtype.Timestamptz{Time: before, Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("deleting feature changes: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// Wait implements ChangeRepository. It keeps a pool connection listening
+// between calls and drops it on errors, so the next call listens anew.
+func (p *postgresChangeRepository) Wait(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listener == nil {
+		conn, err := p.pool.Acquire(ctx)
+		if err != nil {
+			return fmt.Errorf("acquiring connection: %w", err)
+		}
+
+		if _, err := conn.Exec(ctx, "LISTEN "+changesChannel); err != nil {
+			conn.Release()
+			return fmt.Errorf("listening for feature changes: %w", err)
+		}
+
+		p.listener = conn
+	}
+
+	if _, err := p.listener.Conn().WaitForNotification(ctx); err != nil {
+		if ctx.Err() != nil {
+			// the connection is unusable after an interrupted wait
+			p.closeListener()
+			return ctx.Err()
+		}
+
+		p.closeListener()
+		return fmt.Errorf("waiting for feature changes: %w", err)
+	}
+
+	return nil
+}
+
+// Close releases the listening connection.
+func (p *postgresChangeRepository) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeListener()
+}
+
+func mapChangeRow(row dbsqlc.FeatureChange) (Change, error) {
+	change := Change{
+		Seq:       uint64(row.ID),
+		Type:      ChangeType(row.Type),
+		FeatureID: row.FeatureID,
+		At:        row.CreatedAt.Time,
+	}
+
+	if row.Feature != nil {
+		feature, err := (CacheCodec{}).Unmarshal(row.Feature)
+		if err != nil {
+			return Change{}, fmt.Errorf("decoding changed feature: %w", err)
+		}
+		change.Feature = feature
+	}
+
+	return change, nil
+}
+
+func (p *postgresChangeRepository) closeListener() {
+	if p.listener == nil {
+		return
+	}
+
+	// a closed connection is dropped from the pool on release
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_ = p.listener.Conn().Close(ctx)
+	p.listener.Release()
+	p.listener = nil
+}
@@ -2,31 +2,84 @@ package feature
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Batches are copied into a temporary table of their transaction and moved
+// into events from there. The statements are not generated by sqlc, as the
+// table does not exist outside the transaction.
+const (
+	createEventsStaging = `CREATE TEMP TABLE events_staging ON COMMIT DROP AS
+SELECT feature_id, user_id, variant, event_type, created_at, client_event_id, value, properties, variant_mismatch
+FROM events
+WITH NO DATA`
+
+	insertStagedEvents = `INSERT INTO events (feature_id, user_id, variant, event_type, created_at, client_event_id, value, properties, variant_mismatch)
+SELECT feature_id, user_id, variant, event_type, created_at, client_event_id, value, properties, variant_mismatch
+FROM events_staging
+ON CONFLICT (feature_id, client_event_id) WHERE client_event_id IS NOT NULL DO NOTHING
+RETURNING feature_id, client_event_id`
+)
+
+var stagedEventColumns = []string{
+	"feature_id", "user_id", "variant", "event_type", "created_at",
+	"client_event_id", "value", "properties", "variant_mismatch",
+}
+
 type postgresEventRepository struct {
+	pool    *pgxpool.Pool
 	queries *dbsqlc.Queries
 }
 
 var _ EventRepository = (*postgresEventRepository)(nil)
 
-func NewPostgresEventRepository(queries *dbsqlc.Queries) *postgresEventRepository {
-	return &postgresEventRepository{queries: queries}
+func NewPostgresEventRepository(pool *pgxpool.Pool, queries *dbsqlc.Queries) *postgresEventRepository {
+	return &postgresEventRepository{
+		pool:    pool,
+		queries: queries,
+	}
 }
 
+// Create implements EventRepository.
 func (p *postgresEventRepository) Create(ctx context.Context, event *Event) error {
+	properties, err := propertiesParam(event.Properties)
+	if err != nil {
+		return err
+	}
+
 	inserted, err := p.queries.InsertEvent(ctx, dbsqlc.InsertEventParams{
-		FeatureID: pgInt4FromInt32(event.FeatureID),
-		UserID:    textParam(event.UserID),
-		Variant:   textParam(event.Variant),
-		EventType: textParam(event.Type),
+		FeatureID:       pgInt4FromInt32(event.FeatureID),
+		UserID:          textParam(event.UserID),
+		Variant:         textParam(event.Variant),
+		EventType:       textParam(event.Type),
+		ClientEventID:   clientIDParam(event.ClientID),
+		Value:           valueParam(event.Value),
+		Properties:      properties,
+		VariantMismatch: event.VariantMismatch,
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Only a conflicting client id makes the insert return nothing.
+		original, err := p.queries.GetEventByClientID(ctx, dbsqlc.GetEventByClientIDParams{
+			FeatureID:     pgInt4FromInt32(event.FeatureID),
+			ClientEventID: clientIDParam(event.ClientID),
+		})
+		if err != nil {
+			return fmt.Errorf("selecting recorded event: %w", err)
+		}
+
+		applyEvent(original, event)
+
+		return ErrEventAlreadyRecorded
+	}
 	if err != nil {
 		return fmt.Errorf("inserting event: %w", err)
 	}
@@ -36,10 +89,169 @@ func (p *postgresEventRepository) Create(ctx context.Context, event *Event) erro
 	return nil
 }
 
-func (p *postgresEventRepository) ListByFeatureID(ctx context.Context, featureID int32) ([]*Event, error) {
-	rows, err := p.queries.ListEventsByFeatureID(ctx, pgInt4FromInt32(featureID))
+// CreateBatch implements EventRepository. Events are copied into a staging
+// table dropped on commit and moved from there, skipping already recorded
+// client ids, since COPY itself cannot resolve conflicts. Unlike Create it
+// does not populate ID; events without CreatedAt are stamped with the current
+// time.
+func (p *postgresEventRepository) CreateBatch(ctx context.Context, events []*Event) ([]*Event, error) {
+	now := time.Now()
+	rows := make([][]any, len(events))
+	for i, event := range events {
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = now
+		}
+
+		properties, err := propertiesParam(event.Properties)
+		if err != nil {
+			return nil, err
+		}
+
+		rows[i] = []any{
+			pgInt4FromInt32(event.FeatureID),
+			textParam(event.UserID),
+			textParam(event.Variant),
+			textParam(event.Type),
+			timestamptzParam(event.CreatedAt),
+			clientIDParam(event.ClientID),
+			valueParam(event.Value),
+			properties,
+			event.VariantMismatch,
+		}
+	}
+
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, createEventsStaging); err != nil {
+		return nil, fmt.Errorf("creating staging table: %w", err)
+	}
+
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"events_staging"}, stagedEventColumns, pgx.CopyFromRows(rows)); err != nil {
+		return nil, fmt.Errorf("copying events: %w", err)
+	}
+
+	recorded, err := insertStaged(ctx, tx, len(events))
+	if err != nil {
+		return nil, fmt.Errorf("inserting staged events: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	var params dbsqlc.ListEventsByClientIDsParams
+	for _, event := range events {
+		if event.ClientID == "" {
+			continue
+		}
+		if _, ok := recorded[clientKeyOf(event)]; ok {
+			continue
+		}
+
+		params.FeatureIds = append(params.FeatureIds, event.FeatureID)
+		params.ClientEventIds = append(params.ClientEventIds, event.ClientID)
+	}
+
+	if len(params.ClientEventIds) == 0 {
+		return nil, nil
+	}
+
+	originals, err := p.queries.ListEventsByClientIDs(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("selecting recorded events: %w", err)
+	}
+
+	duplicates := make([]*Event, 0, len(originals))
+	for _, row := range originals {
+		e := &Event{}
+		applyEvent(row, e)
+		duplicates = append(duplicates, e)
+	}
+
+	return duplicates, nil
+}
+
+// insertStaged moves the staged events into events and returns the client
+// ids of those inserted.
+func insertStaged(ctx context.Context, tx pgx.Tx, size int) (map[clientEventKey]struct{}, error) {
+	rows, err := tx.Query(ctx, insertStagedEvents)
 	if err != nil {
-		return nil, fmt.Errorf("selecting events by feature id: %w", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	recorded := make(map[clientEventKey]struct{}, size)
+	for rows.Next() {
+		var (
+			featureID pgtype.Int4
+			clientID  pgtype.Text
+		)
+		if err := rows.Scan(&featureID, &clientID); err != nil {
+			return nil, err
+		}
+
+		if clientID.Valid {
+			recorded[clientEventKey{featureID: featureID.Int32, clientID: clientID.String}] = struct{}{}
+		}
+	}
+
+	return recorded, rows.Err()
+}
+
+// GetByClientID implements EventRepository.
+func (p *postgresEventRepository) GetByClientID(ctx context.Context, featureID int32, clientID string) (*Event, error) {
+	row, err := p.queries.GetEventByClientID(ctx, dbsqlc.GetEventByClientIDParams{
+		FeatureID:     pgInt4FromInt32(featureID),
+		ClientEventID: clientIDParam(clientID),
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEventNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("selecting event: %w", err)
+	}
+
+	event := &Event{}
+	applyEvent(row, event)
+
+	return event, nil
+}
+
+func (p *postgresEventRepository) List(ctx context.Context, filter EventFilter) ([]*Event, error) {
+	params := dbsqlc.ListEventsParams{
+		FeatureID: pgInt4FromInt32(filter.FeatureID),
+	}
+
+	if !filter.From.IsZero() {
+		params.CreatedFrom = timestamptzParam(filter.From)
+	}
+	if !filter.To.IsZero() {
+		params.CreatedTo = timestamptzParam(filter.To)
+	}
+	if filter.Type != "" {
+		params.EventType = textParam(filter.Type)
+	}
+	if filter.Variant != "" {
+		params.Variant = textParam(filter.Variant)
+	}
+	if filter.UserID != "" {
+		params.UserID = textParam(filter.UserID)
+	}
+	if filter.Before != nil {
+		params.BeforeCreatedAt = timestamptzParam(filter.Before.CreatedAt)
+		params.BeforeID = pgtype.Int8{Int64: filter.Before.ID, Valid: true}
+	}
+	if filter.Limit > 0 {
+		params.PageSize = pgInt4FromInt32(int32(filter.Limit))
+	}
+
+	rows, err := p.queries.ListEvents(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("selecting events: %w", err)
 	}
 
 	events := make([]*Event, 0, len(rows))
@@ -52,6 +264,141 @@ func (p *postgresEventRepository) ListByFeatureID(ctx context.Context, featureID
 	return events, nil
 }
 
+// AggregateCovariates implements EventRepository.
+func (p *postgresEventRepository) AggregateCovariates(
+	ctx context.Context,
+	featureID int32,
+	metrics []*Metric,
+	lookback time.Duration,
+) ([]CovariateAggregate, error) {
+	var covariates []CovariateAggregate
+	for _, metric := range metrics {
+		if metric.Aggregation == AggregationMean {
+			continue
+		}
+
+		rows, err := p.queries.AggregateMetricCovariates(ctx, dbsqlc.AggregateMetricCovariatesParams{
+			FeatureID:       pgInt4FromInt32(featureID),
+			ExposureType:    textParam(ExposureEventType),
+			WindowSeconds:   windowParam(metric.Window),
+			LookbackSeconds: int64(lookback / time.Second),
+			EventType:       textParam(metric.EventType),
+			Aggregation:     string(metric.Aggregation),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("aggregating covariates of metric %s: %w", metric.Name, err)
+		}
+
+		for _, row := range rows {
+			covariates = append(covariates, CovariateAggregate{
+				Variant:             textToString(row.Variant),
+				Metric:              metric.Name,
+				Users:               row.Users,
+				MetricSum:           row.MetricSum,
+				MetricSumSquares:    row.MetricSumSquares,
+				CovariateSum:        row.CovariateSum,
+				CovariateSumSquares: row.CovariateSumSquares,
+				CrossSum:            row.CrossSum,
+			})
+		}
+	}
+
+	return covariates, nil
+}
+
+// CountExposures implements EventRepository.
+func (p *postgresEventRepository) CountExposures(ctx context.Context, featureID int32) ([]ExposureAggregate, error) {
+	rows, err := p.queries.CountExposures(ctx, dbsqlc.CountExposuresParams{
+		FeatureID:    pgInt4FromInt32(featureID),
+		ExposureType: textParam(ExposureEventType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("counting exposures: %w", err)
+	}
+
+	exposures := make([]ExposureAggregate, len(rows))
+	for i, row := range rows {
+		exposures[i] = ExposureAggregate{
+			Variant: textToString(row.Variant),
+			Users:   row.Users,
+		}
+	}
+
+	return exposures, nil
+}
+
+// Aggregate implements EventRepository.
+func (p *postgresEventRepository) Aggregate(ctx context.Context, featureID int32, metrics []*Metric) (*EventAggregate, error) {
+	exposures, err := p.CountExposures(ctx, featureID)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregate := &EventAggregate{Exposures: exposures}
+
+	if len(metrics) == 0 {
+		aggregate.Metrics, err = p.aggregateEventTypes(ctx, featureID)
+		return aggregate, err
+	}
+
+	for _, metric := range metrics {
+		rows, err := p.queries.AggregateMetric(ctx, dbsqlc.AggregateMetricParams{
+			FeatureID:     pgInt4FromInt32(featureID),
+			ExposureType:  textParam(ExposureEventType),
+			EventType:     textParam(metric.EventType),
+			WindowSeconds: windowParam(metric.Window),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("aggregating metric %s: %w", metric.Name, err)
+		}
+
+		for _, row := range rows {
+			aggregate.Metrics = append(aggregate.Metrics, MetricAggregate{
+				Variant:         textToString(row.Variant),
+				Metric:          metric.Name,
+				Users:           row.Users,
+				CountSum:        row.CountSum,
+				CountSumSquares: row.CountSumSquares,
+				ValueUsers:      row.ValueUsers,
+				ValueSum:        row.ValueSum,
+				ValueSumSquares: row.ValueSumSquares,
+				MeanSum:         row.MeanSum,
+				MeanSumSquares:  row.MeanSumSquares,
+			})
+		}
+	}
+
+	return aggregate, nil
+}
+
+func (p *postgresEventRepository) aggregateEventTypes(ctx context.Context, featureID int32) ([]MetricAggregate, error) {
+	rows, err := p.queries.AggregateMetrics(ctx, dbsqlc.AggregateMetricsParams{
+		FeatureID:    pgInt4FromInt32(featureID),
+		ExposureType: textParam(ExposureEventType),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aggregating event types: %w", err)
+	}
+
+	aggregates := make([]MetricAggregate, len(rows))
+	for i, row := range rows {
+		aggregates[i] = MetricAggregate{
+			Variant:         textToString(row.Variant),
+			Metric:          textToString(row.EventType),
+			Users:           row.Users,
+			CountSum:        row.CountSum,
+			CountSumSquares: row.CountSumSquares,
+			ValueUsers:      row.ValueUsers,
+			ValueSum:        row.ValueSum,
+			ValueSumSquares: row.ValueSumSquares,
+			MeanSum:         row.MeanSum,
+			MeanSumSquares:  row.MeanSumSquares,
+		}
+	}
+
+	return aggregates, nil
+}
+
 func applyEvent(dbEvent dbsqlc.Event, target *Event) {
 	target.ID = dbEvent.ID
 	if dbEvent.FeatureID.Valid {
@@ -63,6 +410,13 @@ func applyEvent(dbEvent dbsqlc.Event, target *Event) {
 	target.Variant = textToString(dbEvent.Variant)
 	target.Type = textToString(dbEvent.EventType)
 	target.CreatedAt = timestamptzToTime(dbEvent.CreatedAt)
+	target.ClientID = textToString(dbEvent.ClientEventID)
+	target.Value = nil
+	if dbEvent.Value.Valid {
+		target.Value = &dbEvent.Value.Float64
+	}
+	target.Properties = propertiesFromJSON(dbEvent.Properties)
+	target.VariantMismatch = dbEvent.VariantMismatch
 }
 
 func timestamptzToTime(value pgtype.Timestamptz) time.Time {
@@ -72,3 +426,52 @@ func timestamptzToTime(value pgtype.Timestamptz) time.Time {
 
 	return value.Time
 }
+
+func timestamptzParam(value time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{
+		Time:  value,
+		Valid: true,
+	}
+}
+
+// clientIDParam stores a missing client id as NULL, which the unique index
+// on client ids ignores.
+func clientIDParam(value string) pgtype.Text {
+	return pgtype.Text{
+		String: value,
+		Valid:  value != "",
+	}
+}
+
+func valueParam(value *float64) pgtype.Float8 {
+	if value == nil {
+		return pgtype.Float8{}
+	}
+
+	return pgtype.Float8{Float64: *value, Valid: true}
+}
+
+// propertiesParam encodes properties for the non-null properties column.
+func propertiesParam(properties map[string]any) ([]byte, error) {
+	if len(properties) == 0 {
+		return []byte("{}"), nil
+	}
+
+	data, err := json.Marshal(properties)
+	if err != nil {
+		return nil, fmt.Errorf("encoding event properties: %w", err)
+	}
+
+	return data, nil
+}
+
+// propertiesFromJSON decodes the properties column. It is only ever written
+// by propertiesParam, so a value that is not an object is treated as empty.
+func propertiesFromJSON(data []byte) map[string]any {
+	var properties map[string]any
+	if err := json.Unmarshal(data, &properties); err != nil || len(properties) == 0 {
+		return nil
+	}
+
+	return properties
+}
@@ -2,10 +2,9 @@ package feature
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"maps"
-	"slices"
 
 	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
 
@@ -16,8 +15,9 @@ import (
 )
 
 var (
-	ErrFeatureNotFound      = errors.New("feature not found")
-	ErrFeatureAlreadyExists = errors.New("feature already exists")
+	ErrFeatureNotFound        = errors.New("feature not found")
+	ErrFeatureAlreadyExists   = errors.New("feature already exists")
+	ErrFeatureVersionConflict = errors.New("feature has been changed concurrently")
 )
 
 type postgresFeatureRepository struct {
@@ -34,28 +34,54 @@ func NewPostgresFeatureRepository(pool *pgxpool.Pool, queries *dbsqlc.Queries) *
 	}
 }
 
-func (p *postgresFeatureRepository) List(ctx context.Context) ([]*Feature, error) {
-	rows, err := p.queries.ListFeatures(ctx)
+// List implements FeatureRepository.
+func (p *postgresFeatureRepository) List(ctx context.Context, filter FeatureFilter) ([]*Feature, error) {
+	params := dbsqlc.ListFeaturesParams{
+		Sort: string(FeatureSortID),
+	}
+
+	if filter.Sort != "" {
+		params.Sort = string(filter.Sort)
+	}
+	if filter.Active != nil {
+		params.Active = pgtype.Bool{Bool: *filter.Active, Valid: true}
+	}
+	if filter.NamePrefix != "" {
+		params.NamePrefix = textParam(filter.NamePrefix)
+	}
+	if filter.Tag != "" {
+		params.Tag = textParam(filter.Tag)
+	}
+	if filter.After != nil {
+		params.AfterID = pgInt4FromInt32(filter.After.ID)
+		params.AfterName = textParam(filter.After.Name)
+	}
+	if filter.Limit > 0 {
+		params.PageSize = pgInt4FromInt32(int32(filter.Limit))
+	}
+
+	rows, err := p.queries.ListFeatures(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("selecting features: %w", err)
 	}
 
-	featureMap := make(map[int32]*Feature, len(rows))
+	// rows arrive in page order, grouped by feature
+	features := make([]*Feature, 0, len(rows))
+	var f *Feature
 	for _, r := range rows {
-		f, ok := featureMap[r.FeatureID]
-		if !ok {
-			f, err = mapFeatureRow(r.FeatureID, r.FeatureName, r.FeatureDescription, r.FeatureActive)
+		if f == nil || f.ID != r.FeatureID {
+			f, err = mapFeatureRow(r.FeatureID, r.FeatureName, r.FeatureDescription, r.FeatureActive, r.FeatureVersion, r.FeatureTags, r.FeatureSampleRatioMismatch, r.FeatureAnalysis, r.FeatureBanditEventType, r.FeatureBanditMinWeight, r.FeatureValueType, r.FeatureKind, r.FeatureValueSchema)
 			if err != nil {
 				return nil, fmt.Errorf("mapping feature: %w", err)
 			}
-			featureMap[r.FeatureID] = f
+			features = append(features, f)
 		}
 
 		if !r.VariantID.Valid || !r.VariantName.Valid {
 			continue
 		}
 
-		variant, err := mapVariantRow(r.VariantID, r.VariantName, r.VariantWeight)
+		variant, err := mapVariantRow(r.VariantID, r.VariantName, r.VariantWeight, r.VariantValue)
 		if err != nil {
 			return nil, fmt.Errorf("mapping variant: %w", err)
 		}
@@ -65,7 +91,19 @@ func (p *postgresFeatureRepository) List(ctx context.Context) ([]*Feature, error
 		}
 	}
 
-	return slices.Collect(maps.Values(featureMap)), nil
+	if err := p.loadPrerequisites(ctx, features...); err != nil {
+		return nil, err
+	}
+
+	if err := p.loadOverrides(ctx, features...); err != nil {
+		return nil, err
+	}
+
+	if err := p.loadRules(ctx, features...); err != nil {
+		return nil, err
+	}
+
+	return features, nil
 }
 
 // GetByID implements FeatureRepository.
@@ -83,7 +121,7 @@ func (p *postgresFeatureRepository) GetByID(ctx context.Context, id int32) (*Fea
 	for _, r := range rows {
 		f, ok := featureMap[r.FeatureID]
 		if !ok {
-			f, err = mapFeatureRow(r.FeatureID, r.FeatureName, r.FeatureDescription, r.FeatureActive)
+			f, err = mapFeatureRow(r.FeatureID, r.FeatureName, r.FeatureDescription, r.FeatureActive, r.FeatureVersion, r.FeatureTags, r.FeatureSampleRatioMismatch, r.FeatureAnalysis, r.FeatureBanditEventType, r.FeatureBanditMinWeight, r.FeatureValueType, r.FeatureKind, r.FeatureValueSchema)
 			if err != nil {
 				return nil, fmt.Errorf("mapping feature: %w", err)
 			}
@@ -94,7 +132,7 @@ func (p *postgresFeatureRepository) GetByID(ctx context.Context, id int32) (*Fea
 			continue
 		}
 
-		variant, err := mapVariantRow(r.VariantID, r.VariantName, r.VariantWeight)
+		variant, err := mapVariantRow(r.VariantID, r.VariantName, r.VariantWeight, r.VariantValue)
 		if err != nil {
 			return nil, fmt.Errorf("mapping variant: %w", err)
 		}
@@ -109,6 +147,18 @@ func (p *postgresFeatureRepository) GetByID(ctx context.Context, id int32) (*Fea
 	}
 
 	for _, feature := range featureMap {
+		if err := p.loadPrerequisites(ctx, feature); err != nil {
+			return nil, err
+		}
+
+		if err := p.loadOverrides(ctx, feature); err != nil {
+			return nil, err
+		}
+
+		if err := p.loadRules(ctx, feature); err != nil {
+			return nil, err
+		}
+
 		return feature, nil
 	}
 
@@ -125,10 +175,17 @@ func (p *postgresFeatureRepository) Create(ctx context.Context, feature *Feature
 
 	queries := p.queries.WithTx(tx)
 
-	featureID, err := queries.InsertFeature(ctx, dbsqlc.InsertFeatureParams{
-		Name:        feature.Name,
-		Description: textParam(feature.Descritption),
-		Active:      feature.Active,
+	inserted, err := queries.InsertFeature(ctx, dbsqlc.InsertFeatureParams{
+		Name:            feature.Name,
+		Description:     textParam(feature.Descritption),
+		Active:          feature.Active,
+		Tags:            tagsParam(feature.Tags),
+		Analysis:        string(feature.Analysis),
+		BanditEventType: banditEventTypeParam(feature.Bandit),
+		BanditMinWeight: banditMinWeightParam(feature.Bandit),
+		ValueType:       string(feature.ValueType),
+		Kind:            string(feature.Kind),
+		ValueSchema:     feature.ValueSchema,
 	})
 	if err != nil {
 		var pgErr *pgconn.PgError
@@ -138,8 +195,9 @@ func (p *postgresFeatureRepository) Create(ctx context.Context, feature *Feature
 
 		return fmt.Errorf("inserting feature: %w", err)
 	}
-	feature.ID = featureID
-	featureIDParam := pgInt4FromInt32(featureID)
+	feature.ID = inserted.ID
+	feature.Version = inserted.Version
+	featureIDParam := pgInt4FromInt32(inserted.ID)
 
 	for i := range feature.Variants {
 		variant := &feature.Variants[i]
@@ -148,6 +206,7 @@ func (p *postgresFeatureRepository) Create(ctx context.Context, feature *Feature
 			FeatureID: featureIDParam,
 			Name:      variant.Name,
 			Weight:    int32(variant.Weight),
+			Value:     variant.Value,
 		})
 		if err != nil {
 			return fmt.Errorf("inserting variant %s: %w", variant.Name, err)
@@ -156,6 +215,18 @@ func (p *postgresFeatureRepository) Create(ctx context.Context, feature *Feature
 		variant.ID = variantID
 	}
 
+	if err := insertPrerequisites(ctx, queries, feature); err != nil {
+		return err
+	}
+
+	if err := insertRules(ctx, queries, feature); err != nil {
+		return err
+	}
+
+	if err := recordChange(ctx, queries, ChangeCreated, feature.ID, feature); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
@@ -164,7 +235,7 @@ func (p *postgresFeatureRepository) Create(ctx context.Context, feature *Feature
 }
 
 // Update implements FeatureRepository.
-func (p *postgresFeatureRepository) Update(ctx context.Context, feature *Feature) (err error) {
+func (p *postgresFeatureRepository) Update(ctx context.Context, feature *Feature, change ChangeType) (err error) {
 	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
 	defer func() {
 		if err != nil {
@@ -178,19 +249,40 @@ func (p *postgresFeatureRepository) Update(ctx context.Context, feature *Feature
 
 	queries := p.queries.WithTx(tx)
 
-	if err := queries.UpdateFeature(ctx, dbsqlc.UpdateFeatureParams{
-		Name:        feature.Name,
-		Description: textParam(feature.Descritption),
-		Active:      feature.Active,
-		ID:          feature.ID,
-	}); err != nil {
+	version, err := queries.UpdateFeature(ctx, dbsqlc.UpdateFeatureParams{
+		Name:            feature.Name,
+		Description:     textParam(feature.Descritption),
+		Active:          feature.Active,
+		Tags:            tagsParam(feature.Tags),
+		Analysis:        string(feature.Analysis),
+		BanditEventType: banditEventTypeParam(feature.Bandit),
+		BanditMinWeight: banditMinWeightParam(feature.Bandit),
+		ValueType:       string(feature.ValueType),
+		Kind:            string(feature.Kind),
+		ValueSchema:     feature.ValueSchema,
+		ID:              feature.ID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrFeatureNotFound
+		}
+
 		return fmt.Errorf("updating feature: %w", err)
 	}
+	feature.Version = version
 
 	if err := queries.DeleteVariantsByFeature(ctx, pgInt4FromInt32(feature.ID)); err != nil {
 		return fmt.Errorf("deleting existing variants: %w", err)
 	}
 
+	if err := queries.DeletePrerequisitesByFeature(ctx, feature.ID); err != nil {
+		return fmt.Errorf("deleting existing prerequisites: %w", err)
+	}
+
+	if err := queries.DeleteRulesByFeature(ctx, feature.ID); err != nil {
+		return fmt.Errorf("deleting existing rules: %w", err)
+	}
+
 	featureIDParam := pgInt4FromInt32(feature.ID)
 	for i := range feature.Variants {
 		variant := &feature.Variants[i]
@@ -199,6 +291,7 @@ func (p *postgresFeatureRepository) Update(ctx context.Context, feature *Feature
 			FeatureID: featureIDParam,
 			Name:      variant.Name,
 			Weight:    int32(variant.Weight),
+			Value:     variant.Value,
 		})
 		if err != nil {
 			return fmt.Errorf("inserting variant %s: %w", variant.Name, err)
@@ -207,6 +300,18 @@ func (p *postgresFeatureRepository) Update(ctx context.Context, feature *Feature
 		variant.ID = variantID
 	}
 
+	if err := insertPrerequisites(ctx, queries, feature); err != nil {
+		return err
+	}
+
+	if err := insertRules(ctx, queries, feature); err != nil {
+		return err
+	}
+
+	if err := recordChange(ctx, queries, change, feature.ID, feature); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
@@ -214,7 +319,546 @@ func (p *postgresFeatureRepository) Update(ctx context.Context, feature *Feature
 	return nil
 }
 
-func (r *postgresFeatureRepository) Delete(ctx context.Context, id int32) (err error) {
+// UpdateWeights implements FeatureRepository.
+func (p *postgresFeatureRepository) UpdateWeights(ctx context.Context, feature *Feature) (err error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	version, err := queries.BumpFeatureVersion(ctx, dbsqlc.BumpFeatureVersionParams{
+		ID:      feature.ID,
+		Version: feature.Version,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrFeatureVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("updating feature version: %w", err)
+	}
+
+	featureIDParam := pgInt4FromInt32(feature.ID)
+	for _, variant := range feature.Variants {
+		updated, err := queries.UpdateVariantWeight(ctx, dbsqlc.UpdateVariantWeightParams{
+			Weight:    int32(variant.Weight),
+			FeatureID: featureIDParam,
+			Name:      variant.Name,
+		})
+		if err != nil {
+			return fmt.Errorf("updating weight of variant %s: %w", variant.Name, err)
+		}
+		if updated == 0 {
+			return ErrFeatureVersionConflict
+		}
+	}
+	feature.Version = version
+
+	if err := recordChange(ctx, queries, ChangeUpdated, feature.ID, feature); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SetActive implements FeatureRepository.
+func (p *postgresFeatureRepository) SetActive(ctx context.Context, feature *Feature) (err error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	version, err := queries.SetFeatureActive(ctx, dbsqlc.SetFeatureActiveParams{
+		Active:  feature.Active,
+		ID:      feature.ID,
+		Version: feature.Version,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrFeatureVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("updating feature: %w", err)
+	}
+	feature.Version = version
+
+	if err := recordChange(ctx, queries, ChangeToggled, feature.ID, feature); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// SetSampleRatioMismatch implements FeatureRepository. The change is
+// recorded without a snapshot, which is loaded when the change is relayed.
+func (p *postgresFeatureRepository) SetSampleRatioMismatch(ctx context.Context, id int32, mismatch bool) (changed bool, err error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return false, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	_, err = queries.SetSampleRatioMismatch(ctx, dbsqlc.SetSampleRatioMismatchParams{
+		SampleRatioMismatch: mismatch,
+		ID:                  id,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// nothing to change; the deferred rollback only runs on errors
+		return false, tx.Rollback(ctx)
+	}
+	if err != nil {
+		return false, fmt.Errorf("updating sample ratio mismatch: %w", err)
+	}
+
+	if err := recordChange(ctx, queries, ChangeUpdated, id, nil); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return true, nil
+}
+
+// RecordReallocation implements FeatureRepository.
+func (p *postgresFeatureRepository) RecordReallocation(ctx context.Context, reallocation *Reallocation) error {
+	variants, err := json.Marshal(reallocation.Variants)
+	if err != nil {
+		return fmt.Errorf("encoding reallocated variants: %w", err)
+	}
+
+	inserted, err := p.queries.InsertReallocation(ctx, dbsqlc.InsertReallocationParams{
+		FeatureID: reallocation.FeatureID,
+		Variants:  variants,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting reallocation: %w", err)
+	}
+
+	reallocation.ID = inserted.ID
+	reallocation.CreatedAt = inserted.CreatedAt.Time
+
+	return nil
+}
+
+// ListReallocations implements FeatureRepository.
+func (p *postgresFeatureRepository) ListReallocations(ctx context.Context, featureID int32, limit int) ([]*Reallocation, error) {
+	params := dbsqlc.ListReallocationsParams{FeatureID: featureID}
+	if limit > 0 {
+		params.PageSize = pgInt4FromInt32(int32(limit))
+	}
+
+	rows, err := p.queries.ListReallocations(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("selecting reallocations: %w", err)
+	}
+
+	reallocations := make([]*Reallocation, len(rows))
+	for i, row := range rows {
+		reallocation := &Reallocation{
+			ID:        row.ID,
+			FeatureID: row.FeatureID,
+			CreatedAt: row.CreatedAt.Time,
+		}
+		if err := json.Unmarshal(row.Variants, &reallocation.Variants); err != nil {
+			return nil, fmt.Errorf("decoding reallocated variants: %w", err)
+		}
+		reallocations[i] = reallocation
+	}
+
+	return reallocations, nil
+}
+
+// ListDependents implements FeatureRepository.
+func (p *postgresFeatureRepository) ListDependents(ctx context.Context, id int32) ([]string, error) {
+	names, err := p.queries.ListDependentFeatures(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("selecting dependent features: %w", err)
+	}
+
+	return names, nil
+}
+
+// ListDependentPrerequisites implements FeatureRepository.
+func (p *postgresFeatureRepository) ListDependentPrerequisites(ctx context.Context, id int32) ([]Dependent, error) {
+	rows, err := p.queries.ListDependentPrerequisites(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("selecting dependent features: %w", err)
+	}
+
+	dependents := make([]Dependent, len(rows))
+	for i, row := range rows {
+		dependents[i] = Dependent{Name: row.Name, Variants: row.Variants}
+	}
+
+	return dependents, nil
+}
+
+// loadPrerequisites fills in the prerequisites of features.
+func (p *postgresFeatureRepository) loadPrerequisites(ctx context.Context, features ...*Feature) error {
+	if len(features) == 0 {
+		return nil
+	}
+
+	byID := make(map[int32]*Feature, len(features))
+	ids := make([]int32, len(features))
+	for i, feature := range features {
+		byID[feature.ID] = feature
+		ids[i] = feature.ID
+	}
+
+	rows, err := p.queries.ListPrerequisites(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("selecting prerequisites: %w", err)
+	}
+
+	for _, row := range rows {
+		feature := byID[row.FeatureID]
+		feature.Prerequisites = append(feature.Prerequisites, Prerequisite{
+			FeatureID: row.PrerequisiteID,
+			Variants:  row.Variants,
+		})
+	}
+
+	return nil
+}
+
+// SetOverride implements FeatureRepository.
+func (p *postgresFeatureRepository) SetOverride(ctx context.Context, featureID int32, userKey, variant string) (err error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	if _, err := queries.IncrementFeatureVersion(ctx, featureID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrFeatureNotFound
+		}
+
+		return fmt.Errorf("updating feature version: %w", err)
+	}
+
+	err = queries.UpsertOverride(ctx, dbsqlc.UpsertOverrideParams{
+		FeatureID: featureID,
+		UserKey:   userKey,
+		Variant:   variant,
+	})
+	if err != nil {
+		return fmt.Errorf("upserting override: %w", err)
+	}
+
+	if err := recordChange(ctx, queries, ChangeUpdated, featureID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveOverride implements FeatureRepository.
+func (p *postgresFeatureRepository) RemoveOverride(ctx context.Context, featureID int32, userKey string) (err error) {
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	deleted, err := queries.DeleteOverride(ctx, dbsqlc.DeleteOverrideParams{
+		FeatureID: featureID,
+		UserKey:   userKey,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting override: %w", err)
+	}
+	if deleted == 0 {
+		return ErrOverrideNotFound
+	}
+
+	if _, err := queries.IncrementFeatureVersion(ctx, featureID); err != nil {
+		return fmt.Errorf("updating feature version: %w", err)
+	}
+
+	if err := recordChange(ctx, queries, ChangeUpdated, featureID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// loadOverrides fills in the overrides of features.
+func (p *postgresFeatureRepository) loadOverrides(ctx context.Context, features ...*Feature) error {
+	if len(features) == 0 {
+		return nil
+	}
+
+	byID := make(map[int32]*Feature, len(features))
+	ids := make([]int32, len(features))
+	for i, feature := range features {
+		byID[feature.ID] = feature
+		ids[i] = feature.ID
+	}
+
+	rows, err := p.queries.ListOverrides(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("selecting overrides: %w", err)
+	}
+
+	for _, row := range rows {
+		feature := byID[row.FeatureID]
+		if feature.Overrides == nil {
+			feature.Overrides = make(map[string]string)
+		}
+		feature.Overrides[row.UserKey] = row.Variant
+	}
+
+	return nil
+}
+
+// loadRules fills in the rules of features along with their segments.
+func (p *postgresFeatureRepository) loadRules(ctx context.Context, features ...*Feature) error {
+	if len(features) == 0 {
+		return nil
+	}
+
+	byID := make(map[int32]*Feature, len(features))
+	ids := make([]int32, len(features))
+	for i, feature := range features {
+		byID[feature.ID] = feature
+		ids[i] = feature.ID
+	}
+
+	rows, err := p.queries.ListRules(ctx, ids)
+	if err != nil {
+		return fmt.Errorf("selecting rules: %w", err)
+	}
+
+	for _, row := range rows {
+		segment, err := mapSegmentRow(row.Segment)
+		if err != nil {
+			return fmt.Errorf("mapping segment: %w", err)
+		}
+
+		feature := byID[row.FeatureID]
+		feature.Rules = append(feature.Rules, Rule{
+			SegmentID: segment.ID,
+			Variant:   row.Variant,
+			Segment:   segment,
+		})
+	}
+
+	return nil
+}
+
+func insertRules(ctx context.Context, queries *dbsqlc.Queries, feature *Feature) error {
+	for i, rule := range feature.Rules {
+		err := queries.InsertRule(ctx, dbsqlc.InsertRuleParams{
+			FeatureID: feature.ID,
+			Position:  int32(i),
+			SegmentID: rule.SegmentID,
+			Variant:   rule.Variant,
+		})
+		if err != nil {
+			return fmt.Errorf("inserting rule %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func insertPrerequisites(ctx context.Context, queries *dbsqlc.Queries, feature *Feature) error {
+	for _, prerequisite := range feature.Prerequisites {
+		err := queries.InsertPrerequisite(ctx, dbsqlc.InsertPrerequisiteParams{
+			FeatureID:      feature.ID,
+			PrerequisiteID: prerequisite.FeatureID,
+			Variants:       prerequisite.Variants,
+		})
+		if err != nil {
+			return fmt.Errorf("inserting prerequisite %d: %w", prerequisite.FeatureID, err)
+		}
+	}
+
+	return nil
+}
+
+// scheduledVariant is how the variants of a schedule are stored.
+type scheduledVariant struct {
+	Name   string          `json:"name"`
+	Weight uint8           `json:"weight"`
+	Value  json.RawMessage `json:"value,omitempty"`
+}
+
+// ListSchedules implements FeatureRepository.
+func (p *postgresFeatureRepository) ListSchedules(ctx context.Context, featureID int32) ([]*Schedule, error) {
+	rows, err := p.queries.ListSchedules(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting schedules: %w", err)
+	}
+
+	return mapScheduleRows(rows)
+}
+
+// CreateSchedule implements FeatureRepository.
+func (p *postgresFeatureRepository) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	var variants []byte
+	if len(schedule.Variants) > 0 {
+		stored := make([]scheduledVariant, len(schedule.Variants))
+		for i, variant := range schedule.Variants {
+			stored[i] = scheduledVariant{Name: variant.Name, Weight: variant.Weight, Value: variant.Value}
+		}
+
+		var err error
+		if variants, err = json.Marshal(stored); err != nil {
+			return fmt.Errorf("encoding scheduled variants: %w", err)
+		}
+	}
+
+	inserted, err := p.queries.InsertSchedule(ctx, dbsqlc.InsertScheduleParams{
+		FeatureID: schedule.FeatureID,
+		Action:    string(schedule.Action),
+		Variants:  variants,
+		RunAt:     pgtype.Timestamptz{Time: schedule.RunAt, Valid: true},
+		Timezone:  schedule.Timezone,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting schedule: %w", err)
+	}
+
+	schedule.ID = inserted.ID
+	schedule.Status = ScheduleStatus(inserted.Status)
+	schedule.CreatedAt = inserted.CreatedAt.Time
+
+	return nil
+}
+
+// CancelSchedule implements FeatureRepository.
+func (p *postgresFeatureRepository) CancelSchedule(ctx context.Context, featureID int32, scheduleID int64) error {
+	deleted, err := p.queries.DeletePendingSchedule(ctx, dbsqlc.DeletePendingScheduleParams{
+		ID:        scheduleID,
+		FeatureID: featureID,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting schedule: %w", err)
+	}
+
+	if deleted == 0 {
+		return ErrScheduleNotFound
+	}
+
+	return nil
+}
+
+// ListDueSchedules implements FeatureRepository.
+func (p *postgresFeatureRepository) ListDueSchedules(ctx context.Context, limit int) ([]*Schedule, error) {
+	rows, err := p.queries.ListDueSchedules(ctx, int32(limit))
+	if err != nil {
+		return nil, fmt.Errorf("selecting due schedules: %w", err)
+	}
+
+	return mapScheduleRows(rows)
+}
+
+// CompleteSchedule implements FeatureRepository.
+func (p *postgresFeatureRepository) CompleteSchedule(ctx context.Context, schedule *Schedule) error {
+	params := dbsqlc.CompleteScheduleParams{
+		Status: string(schedule.Status),
+		ID:     schedule.ID,
+	}
+	if schedule.Error != "" {
+		params.Error = textParam(schedule.Error)
+	}
+
+	if err := p.queries.CompleteSchedule(ctx, params); err != nil {
+		return fmt.Errorf("updating schedule: %w", err)
+	}
+
+	return nil
+}
+
+func mapScheduleRows(rows []dbsqlc.FeatureSchedule) ([]*Schedule, error) {
+	schedules := make([]*Schedule, len(rows))
+	for i, row := range rows {
+		schedule := &Schedule{
+			ID:         row.ID,
+			FeatureID:  row.FeatureID,
+			Action:     ScheduleAction(row.Action),
+			RunAt:      row.RunAt.Time,
+			Timezone:   row.Timezone,
+			Status:     ScheduleStatus(row.Status),
+			Error:      textToString(row.Error),
+			CreatedAt:  row.CreatedAt.Time,
+			ExecutedAt: row.ExecutedAt.Time,
+		}
+
+		if row.Variants != nil {
+			var stored []scheduledVariant
+			if err := json.Unmarshal(row.Variants, &stored); err != nil {
+				return nil, fmt.Errorf("decoding scheduled variants: %w", err)
+			}
+			for _, variant := range stored {
+				schedule.Variants = append(schedule.Variants, Variant{Name: variant.Name, Weight: variant.Weight, Value: variant.Value})
+			}
+		}
+
+		schedules[i] = schedule
+	}
+
+	return schedules, nil
+}
+
+// Delete implements FeatureRepository.
+func (r *postgresFeatureRepository) Delete(ctx context.Context, feature *Feature) (err error) {
 	tx, err := r.pool.BeginTx(ctx, pgx.TxOptions{})
 	defer func() {
 		if err != nil {
@@ -228,14 +872,18 @@ func (r *postgresFeatureRepository) Delete(ctx context.Context, id int32) (err e
 
 	queries := r.queries.WithTx(tx)
 
-	if err := queries.DeleteVariantsByFeature(ctx, pgInt4FromInt32(id)); err != nil {
+	if err := queries.DeleteVariantsByFeature(ctx, pgInt4FromInt32(feature.ID)); err != nil {
 		return fmt.Errorf("deleting existing variants: %w", err)
 	}
 
-	if err := queries.DeleteFeature(ctx, id); err != nil {
+	if err := queries.DeleteFeature(ctx, feature.ID); err != nil {
 		return fmt.Errorf("deleting feature: %w", err)
 	}
 
+	if err := recordChange(ctx, queries, ChangeDeleted, feature.ID, feature); err != nil {
+		return err
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return fmt.Errorf("commit transaction: %w", err)
 	}
@@ -243,18 +891,47 @@ func (r *postgresFeatureRepository) Delete(ctx context.Context, id int32) (err e
 	return nil
 }
 
-func mapFeatureRow(id int32, name string, description pgtype.Text, active bool) (*Feature, error) {
+func mapFeatureRow(
+	id int32,
+	name string,
+	description pgtype.Text,
+	active bool,
+	version int32,
+	tags []string,
+	sampleRatioMismatch bool,
+	analysis string,
+	banditEventType pgtype.Text,
+	banditMinWeight int32,
+	valueType string,
+	kind string,
+	valueSchema []byte,
+) (*Feature, error) {
 	feature, err := NewFeature(name, textToString(description), active, &Variants{})
 	if err != nil {
 		return nil, err
 	}
 
 	feature.ID = id
+	feature.Version = version
+	feature.Tags = tags
+	feature.SampleRatioMismatch = sampleRatioMismatch
+	feature.Analysis = Analysis(analysis)
+	feature.ValueType = ValueType(valueType)
+	feature.Kind = Kind(kind)
+	feature.ValueSchema = valueSchema
+
+	if banditEventType.Valid {
+		minWeight, err := uint8FromInt32(banditMinWeight)
+		if err != nil {
+			return nil, err
+		}
+		feature.Bandit = &Bandit{EventType: banditEventType.String, MinWeight: minWeight}
+	}
 
 	return feature, nil
 }
 
-func mapVariantRow(id pgtype.Int4, name pgtype.Text, weight pgtype.Int4) (Variant, error) {
+func mapVariantRow(id pgtype.Int4, name pgtype.Text, weight pgtype.Int4, value []byte) (Variant, error) {
 	if !id.Valid {
 		return Variant{}, errors.New("variant id is null")
 	}
@@ -276,6 +953,7 @@ func mapVariantRow(id pgtype.Int4, name pgtype.Text, weight pgtype.Int4) (Varian
 	}
 
 	variant.ID = id.Int32
+	variant.Value = value
 
 	return variant, nil
 }
@@ -295,6 +973,31 @@ func textParam(value string) pgtype.Text {
 	}
 }
 
+// tagsParam avoids writing NULL into the non-null tags column.
+func tagsParam(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+func banditEventTypeParam(bandit *Bandit) pgtype.Text {
+	if bandit == nil {
+		return pgtype.Text{}
+	}
+
+	return textParam(bandit.EventType)
+}
+
+func banditMinWeightParam(bandit *Bandit) int32 {
+	if bandit == nil {
+		return 0
+	}
+
+	return int32(bandit.MinWeight)
+}
+
 func uint8FromInt32(value int32) (uint8, error) {
 	if value < 0 || value > 255 {
 		return 0, fmt.Errorf("value %d cannot be represented as uint8", value)
@@ -0,0 +1,56 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresLocker hands out session level advisory locks. A taken lock keeps
+// its pool connection until it is released.
+type postgresLocker struct {
+	pool *pgxpool.Pool
+}
+
+var _ Locker = (*postgresLocker)(nil)
+
+func NewPostgresLocker(pool *pgxpool.Pool) *postgresLocker {
+	return &postgresLocker{pool: pool}
+}
+
+// TryLock implements Locker.
+func (l *postgresLocker) TryLock(ctx context.Context, key int64) (func(), bool, error) {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("acquiring connection: %w", err)
+	}
+
+	queries := dbsqlc.New(conn)
+	locked, err := queries.TryAdvisoryLock(ctx, key)
+	if err != nil || !locked {
+		conn.Release()
+		if err != nil {
+			return nil, false, fmt.Errorf("taking advisory lock: %w", err)
+		}
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// ctx may be done by now
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if _, err := queries.AdvisoryUnlock(ctx, key); err != nil {
+			// closing the session releases its locks, the pool drops the
+			// closed connection
+			_ = conn.Conn().Close(ctx)
+		}
+		conn.Release()
+	}
+
+	return unlock, true, nil
+}
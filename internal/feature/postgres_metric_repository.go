@@ -0,0 +1,244 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type postgresMetricRepository struct {
+	queries *dbsqlc.Queries
+}
+
+var _ MetricRepository = (*postgresMetricRepository)(nil)
+
+func NewPostgresMetricRepository(queries *dbsqlc.Queries) *postgresMetricRepository {
+	return &postgresMetricRepository{queries: queries}
+}
+
+// GetByID implements MetricRepository.
+func (p *postgresMetricRepository) GetByID(ctx context.Context, id int32) (*Metric, error) {
+	row, err := p.queries.GetMetric(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMetricNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("selecting metric by id: %w", err)
+	}
+
+	return mapMetricRow(row), nil
+}
+
+// List implements MetricRepository.
+func (p *postgresMetricRepository) List(ctx context.Context) ([]*Metric, error) {
+	rows, err := p.queries.ListMetrics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("selecting metrics: %w", err)
+	}
+
+	return mapMetricRows(rows), nil
+}
+
+// ListByFeature implements MetricRepository.
+func (p *postgresMetricRepository) ListByFeature(ctx context.Context, featureID int32) ([]*Metric, error) {
+	rows, err := p.queries.ListMetricsByFeature(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting metrics of feature: %w", err)
+	}
+
+	return mapMetricRows(rows), nil
+}
+
+// Create implements MetricRepository.
+func (p *postgresMetricRepository) Create(ctx context.Context, metric *Metric) error {
+	id, err := p.queries.InsertMetric(ctx, dbsqlc.InsertMetricParams{
+		Name:          metric.Name,
+		Description:   textParam(metric.Description),
+		EventType:     metric.EventType,
+		Aggregation:   string(metric.Aggregation),
+		WindowSeconds: windowParam(metric.Window),
+		Direction:     string(metric.Direction),
+	})
+	if isUniqueViolation(err) {
+		return ErrMetricAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("inserting metric: %w", err)
+	}
+
+	metric.ID = id
+
+	return nil
+}
+
+// Update implements MetricRepository.
+func (p *postgresMetricRepository) Update(ctx context.Context, metric *Metric) error {
+	updated, err := p.queries.UpdateMetric(ctx, dbsqlc.UpdateMetricParams{
+		Name:          metric.Name,
+		Description:   textParam(metric.Description),
+		EventType:     metric.EventType,
+		Aggregation:   string(metric.Aggregation),
+		WindowSeconds: windowParam(metric.Window),
+		Direction:     string(metric.Direction),
+		ID:            metric.ID,
+	})
+	if isUniqueViolation(err) {
+		return ErrMetricAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("updating metric: %w", err)
+	}
+
+	if updated == 0 {
+		return ErrMetricNotFound
+	}
+
+	return nil
+}
+
+// Delete implements MetricRepository. The metric is detached from all
+// features.
+func (p *postgresMetricRepository) Delete(ctx context.Context, id int32) error {
+	deleted, err := p.queries.DeleteMetric(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting metric: %w", err)
+	}
+
+	if deleted == 0 {
+		return ErrMetricNotFound
+	}
+
+	return nil
+}
+
+// Attach implements MetricRepository. Attaching a metric twice is a no-op.
+func (p *postgresMetricRepository) Attach(ctx context.Context, featureID, metricID int32) error {
+	err := p.queries.AttachMetric(ctx, dbsqlc.AttachMetricParams{
+		FeatureID: featureID,
+		MetricID:  metricID,
+	})
+	if err != nil {
+		return fmt.Errorf("attaching metric: %w", err)
+	}
+
+	return nil
+}
+
+// Detach implements MetricRepository.
+func (p *postgresMetricRepository) Detach(ctx context.Context, featureID, metricID int32) error {
+	detached, err := p.queries.DetachMetric(ctx, dbsqlc.DetachMetricParams{
+		FeatureID: featureID,
+		MetricID:  metricID,
+	})
+	if err != nil {
+		return fmt.Errorf("detaching metric: %w", err)
+	}
+
+	if detached == 0 {
+		return ErrMetricNotAttached
+	}
+
+	return nil
+}
+
+// ListGuardrails implements MetricRepository.
+func (p *postgresMetricRepository) ListGuardrails(ctx context.Context, featureID int32) ([]*Guardrail, error) {
+	rows, err := p.queries.ListGuardrailsByFeature(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("selecting guardrails of feature: %w", err)
+	}
+
+	guardrails := make([]*Guardrail, len(rows))
+	for i, row := range rows {
+		metric := mapMetricRow(dbsqlc.Metric{
+			ID:            row.ID,
+			Name:          row.Name,
+			Description:   row.Description,
+			EventType:     row.EventType,
+			Aggregation:   row.Aggregation,
+			WindowSeconds: row.WindowSeconds,
+			Direction:     row.Direction,
+			CreatedAt:     row.CreatedAt,
+		})
+		guardrails[i] = &Guardrail{Metric: *metric, Threshold: row.Threshold}
+	}
+
+	return guardrails, nil
+}
+
+// SetGuardrail implements MetricRepository.
+func (p *postgresMetricRepository) SetGuardrail(ctx context.Context, featureID, metricID int32, threshold float64) error {
+	err := p.queries.SetGuardrail(ctx, dbsqlc.SetGuardrailParams{
+		FeatureID: featureID,
+		MetricID:  metricID,
+		Threshold: threshold,
+	})
+	if err != nil {
+		return fmt.Errorf("upserting guardrail: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveGuardrail implements MetricRepository.
+func (p *postgresMetricRepository) RemoveGuardrail(ctx context.Context, featureID, metricID int32) error {
+	removed, err := p.queries.RemoveGuardrail(ctx, dbsqlc.RemoveGuardrailParams{
+		FeatureID: featureID,
+		MetricID:  metricID,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting guardrail: %w", err)
+	}
+
+	if removed == 0 {
+		return ErrGuardrailNotFound
+	}
+
+	return nil
+}
+
+func mapMetricRows(rows []dbsqlc.Metric) []*Metric {
+	metrics := make([]*Metric, len(rows))
+	for i, row := range rows {
+		metrics[i] = mapMetricRow(row)
+	}
+
+	return metrics
+}
+
+func mapMetricRow(row dbsqlc.Metric) *Metric {
+	metric := &Metric{
+		ID:          row.ID,
+		Name:        row.Name,
+		Description: textToString(row.Description),
+		EventType:   row.EventType,
+		Aggregation: Aggregation(row.Aggregation),
+		Direction:   Direction(row.Direction),
+	}
+	if row.WindowSeconds.Valid {
+		metric.Window = time.Duration(row.WindowSeconds.Int64) * time.Second
+	}
+
+	return metric
+}
+
+// windowParam stores an unlimited window as NULL.
+func windowParam(window time.Duration) pgtype.Int8 {
+	if window <= 0 {
+		return pgtype.Int8{}
+	}
+
+	return pgtype.Int8{Int64: int64(window / time.Second), Valid: true}
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
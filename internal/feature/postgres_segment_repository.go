@@ -0,0 +1,196 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type postgresSegmentRepository struct {
+	pool    *pgxpool.Pool
+	queries *dbsqlc.Queries
+}
+
+var _ SegmentRepository = (*postgresSegmentRepository)(nil)
+
+func NewPostgresSegmentRepository(pool *pgxpool.Pool, queries *dbsqlc.Queries) *postgresSegmentRepository {
+	return &postgresSegmentRepository{
+		pool:    pool,
+		queries: queries,
+	}
+}
+
+// GetByID implements SegmentRepository.
+func (p *postgresSegmentRepository) GetByID(ctx context.Context, id int32) (*Segment, error) {
+	row, err := p.queries.GetSegment(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSegmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("selecting segment by id: %w", err)
+	}
+
+	return mapSegmentRow(row)
+}
+
+// List implements SegmentRepository.
+func (p *postgresSegmentRepository) List(ctx context.Context) ([]*Segment, error) {
+	rows, err := p.queries.ListSegments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("selecting segments: %w", err)
+	}
+
+	segments := make([]*Segment, len(rows))
+	for i, row := range rows {
+		if segments[i], err = mapSegmentRow(row); err != nil {
+			return nil, fmt.Errorf("mapping segment: %w", err)
+		}
+	}
+
+	return segments, nil
+}
+
+// Create implements SegmentRepository.
+func (p *postgresSegmentRepository) Create(ctx context.Context, segment *Segment) error {
+	conditions, err := json.Marshal(conditionsParam(segment.Conditions))
+	if err != nil {
+		return fmt.Errorf("encoding segment conditions: %w", err)
+	}
+
+	inserted, err := p.queries.InsertSegment(ctx, dbsqlc.InsertSegmentParams{
+		Name:        segment.Name,
+		Description: textParam(segment.Description),
+		UserKeys:    tagsParam(segment.UserKeys),
+		Conditions:  conditions,
+	})
+	if isUniqueViolation(err) {
+		return ErrSegmentAlreadyExists
+	}
+	if err != nil {
+		return fmt.Errorf("inserting segment: %w", err)
+	}
+
+	segment.ID = inserted.ID
+	segment.CreatedAt = inserted.CreatedAt.Time
+	segment.UpdatedAt = inserted.UpdatedAt.Time
+
+	return nil
+}
+
+// Update implements SegmentRepository.
+func (p *postgresSegmentRepository) Update(ctx context.Context, segment *Segment) (featureIDs []int32, err error) {
+	conditions, err := json.Marshal(conditionsParam(segment.Conditions))
+	if err != nil {
+		return nil, fmt.Errorf("encoding segment conditions: %w", err)
+	}
+
+	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+
+	queries := p.queries.WithTx(tx)
+
+	updated, err := queries.UpdateSegment(ctx, dbsqlc.UpdateSegmentParams{
+		Name:        segment.Name,
+		Description: textParam(segment.Description),
+		UserKeys:    tagsParam(segment.UserKeys),
+		Conditions:  conditions,
+		ID:          segment.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrSegmentNotFound
+	}
+	if isUniqueViolation(err) {
+		return nil, ErrSegmentAlreadyExists
+	}
+	if err != nil {
+		return nil, fmt.Errorf("updating segment: %w", err)
+	}
+
+	featureIDs, err = queries.TouchSegmentFeatures(ctx, segment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("updating versions of targeting features: %w", err)
+	}
+
+	for _, id := range featureIDs {
+		if err := recordChange(ctx, queries, ChangeUpdated, id, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	segment.CreatedAt = updated.CreatedAt.Time
+	segment.UpdatedAt = updated.UpdatedAt.Time
+
+	return featureIDs, nil
+}
+
+// Delete implements SegmentRepository.
+func (p *postgresSegmentRepository) Delete(ctx context.Context, id int32) error {
+	deleted, err := p.queries.DeleteSegment(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting segment: %w", err)
+	}
+
+	if deleted == 0 {
+		return ErrSegmentNotFound
+	}
+
+	return nil
+}
+
+// ListUsage implements SegmentRepository.
+func (p *postgresSegmentRepository) ListUsage(ctx context.Context, id int32) ([]SegmentUsage, error) {
+	rows, err := p.queries.ListSegmentFeatures(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("selecting features of segment: %w", err)
+	}
+
+	usage := make([]SegmentUsage, len(rows))
+	for i, row := range rows {
+		usage[i] = SegmentUsage{FeatureID: row.ID, Feature: row.Name}
+	}
+
+	return usage, nil
+}
+
+func mapSegmentRow(row dbsqlc.Segment) (*Segment, error) {
+	segment := &Segment{
+		ID:          row.ID,
+		Name:        row.Name,
+		Description: textToString(row.Description),
+		UserKeys:    row.UserKeys,
+		CreatedAt:   row.CreatedAt.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+	if err := json.Unmarshal(row.Conditions, &segment.Conditions); err != nil {
+		return nil, fmt.Errorf("decoding segment conditions: %w", err)
+	}
+
+	return segment, nil
+}
+
+// conditionsParam avoids writing null into the non-null conditions column.
+func conditionsParam(conditions []Condition) []Condition {
+	if conditions == nil {
+		return []Condition{}
+	}
+
+	return conditions
+}
@@ -0,0 +1,258 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	dbsqlc "github.com/eve-an/splitter/internal/db/sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type postgresWebhookRepository struct {
+	queries *dbsqlc.Queries
+}
+
+var _ WebhookRepository = (*postgresWebhookRepository)(nil)
+
+func NewPostgresWebhookRepository(queries *dbsqlc.Queries) *postgresWebhookRepository {
+	return &postgresWebhookRepository{queries: queries}
+}
+
+// GetByID implements WebhookRepository.
+func (p *postgresWebhookRepository) GetByID(ctx context.Context, id int32) (*Webhook, error) {
+	row, err := p.queries.GetWebhook(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("selecting webhook by id: %w", err)
+	}
+
+	return mapWebhookRow(row), nil
+}
+
+// List implements WebhookRepository.
+func (p *postgresWebhookRepository) List(ctx context.Context) ([]*Webhook, error) {
+	rows, err := p.queries.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("selecting webhooks: %w", err)
+	}
+
+	webhooks := make([]*Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = mapWebhookRow(row)
+	}
+
+	return webhooks, nil
+}
+
+// Create implements WebhookRepository.
+func (p *postgresWebhookRepository) Create(ctx context.Context, webhook *Webhook) error {
+	inserted, err := p.queries.InsertWebhook(ctx, dbsqlc.InsertWebhookParams{
+		Url:    webhook.URL,
+		Secret: webhook.Secret,
+		Events: webhookEventsParam(webhook.Events),
+		Active: webhook.Active,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting webhook: %w", err)
+	}
+
+	webhook.ID = inserted.ID
+	webhook.CreatedAt = inserted.CreatedAt.Time
+	webhook.UpdatedAt = inserted.UpdatedAt.Time
+
+	return nil
+}
+
+// Update implements WebhookRepository.
+func (p *postgresWebhookRepository) Update(ctx context.Context, webhook *Webhook) error {
+	updated, err := p.queries.UpdateWebhook(ctx, dbsqlc.UpdateWebhookParams{
+		Url:    webhook.URL,
+		Secret: webhook.Secret,
+		Events: webhookEventsParam(webhook.Events),
+		Active: webhook.Active,
+		ID:     webhook.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("updating webhook: %w", err)
+	}
+
+	webhook.CreatedAt = updated.CreatedAt.Time
+	webhook.UpdatedAt = updated.UpdatedAt.Time
+
+	return nil
+}
+
+// Delete implements WebhookRepository.
+func (p *postgresWebhookRepository) Delete(ctx context.Context, id int32) error {
+	deleted, err := p.queries.DeleteWebhook(ctx, id)
+	if err != nil {
+		return fmt.Errorf("deleting webhook: %w", err)
+	}
+
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+// Enqueue implements WebhookRepository.
+func (p *postgresWebhookRepository) Enqueue(ctx context.Context, event WebhookEvent, payload []byte) (int64, error) {
+	queued, err := p.queries.EnqueueWebhookDeliveries(ctx, dbsqlc.EnqueueWebhookDeliveriesParams{
+		Event:   string(event),
+		Payload: payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("inserting webhook deliveries: %w", err)
+	}
+
+	return queued, nil
+}
+
+// CreateDelivery implements WebhookRepository.
+func (p *postgresWebhookRepository) CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	row, err := p.queries.InsertWebhookDelivery(ctx, dbsqlc.InsertWebhookDeliveryParams{
+		WebhookID: delivery.WebhookID,
+		Event:     string(delivery.Event),
+		Payload:   delivery.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("inserting webhook delivery: %w", err)
+	}
+
+	*delivery = *mapWebhookDeliveryRow(row)
+
+	return nil
+}
+
+// ClaimDeliveries implements WebhookRepository.
+func (p *postgresWebhookRepository) ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]DueWebhookDelivery, error) {
+	rows, err := p.queries.ClaimWebhookDeliveries(ctx, dbsqlc.ClaimWebhookDeliveriesParams{
+		LeaseUntil: pgtype.Timestamptz{Time: leaseUntil, Valid: true},
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claiming webhook deliveries: %w", err)
+	}
+
+	due := make([]DueWebhookDelivery, len(rows))
+	for i, row := range rows {
+		due[i] = DueWebhookDelivery{
+			Delivery: mapWebhookDeliveryRow(dbsqlc.WebhookDelivery{
+				ID:             row.ID,
+				WebhookID:      row.WebhookID,
+				Event:          row.Event,
+				Payload:        row.Payload,
+				Status:         row.Status,
+				Attempts:       row.Attempts,
+				ResponseStatus: row.ResponseStatus,
+				Error:          row.Error,
+				NextAttemptAt:  row.NextAttemptAt,
+				CreatedAt:      row.CreatedAt,
+				CompletedAt:    row.CompletedAt,
+			}),
+			URL:    row.Url,
+			Secret: row.Secret,
+		}
+	}
+
+	return due, nil
+}
+
+// RecordAttempt implements WebhookRepository.
+func (p *postgresWebhookRepository) RecordAttempt(ctx context.Context, delivery *WebhookDelivery) error {
+	params := dbsqlc.RecordWebhookAttemptParams{
+		Status:        string(delivery.Status),
+		Attempts:      int32(delivery.Attempts),
+		NextAttemptAt: pgtype.Timestamptz{Time: delivery.NextAttemptAt, Valid: true},
+		ID:            delivery.ID,
+	}
+	if delivery.Error != "" {
+		params.Error = textParam(delivery.Error)
+	}
+	if delivery.ResponseStatus != 0 {
+		params.ResponseStatus = pgInt4FromInt32(int32(delivery.ResponseStatus))
+	}
+	if !delivery.CompletedAt.IsZero() {
+		params.CompletedAt = pgtype.Timestamptz{Time: delivery.CompletedAt, Valid: true}
+	}
+
+	if err := p.queries.RecordWebhookAttempt(ctx, params); err != nil {
+		return fmt.Errorf("updating webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ListDeliveries implements WebhookRepository.
+func (p *postgresWebhookRepository) ListDeliveries(ctx context.Context, webhookID int32, limit int) ([]*WebhookDelivery, error) {
+	params := dbsqlc.ListWebhookDeliveriesParams{WebhookID: webhookID}
+	if limit > 0 {
+		params.PageSize = pgInt4FromInt32(int32(limit))
+	}
+
+	rows, err := p.queries.ListWebhookDeliveries(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("selecting webhook deliveries: %w", err)
+	}
+
+	deliveries := make([]*WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = mapWebhookDeliveryRow(row)
+	}
+
+	return deliveries, nil
+}
+
+func mapWebhookRow(row dbsqlc.Webhook) *Webhook {
+	webhook := &Webhook{
+		ID:        row.ID,
+		URL:       row.Url,
+		Secret:    row.Secret,
+		Events:    make([]WebhookEvent, len(row.Events)),
+		Active:    row.Active,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+	for i, event := range row.Events {
+		webhook.Events[i] = WebhookEvent(event)
+	}
+
+	return webhook
+}
+
+func mapWebhookDeliveryRow(row dbsqlc.WebhookDelivery) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:             row.ID,
+		WebhookID:      row.WebhookID,
+		Event:          WebhookEvent(row.Event),
+		Payload:        row.Payload,
+		Status:         WebhookDeliveryStatus(row.Status),
+		Attempts:       int(row.Attempts),
+		ResponseStatus: int(row.ResponseStatus.Int32),
+		Error:          textToString(row.Error),
+		NextAttemptAt:  row.NextAttemptAt.Time,
+		CreatedAt:      row.CreatedAt.Time,
+		CompletedAt:    row.CompletedAt.Time,
+	}
+}
+
+// webhookEventsParam stores an empty filter as an empty array, which the
+// column requires instead of NULL.
+func webhookEventsParam(events []WebhookEvent) []string {
+	param := make([]string, len(events))
+	for i, event := range events {
+		param[i] = string(event)
+	}
+
+	return param
+}
@@ -0,0 +1,151 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrPrerequisiteVariantsRequired = errors.New("prerequisites require variants")
+	ErrDuplicatePrerequisite        = errors.New("prerequisite listed more than once")
+	ErrPrerequisiteCycle            = errors.New("prerequisites must not depend on the feature")
+	ErrPrerequisiteNotFound         = errors.New("prerequisite feature not found")
+	ErrUnknownPrerequisiteVariant   = errors.New("prerequisite variant does not exist")
+	ErrFeatureIsPrerequisite        = errors.New("feature is a prerequisite of other features")
+)
+
+// Prerequisite restricts a feature to the users assigned one of Variants of
+// the feature FeatureID. Users failing it are not bucketed.
+type Prerequisite struct {
+	FeatureID int32
+	Variants  []string
+}
+
+// Dependent is a feature having another feature as prerequisite, requiring
+// Variants of it.
+type Dependent struct {
+	Name     string
+	Variants []string
+}
+
+func validatePrerequisites(featureID int32, prerequisites []Prerequisite) error {
+	var errs []error
+	seen := make(map[int32]struct{}, len(prerequisites))
+	for _, prerequisite := range prerequisites {
+		if prerequisite.FeatureID <= 0 {
+			errs = append(errs, ErrInvalidFeatureID)
+		}
+		if featureID != 0 && prerequisite.FeatureID == featureID {
+			errs = append(errs, ErrPrerequisiteCycle)
+		}
+		if len(prerequisite.Variants) == 0 {
+			errs = append(errs, ErrPrerequisiteVariantsRequired)
+		}
+
+		if _, found := seen[prerequisite.FeatureID]; found {
+			errs = append(errs, ErrDuplicatePrerequisite)
+		}
+		seen[prerequisite.FeatureID] = struct{}{}
+	}
+
+	return errors.Join(errs...)
+}
+
+func equalPrerequisites(a, b []Prerequisite) bool {
+	return slices.EqualFunc(a, b, func(x, y Prerequisite) bool {
+		return x.FeatureID == y.FeatureID && slices.Equal(x.Variants, y.Variants)
+	})
+}
+
+// checkPrerequisites makes sure the prerequisites of feature exist, have the
+// required variants and do not depend on feature themselves, directly or
+// through their own prerequisites.
+func (s *Service) checkPrerequisites(ctx context.Context, feature *Feature) error {
+	for _, prerequisite := range feature.Prerequisites {
+		required, err := s.featureRepo.GetByID(ctx, prerequisite.FeatureID)
+		if errors.Is(err, ErrFeatureNotFound) {
+			return fmt.Errorf("%w: %d", ErrPrerequisiteNotFound, prerequisite.FeatureID)
+		}
+		if err != nil {
+			return fmt.Errorf("get prerequisite: %w", err)
+		}
+
+		names := required.Variants.Names()
+		for _, variant := range prerequisite.Variants {
+			if !slices.Contains(names, variant) {
+				return fmt.Errorf("%w: %s of %s", ErrUnknownPrerequisiteVariant, variant, required.Name)
+			}
+		}
+	}
+
+	// new features cannot be the prerequisite of anything yet
+	if feature.ID == 0 {
+		return nil
+	}
+
+	pending := make([]int32, 0, len(feature.Prerequisites))
+	for _, prerequisite := range feature.Prerequisites {
+		pending = append(pending, prerequisite.FeatureID)
+	}
+
+	visited := make(map[int32]struct{})
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		if id == feature.ID {
+			return ErrPrerequisiteCycle
+		}
+		if _, found := visited[id]; found {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		required, err := s.featureRepo.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("get prerequisite: %w", err)
+		}
+		for _, prerequisite := range required.Prerequisites {
+			pending = append(pending, prerequisite.FeatureID)
+		}
+	}
+
+	return nil
+}
+
+// checkDependents makes sure the features having feature as prerequisite
+// still find the variants they require.
+func (s *Service) checkDependents(ctx context.Context, feature *Feature) error {
+	dependents, err := s.featureRepo.ListDependentPrerequisites(ctx, feature.ID)
+	if err != nil {
+		return fmt.Errorf("list dependent features: %w", err)
+	}
+
+	names := feature.Variants.Names()
+	for _, dependent := range dependents {
+		for _, variant := range dependent.Variants {
+			if !slices.Contains(names, variant) {
+				return fmt.Errorf("%w: %s required by %s", ErrUnknownPrerequisiteVariant, variant, dependent.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkNotPrerequisite refuses to delete features others depend on.
+func (s *Service) checkNotPrerequisite(ctx context.Context, id int32) error {
+	dependents, err := s.featureRepo.ListDependents(ctx, id)
+	if err != nil {
+		return fmt.Errorf("list dependent features: %w", err)
+	}
+
+	if len(dependents) > 0 {
+		return fmt.Errorf("%w: %s", ErrFeatureIsPrerequisite, strings.Join(dependents, ", "))
+	}
+
+	return nil
+}
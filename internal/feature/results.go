@@ -0,0 +1,493 @@
+package feature
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/eve-an/splitter/internal/stats"
+)
+
+// ExposureEventType marks the event sent when a user is shown a variant.
+// Results only count users with an exposure, and only their events after
+// the first exposure, attributed to the variant they were first exposed to.
+const ExposureEventType = "exposure"
+
+// ExposureAggregate counts the exposed users of a variant.
+type ExposureAggregate struct {
+	Variant string
+	Users   int64
+}
+
+// MetricAggregate summarises the events of one metric within one variant.
+// Users counts exposed users with at least one event. The sums are taken
+// over per user values: the number of events, the sum of their values and,
+// for users with values, the mean of their values.
+type MetricAggregate struct {
+	Variant         string
+	Metric          string
+	Users           int64
+	CountSum        float64
+	CountSumSquares float64
+	ValueUsers      int64
+	ValueSum        float64
+	ValueSumSquares float64
+	MeanSum         float64
+	MeanSumSquares  float64
+}
+
+// CovariateAggregate pairs the per user values of a metric within a variant
+// with the same values over CUPEDLookback before each user's exposure. Unlike
+// MetricAggregate, it covers every exposed user.
+type CovariateAggregate struct {
+	Variant             string
+	Metric              string
+	Users               int64
+	MetricSum           float64
+	MetricSumSquares    float64
+	CovariateSum        float64
+	CovariateSumSquares float64
+	CrossSum            float64
+}
+
+type EventAggregate struct {
+	Exposures []ExposureAggregate
+	Metrics   []MetricAggregate
+}
+
+// Verdict sums up how a variant compares to the control on a metric, taking
+// the metric's direction into account.
+type Verdict string
+
+const (
+	VerdictImprovement  Verdict = "improvement"
+	VerdictRegression   Verdict = "regression"
+	VerdictInconclusive Verdict = "inconclusive"
+)
+
+var ErrInvalidResultsMode = errors.New("invalid results mode")
+
+// ResultsMode decides how results compare conversion rates.
+type ResultsMode string
+
+const (
+	// ResultsFrequentist tests conversion rates with the tests of the
+	// feature's Analysis.
+	ResultsFrequentist ResultsMode = "frequentist"
+	// ResultsBayesian reports Beta-Binomial posteriors of conversion rates,
+	// the probability of beating the control and the expected loss instead.
+	ResultsBayesian ResultsMode = "bayesian"
+)
+
+func (m ResultsMode) Valid() bool {
+	return m == ResultsFrequentist || m == ResultsBayesian
+}
+
+// bayesianDecisionProbability is the probability to beat the control above
+// which, or below one minus which, a Bayesian verdict is reached.
+const bayesianDecisionProbability = 0.95
+
+// CUPEDLookback is how far before their exposure the activity of users is
+// used to reduce the variance of results.
+const CUPEDLookback = 14 * 24 * time.Hour
+
+// sequentialEffectSize is the standardised difference sequential tests are
+// most powerful for.
+const sequentialEffectSize = 0.1
+
+// Results compares the variants of a feature. The first variant of the
+// feature is the control every other variant is tested against, with the
+// tests of the feature's Analysis.
+type Results struct {
+	FeatureID int32
+	Control   string
+	Mode      ResultsMode
+	Analysis  Analysis
+	Exposures []ExposureAggregate
+	// SampleRatio is nil when there are not enough exposures to check.
+	SampleRatio *SampleRatio
+	Metrics     []MetricResult
+}
+
+// MetricResult holds the results of one metric. Features without attached
+// metrics get one metric per event type, with a zero ID, counting unique
+// users or, if the events carry values, summing them.
+//
+// CUPED is the variance reduction applied to the adjusted results of the
+// variants. It is nil for mean metrics and when no user had activity before
+// the exposure.
+type MetricResult struct {
+	Metric   Metric
+	CUPED    *stats.CUPED
+	Variants []VariantMetric
+}
+
+// VariantMetric reports a metric for one variant. Sum counts events for
+// count metrics and adds up values otherwise. Mean and StdDev are per
+// exposed user, counting users without events as zero, except for mean
+// metrics, where they are taken over the users with values.
+//
+// The tests and the verdict are unset for the control and tests are nil
+// when there is not enough data. MeanTest is only set for metrics that are
+// not unique. In ResultsBayesian mode, Bayesian replaces ConversionTest and
+// decides the verdict of unique metrics. Otherwise the verdict follows the
+// adjusted test, if there is one.
+type VariantMetric struct {
+	Variant        string
+	Users          int64
+	Conversions    int64
+	ConversionRate float64
+	Sum            float64
+	Mean           float64
+	StdDev         float64
+	ConversionTest *stats.TestResult
+	MeanTest       *stats.TestResult
+	Bayesian       *BayesianConversion
+	Adjusted       *AdjustedMetric
+	Verdict        Verdict
+}
+
+// AdjustedMetric is the mean per exposed user after the CUPED adjustment,
+// tested against the adjusted control; Test is nil for the control.
+type AdjustedMetric struct {
+	Mean   float64
+	StdDev float64
+	Test   *stats.TestResult
+}
+
+// BayesianConversion is the posterior of a variant's conversion rate with
+// its credible interval. Comparison with the control is nil for the control.
+type BayesianConversion struct {
+	Posterior  stats.BetaDistribution
+	Lower      float64
+	Upper      float64
+	Comparison *stats.BayesianComparison
+}
+
+// GetResults aggregates the events of a feature into results for each of
+// its metrics. An empty mode is ResultsFrequentist.
+func (s *Service) GetResults(ctx context.Context, featureID int32, mode ResultsMode) (*Results, error) {
+	if mode == "" {
+		mode = ResultsFrequentist
+	}
+	if !mode.Valid() {
+		return nil, ErrInvalidResultsMode
+	}
+
+	feature, err := s.GetFeature(ctx, featureID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := s.metricRepo.ListByFeature(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("list metrics: %w", err)
+	}
+
+	aggregate, err := s.eventRepo.Aggregate(ctx, featureID, metrics)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate events: %w", err)
+	}
+
+	if len(metrics) == 0 {
+		metrics = eventTypeMetrics(aggregate)
+	}
+
+	covariates, err := s.eventRepo.AggregateCovariates(ctx, featureID, metrics, CUPEDLookback)
+	if err != nil {
+		return nil, fmt.Errorf("aggregate covariates: %w", err)
+	}
+
+	return buildResults(feature, mode, metrics, aggregate, covariates), nil
+}
+
+// eventTypeMetrics defines a metric for every event type in aggregate.
+func eventTypeMetrics(aggregate *EventAggregate) []*Metric {
+	byName := make(map[string]*Metric)
+	var metrics []*Metric
+	for _, m := range aggregate.Metrics {
+		metric, ok := byName[m.Metric]
+		if !ok {
+			metric = &Metric{
+				Name:        m.Metric,
+				EventType:   m.Metric,
+				Aggregation: AggregationUnique,
+				Direction:   DirectionIncrease,
+			}
+			byName[m.Metric] = metric
+			metrics = append(metrics, metric)
+		}
+
+		if m.ValueUsers > 0 {
+			metric.Aggregation = AggregationSum
+		}
+	}
+
+	slices.SortFunc(metrics, func(a, b *Metric) int { return cmp.Compare(a.Name, b.Name) })
+
+	return metrics
+}
+
+func buildResults(
+	feature *Feature,
+	mode ResultsMode,
+	metrics []*Metric,
+	aggregate *EventAggregate,
+	covariates []CovariateAggregate,
+) *Results {
+	variants := resultVariants(feature, aggregate)
+
+	exposed := make(map[string]int64, len(aggregate.Exposures))
+	for _, exposure := range aggregate.Exposures {
+		exposed[exposure.Variant] = exposure.Users
+	}
+
+	results := &Results{
+		FeatureID:   feature.ID,
+		Mode:        mode,
+		Analysis:    feature.Analysis,
+		Exposures:   make([]ExposureAggregate, len(variants)),
+		SampleRatio: checkSampleRatio(feature, aggregate.Exposures),
+		Metrics:     make([]MetricResult, len(metrics)),
+	}
+	if len(variants) > 0 {
+		results.Control = variants[0]
+	}
+	for i, variant := range variants {
+		results.Exposures[i] = ExposureAggregate{Variant: variant, Users: exposed[variant]}
+	}
+
+	byMetric := make(map[string]map[string]MetricAggregate, len(metrics))
+	for _, m := range aggregate.Metrics {
+		if byMetric[m.Metric] == nil {
+			byMetric[m.Metric] = make(map[string]MetricAggregate)
+		}
+		byMetric[m.Metric][m.Variant] = m
+	}
+
+	byCovariate := make(map[string]map[string]stats.CovariateSample, len(metrics))
+	for _, c := range covariates {
+		if byCovariate[c.Metric] == nil {
+			byCovariate[c.Metric] = make(map[string]stats.CovariateSample)
+		}
+		byCovariate[c.Metric][c.Variant] = stats.CovariateSample{
+			N:                   c.Users,
+			MetricSum:           c.MetricSum,
+			MetricSumSquares:    c.MetricSumSquares,
+			CovariateSum:        c.CovariateSum,
+			CovariateSumSquares: c.CovariateSumSquares,
+			CrossSum:            c.CrossSum,
+		}
+	}
+
+	for i, metric := range metrics {
+		result := MetricResult{Metric: *metric, Variants: make([]VariantMetric, len(variants))}
+		result.CUPED = cuped(metric, byCovariate[metric.Name])
+
+		var control MetricAggregate
+		var controlSample stats.Sample
+		var controlBayesian *BayesianConversion
+		var controlAdjusted stats.Sample
+		for j, variant := range variants {
+			m := byMetric[metric.Name][variant]
+			n := exposed[variant]
+			sample := metricSample(metric.Aggregation, m, n)
+
+			vm := VariantMetric{
+				Variant:     variant,
+				Users:       n,
+				Conversions: m.Users,
+				Sum:         m.ValueSum,
+				Mean:        sample.Mean,
+				StdDev:      math.Sqrt(sample.Variance),
+			}
+			if metric.Aggregation == AggregationCount {
+				vm.Sum = m.CountSum
+			}
+			if n > 0 {
+				vm.ConversionRate = float64(m.Users) / float64(n)
+			}
+			if mode == ResultsBayesian {
+				vm.Bayesian = bayesianConversion(m.Users, n)
+			}
+
+			var adjusted stats.Sample
+			if result.CUPED != nil {
+				adjusted = result.CUPED.Adjust(byCovariate[metric.Name][variant])
+				vm.Adjusted = &AdjustedMetric{Mean: adjusted.Mean, StdDev: math.Sqrt(adjusted.Variance)}
+			}
+
+			if j == 0 {
+				control, controlSample, controlBayesian, controlAdjusted = m, sample, vm.Bayesian, adjusted
+				result.Variants[j] = vm
+				continue
+			}
+
+			if vm.Adjusted != nil {
+				if test, ok := meanTest(feature.Analysis, controlAdjusted, adjusted); ok {
+					vm.Adjusted.Test = &test
+				}
+			}
+
+			if mode == ResultsBayesian {
+				comparison := stats.CompareBetas(controlBayesian.Posterior, vm.Bayesian.Posterior)
+				vm.Bayesian.Comparison = &comparison
+			} else if test, ok := conversionTest(feature.Analysis, control.Users, exposed[variants[0]], m.Users, n); ok {
+				vm.ConversionTest = &test
+			}
+
+			if metric.Aggregation != AggregationUnique {
+				if test, ok := meanTest(feature.Analysis, controlSample, sample); ok {
+					vm.MeanTest = &test
+				}
+			}
+			vm.Verdict = metricVerdict(metric, vm)
+
+			result.Variants[j] = vm
+		}
+
+		results.Metrics[i] = result
+	}
+
+	return results
+}
+
+// metricSample returns the per user values of a metric within a variant
+// with n exposed users.
+func metricSample(aggregation Aggregation, m MetricAggregate, n int64) stats.Sample {
+	switch aggregation {
+	case AggregationUnique:
+		return conversionSample(m.Users, n)
+	case AggregationCount:
+		return stats.SampleFromSums(n, m.CountSum, m.CountSumSquares)
+	case AggregationMean:
+		return stats.SampleFromSums(m.ValueUsers, m.MeanSum, m.MeanSumSquares)
+	default:
+		return stats.SampleFromSums(n, m.ValueSum, m.ValueSumSquares)
+	}
+}
+
+// conversionSample returns conversions out of n users as a sample of ones
+// and zeros.
+func conversionSample(conversions, n int64) stats.Sample {
+	// a conversion is 1, so the sum of squares equals the sum
+	return stats.SampleFromSums(n, float64(conversions), float64(conversions))
+}
+
+func conversionTest(analysis Analysis, controlConversions, controlUsers, conversions, users int64) (stats.TestResult, bool) {
+	if analysis == AnalysisSequential {
+		return stats.MixtureSPRT(
+			conversionSample(controlConversions, controlUsers),
+			conversionSample(conversions, users),
+			sequentialEffectSize,
+		)
+	}
+
+	return stats.TwoProportionZTest(controlConversions, controlUsers, conversions, users)
+}
+
+func meanTest(analysis Analysis, control, treatment stats.Sample) (stats.TestResult, bool) {
+	if analysis == AnalysisSequential {
+		return stats.MixtureSPRT(control, treatment, sequentialEffectSize)
+	}
+
+	return stats.WelchTTest(control, treatment)
+}
+
+func bayesianConversion(conversions, n int64) *BayesianConversion {
+	posterior := stats.BetaPosterior(conversions, n)
+	lower, upper := posterior.CredibleInterval()
+
+	return &BayesianConversion{Posterior: posterior, Lower: lower, Upper: upper}
+}
+
+// cuped estimates the CUPED adjustment of a metric from the covariates of
+// its variants. Mean metrics average over users with values only, so they
+// are not adjusted.
+func cuped(metric *Metric, covariates map[string]stats.CovariateSample) *stats.CUPED {
+	if metric.Aggregation == AggregationMean || len(covariates) == 0 {
+		return nil
+	}
+
+	samples := make([]stats.CovariateSample, 0, len(covariates))
+	for _, sample := range covariates {
+		samples = append(samples, sample)
+	}
+
+	adjustment, ok := stats.NewCUPED(samples...)
+	if !ok {
+		return nil
+	}
+
+	return &adjustment
+}
+
+// metricVerdict decides the verdict of a variant from the Bayesian
+// comparison of a unique metric or else the adjusted test, falling back to
+// the unadjusted one.
+func metricVerdict(metric *Metric, vm VariantMetric) Verdict {
+	if metric.Aggregation == AggregationUnique && vm.Bayesian != nil && vm.Bayesian.Comparison != nil {
+		return bayesianVerdict(metric.Direction, vm.Bayesian.Comparison)
+	}
+
+	if vm.Adjusted != nil && vm.Adjusted.Test != nil {
+		return verdict(metric.Direction, vm.Adjusted.Test)
+	}
+
+	if metric.Aggregation == AggregationUnique {
+		return verdict(metric.Direction, vm.ConversionTest)
+	}
+
+	return verdict(metric.Direction, vm.MeanTest)
+}
+
+func bayesianVerdict(direction Direction, comparison *stats.BayesianComparison) Verdict {
+	probability := comparison.ProbabilityToBeat
+	if direction == DirectionDecrease {
+		probability = 1 - probability
+	}
+
+	switch {
+	case probability >= bayesianDecisionProbability:
+		return VerdictImprovement
+	case probability <= 1-bayesianDecisionProbability:
+		return VerdictRegression
+	default:
+		return VerdictInconclusive
+	}
+}
+
+func verdict(direction Direction, test *stats.TestResult) Verdict {
+	if test == nil || test.PValue >= 1-stats.ConfidenceLevel {
+		return VerdictInconclusive
+	}
+
+	if (test.Difference > 0) == (direction == DirectionIncrease) {
+		return VerdictImprovement
+	}
+
+	return VerdictRegression
+}
+
+// resultVariants lists the variants of the feature in order, followed by
+// variants only seen in events, such as ones removed since.
+func resultVariants(feature *Feature, aggregate *EventAggregate) []string {
+	variants := feature.Variants.Names()
+
+	var extra []string
+	seen := func(name string) bool {
+		return slices.Contains(variants, name) || slices.Contains(extra, name)
+	}
+	for _, exposure := range aggregate.Exposures {
+		if !seen(exposure.Variant) {
+			extra = append(extra, exposure.Variant)
+		}
+	}
+	slices.Sort(extra)
+
+	return append(variants, extra...)
+}
@@ -0,0 +1,60 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrRuleSegmentRequired = errors.New("rules require a segment")
+	ErrUnknownRuleVariant  = errors.New("rule variant does not exist on feature")
+	ErrUnknownSegment      = errors.New("rule segment does not exist")
+)
+
+// Rule serves Variant to the users in the segment SegmentID. The rules of a
+// feature are evaluated in order after its prerequisites and the first
+// matching one wins; users matching none are bucketed.
+type Rule struct {
+	SegmentID int32
+	Variant   string
+	// Segment is loaded along with the feature.
+	Segment *Segment
+}
+
+func validateRules(variants Variants, rules []Rule) error {
+	names := variants.Names()
+
+	var errs []error
+	for _, rule := range rules {
+		if rule.SegmentID <= 0 {
+			errs = append(errs, ErrRuleSegmentRequired)
+		}
+		if !slices.Contains(names, rule.Variant) {
+			errs = append(errs, ErrUnknownRuleVariant)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// loadRuleSegments makes sure the segments of the rules of feature exist and
+// sets them on the rules.
+func (s *Service) loadRuleSegments(ctx context.Context, feature *Feature) error {
+	for i := range feature.Rules {
+		rule := &feature.Rules[i]
+
+		segment, err := s.segmentRepo.GetByID(ctx, rule.SegmentID)
+		if errors.Is(err, ErrSegmentNotFound) {
+			return fmt.Errorf("%w: %d", ErrUnknownSegment, rule.SegmentID)
+		}
+		if err != nil {
+			return fmt.Errorf("get segment: %w", err)
+		}
+
+		rule.Segment = segment
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package feature
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/eve-an/splitter/internal/stats"
+)
+
+// SampleRatioThreshold is the p-value below which exposures are taken to
+// mismatch the variant weights. It is far stricter than ConfidenceLevel
+// since every feature is checked over and over again.
+const SampleRatioThreshold = 0.001
+
+// minExpectedExposures is the smallest expected number of exposures per
+// variant for which the chi-square approximation holds.
+const minExpectedExposures = 5
+
+// SampleRatio compares the exposures of a feature with its variant weights.
+type SampleRatio struct {
+	Variants []SampleRatioVariant
+	Test     stats.GoodnessOfFit
+	Mismatch bool
+}
+
+type SampleRatioVariant struct {
+	Variant  string
+	Weight   uint8
+	Users    int64
+	Expected float64
+}
+
+// checkSampleRatio runs a chi-square test of the exposures against the
+// weights of the variants. Variants without weight and exposures of variants
+// the feature no longer has are left out. It returns nil if there are fewer
+// than two weighted variants or not enough exposures, for features that are
+// not experiments, and for bandit features, whose exposures follow weights
+// that change over time.
+func checkSampleRatio(feature *Feature, exposures []ExposureAggregate) *SampleRatio {
+	if feature.Kind != KindExperiment || feature.Bandit != nil {
+		return nil
+	}
+
+	exposed := make(map[string]int64, len(exposures))
+	for _, exposure := range exposures {
+		exposed[exposure.Variant] = exposure.Users
+	}
+
+	var observed []int64
+	var weights []float64
+	var total int64
+	var totalWeight float64
+	ratio := &SampleRatio{}
+	for _, variant := range feature.Variants {
+		if variant.Weight == 0 {
+			continue
+		}
+
+		users := exposed[variant.Name]
+		observed = append(observed, users)
+		weights = append(weights, float64(variant.Weight))
+		total += users
+		totalWeight += float64(variant.Weight)
+		ratio.Variants = append(ratio.Variants, SampleRatioVariant{
+			Variant: variant.Name,
+			Weight:  variant.Weight,
+			Users:   users,
+		})
+	}
+
+	for i := range ratio.Variants {
+		expected := float64(total) * weights[i] / totalWeight
+		if expected < minExpectedExposures {
+			return nil
+		}
+		ratio.Variants[i].Expected = expected
+	}
+
+	test, ok := stats.ChiSquareTest(observed, weights)
+	if !ok {
+		return nil
+	}
+
+	ratio.Test = test
+	ratio.Mismatch = test.PValue < SampleRatioThreshold
+
+	return ratio
+}
+
+// CheckSampleRatio tests the exposures of a feature against its variant
+// weights and flags or unflags the feature accordingly. Without enough
+// exposures it returns nil and leaves the flag as it is.
+func (s *Service) CheckSampleRatio(ctx context.Context, feature *Feature) (*SampleRatio, error) {
+	exposures, err := s.eventRepo.CountExposures(ctx, feature.ID)
+	if err != nil {
+		return nil, fmt.Errorf("count exposures: %w", err)
+	}
+
+	ratio := checkSampleRatio(feature, exposures)
+	if ratio == nil {
+		return nil, nil
+	}
+
+	changed, err := s.featureRepo.SetSampleRatioMismatch(ctx, feature.ID, ratio.Mismatch)
+	if err != nil {
+		return nil, fmt.Errorf("flag sample ratio mismatch: %w", err)
+	}
+
+	if changed {
+		s.featureCache.Delete(featureCacheKey(feature.ID))
+	}
+
+	return ratio, nil
+}
+
+// sampleRatioLockKey identifies the lock held by the replica checking sample
+// ratios; it is "splitsrm" in ASCII.
+const sampleRatioLockKey int64 = 0x73706c697473726d
+
+type SampleRatioMonitorOptions struct {
+	Interval time.Duration
+	// Timeout bounds the check of a single feature.
+	Timeout time.Duration
+}
+
+// SampleRatioMonitor periodically checks the sample ratio of all active
+// features, flagging them and logging a warning on a mismatch. Only one
+// replica checks at a time, holding a lock handed out by its Locker.
+type SampleRatioMonitor struct {
+	*periodic
+
+	svc    *Service
+	locker Locker
+	opts   SampleRatioMonitorOptions
+	logger *slog.Logger
+}
+
+func NewSampleRatioMonitor(svc *Service, locker Locker, opts SampleRatioMonitorOptions, logger *slog.Logger) *SampleRatioMonitor {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+
+	m := &SampleRatioMonitor{
+		svc:    svc,
+		locker: locker,
+		opts:   opts,
+		logger: logger,
+	}
+	m.periodic = startPeriodic("sample ratio monitor", opts.Interval, m.check)
+
+	return m
+}
+
+func (m *SampleRatioMonitor) check(stop <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	unlock, locked, err := m.locker.TryLock(ctx, sampleRatioLockKey)
+	cancel()
+	if err != nil {
+		m.logger.Error("sample ratio check failed to take lock", slog.Any("error", err))
+		return
+	}
+	if !locked {
+		// another replica is checking
+		return
+	}
+	defer unlock()
+
+	active := true
+	page, err := m.svc.ListFeatures(context.Background(), FeatureFilter{Active: &active})
+	if err != nil {
+		m.logger.Error("sample ratio check failed to list features", slog.Any("error", err))
+		return
+	}
+
+	for _, feature := range page.Features {
+		if stopped(stop) {
+			return
+		}
+
+		m.checkFeature(feature)
+	}
+}
+
+func (m *SampleRatioMonitor) checkFeature(feature *Feature) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.opts.Timeout)
+	defer cancel()
+
+	ratio, err := m.svc.CheckSampleRatio(ctx, feature)
+	if err != nil {
+		m.logger.Error("sample ratio check failed", slog.Int("feature_id", int(feature.ID)), slog.Any("error", err))
+		return
+	}
+
+	if ratio == nil {
+		return
+	}
+
+	attrs := []any{
+		slog.Int("feature_id", int(feature.ID)),
+		slog.String("feature", feature.Name),
+		slog.Float64("p_value", ratio.Test.PValue),
+	}
+	for _, variant := range ratio.Variants {
+		attrs = append(attrs, slog.Group(variant.Variant,
+			slog.Int64("users", variant.Users),
+			slog.Float64("expected", variant.Expected),
+		))
+	}
+
+	switch {
+	case ratio.Mismatch:
+		m.logger.Warn("sample ratio mismatch", attrs...)
+	case feature.SampleRatioMismatch:
+		m.logger.Info("sample ratio mismatch resolved", attrs...)
+	}
+}
@@ -0,0 +1,321 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+var (
+	ErrScheduleNotFound           = errors.New("pending schedule not found")
+	ErrInvalidScheduleAction      = errors.New("invalid schedule action")
+	ErrInvalidScheduleTime        = errors.New("schedule time must be an RFC 3339 timestamp or a local date and time")
+	ErrScheduleInPast             = errors.New("schedule time must be in the future")
+	ErrInvalidTimezone            = errors.New("invalid timezone")
+	ErrScheduleVariantsRequired   = errors.New("variant schedules require variants")
+	ErrScheduleVariantsNotAllowed = errors.New("only variant schedules take variants")
+)
+
+// scheduleLockKey identifies the lock held by the replica applying due
+// schedules; it is "splitter" in ASCII.
+const scheduleLockKey int64 = 0x73706c6974746572
+
+// scheduleActor is the actor of audit entries written by the Scheduler.
+const scheduleActor = "scheduler"
+
+type ScheduleAction string
+
+const (
+	ScheduleActivate      ScheduleAction = "activate"
+	ScheduleDeactivate    ScheduleAction = "deactivate"
+	ScheduleApplyVariants ScheduleAction = "apply_variants"
+)
+
+func (a ScheduleAction) Valid() bool {
+	return a == ScheduleActivate || a == ScheduleDeactivate || a == ScheduleApplyVariants
+}
+
+type ScheduleStatus string
+
+const (
+	SchedulePending ScheduleStatus = "pending"
+	ScheduleApplied ScheduleStatus = "applied"
+	ScheduleFailed  ScheduleStatus = "failed"
+)
+
+// Schedule is a change of a feature applied by the Scheduler once RunAt has
+// passed: activating it, deactivating it or replacing its variants with
+// Variants. Timezone is the zone RunAt was given in.
+type Schedule struct {
+	ID         int64
+	FeatureID  int32
+	Action     ScheduleAction
+	Variants   Variants
+	RunAt      time.Time
+	Timezone   string
+	Status     ScheduleStatus
+	Error      string
+	CreatedAt  time.Time
+	ExecutedAt time.Time
+}
+
+func (s *Schedule) Validate() error {
+	var errs []error
+	if !s.Action.Valid() {
+		errs = append(errs, ErrInvalidScheduleAction)
+	}
+	if s.RunAt.IsZero() {
+		errs = append(errs, ErrInvalidScheduleTime)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		errs = append(errs, ErrInvalidTimezone)
+	}
+
+	switch {
+	case s.Action == ScheduleApplyVariants && len(s.Variants) == 0:
+		errs = append(errs, ErrScheduleVariantsRequired)
+	case s.Action != ScheduleApplyVariants && len(s.Variants) > 0:
+		errs = append(errs, ErrScheduleVariantsNotAllowed)
+	case len(s.Variants) > 0:
+		if _, err := NewVariants(s.Variants...); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// LocalRunAt returns RunAt in the schedule's timezone.
+func (s *Schedule) LocalRunAt() time.Time {
+	location, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return s.RunAt
+	}
+
+	return s.RunAt.In(location)
+}
+
+// ParseScheduleTime reads an RFC 3339 timestamp, or a date and time without
+// offset such as 2006-01-02T09:00 in timezone. An empty timezone is UTC.
+func ParseScheduleTime(value, timezone string) (time.Time, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, ErrInvalidTimezone
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidScheduleTime
+}
+
+// ListSchedules returns all schedules of a feature, including applied and
+// failed ones, by time.
+func (s *Service) ListSchedules(ctx context.Context, featureID int32) ([]*Schedule, error) {
+	if _, err := s.GetFeature(ctx, featureID); err != nil {
+		return nil, err
+	}
+
+	schedules, err := s.featureRepo.ListSchedules(ctx, featureID)
+	if err != nil {
+		return nil, fmt.Errorf("list schedules: %w", err)
+	}
+
+	return schedules, nil
+}
+
+func (s *Service) CreateSchedule(ctx context.Context, schedule *Schedule) error {
+	if err := schedule.Validate(); err != nil {
+		return fmt.Errorf("validate schedule: %w", err)
+	}
+
+	if !schedule.RunAt.After(time.Now()) {
+		return ErrScheduleInPast
+	}
+
+	if _, err := s.GetFeature(ctx, schedule.FeatureID); err != nil {
+		return err
+	}
+
+	if err := s.featureRepo.CreateSchedule(ctx, schedule); err != nil {
+		return fmt.Errorf("create schedule: %w", err)
+	}
+
+	return nil
+}
+
+// CancelSchedule removes a schedule that has not been applied yet.
+func (s *Service) CancelSchedule(ctx context.Context, featureID int32, scheduleID int64) error {
+	if err := s.featureRepo.CancelSchedule(ctx, featureID, scheduleID); err != nil {
+		return fmt.Errorf("cancel schedule: %w", err)
+	}
+
+	return nil
+}
+
+// RunDueSchedules applies up to limit due schedules, oldest first, and
+// returns them with their outcome. A schedule that cannot be applied is
+// marked failed and not retried. Every outcome is recorded in the audit log.
+func (s *Service) RunDueSchedules(ctx context.Context, limit int) ([]*Schedule, error) {
+	schedules, err := s.featureRepo.ListDueSchedules(ctx, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list due schedules: %w", err)
+	}
+
+	for i, schedule := range schedules {
+		action := AuditScheduleApplied
+		details := map[string]any{"schedule_id": schedule.ID, "action": schedule.Action}
+
+		schedule.Status = ScheduleApplied
+		schedule.Error = ""
+		if err := s.applySchedule(ctx, schedule); err != nil {
+			schedule.Status = ScheduleFailed
+			schedule.Error = err.Error()
+			action = AuditScheduleFailed
+			details["error"] = schedule.Error
+		}
+
+		if err := s.featureRepo.CompleteSchedule(ctx, schedule); err != nil {
+			return schedules[:i], fmt.Errorf("complete schedule: %w", err)
+		}
+
+		entry := &AuditEntry{FeatureID: schedule.FeatureID, Actor: scheduleActor, Action: action, Details: details}
+		if err := s.auditRepo.Record(ctx, entry); err != nil {
+			return schedules[:i+1], fmt.Errorf("record audit entry: %w", err)
+		}
+	}
+
+	return schedules, nil
+}
+
+func (s *Service) applySchedule(ctx context.Context, schedule *Schedule) error {
+	feature, err := s.featureRepo.GetByID(ctx, schedule.FeatureID)
+	if err != nil {
+		return fmt.Errorf("get feature: %w", err)
+	}
+
+	updated := *feature
+	switch schedule.Action {
+	case ScheduleActivate:
+		updated.Active = true
+	case ScheduleDeactivate:
+		updated.Active = false
+	case ScheduleApplyVariants:
+		updated.Variants = slices.Clone(schedule.Variants)
+		// schedules meant to shift weights keep the values of the variants
+		for i := range updated.Variants {
+			variant := &updated.Variants[i]
+			if current, found := variantByName(feature.Variants, variant.Name); found && variant.Value == nil {
+				variant.Value = current.Value
+			}
+		}
+	default:
+		return ErrInvalidScheduleAction
+	}
+
+	return s.UpdateFeature(ctx, &updated)
+}
+
+// Locker hands out locks shared by all replicas of the service.
+type Locker interface {
+	// TryLock takes the lock of key unless it is held elsewhere, in which
+	// case ok is false. unlock releases a taken lock.
+	TryLock(ctx context.Context, key int64) (unlock func(), ok bool, err error)
+}
+
+type SchedulerOptions struct {
+	Interval time.Duration
+	// BatchSize is the number of due schedules applied at once.
+	BatchSize int
+	// Timeout bounds a single run over the due schedules.
+	Timeout time.Duration
+}
+
+// Scheduler periodically applies due schedules. Only one replica applies
+// them at a time, holding a lock handed out by its Locker.
+type Scheduler struct {
+	*periodic
+
+	svc    *Service
+	locker Locker
+	opts   SchedulerOptions
+	logger *slog.Logger
+}
+
+func NewScheduler(svc *Service, locker Locker, opts SchedulerOptions, logger *slog.Logger) *Scheduler {
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 50
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = time.Minute
+	}
+
+	s := &Scheduler{
+		svc:    svc,
+		locker: locker,
+		opts:   opts,
+		logger: logger,
+	}
+	s.periodic = startPeriodic("scheduler", opts.Interval, s.run)
+
+	return s
+}
+
+func (s *Scheduler) run(stop <-chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.Timeout)
+	defer cancel()
+
+	unlock, locked, err := s.locker.TryLock(ctx, scheduleLockKey)
+	if err != nil {
+		s.logger.Error("scheduler failed to take lock", slog.Any("error", err))
+		return
+	}
+	if !locked {
+		// another replica is applying the schedules
+		return
+	}
+	defer unlock()
+
+	for !stopped(stop) {
+		schedules, err := s.svc.RunDueSchedules(ctx, s.opts.BatchSize)
+		for _, schedule := range schedules {
+			s.logSchedule(schedule)
+		}
+		if err != nil {
+			s.logger.Error("applying schedules failed", slog.Any("error", err))
+			return
+		}
+
+		if len(schedules) < s.opts.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *Scheduler) logSchedule(schedule *Schedule) {
+	attrs := []any{
+		slog.Int("feature_id", int(schedule.FeatureID)),
+		slog.Int64("schedule_id", schedule.ID),
+		slog.String("action", string(schedule.Action)),
+	}
+
+	if schedule.Status == ScheduleFailed {
+		s.logger.Warn("schedule failed", append(attrs, slog.String("error", schedule.Error))...)
+		return
+	}
+
+	s.logger.Info("schedule applied", attrs...)
+}
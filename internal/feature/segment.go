@@ -0,0 +1,245 @@
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSegmentNotFound             = errors.New("segment not found")
+	ErrSegmentAlreadyExists        = errors.New("segment already exists")
+	ErrSegmentNameRequired         = errors.New("segment name is required")
+	ErrSegmentInUse                = errors.New("segment is targeted by features")
+	ErrEmptyUserKey                = errors.New("segment user keys must not be empty")
+	ErrConditionAttributeRequired  = errors.New("condition attribute is required")
+	ErrInvalidConditionOperator    = errors.New("invalid condition operator")
+	ErrConditionValuesRequired     = errors.New("condition values are required")
+	ErrSegmentConditionsOrUserKeys = errors.New("segments require user keys or conditions")
+)
+
+// ConditionOperator compares an attribute of a user with the values of a
+// Condition.
+type ConditionOperator string
+
+const (
+	// OperatorIn matches attributes equal to one of the values.
+	OperatorIn ConditionOperator = "in"
+	// OperatorNotIn matches attributes that are set and equal to none of
+	// the values.
+	OperatorNotIn ConditionOperator = "not_in"
+	// OperatorStartsWith matches attributes starting with one of the values.
+	OperatorStartsWith ConditionOperator = "starts_with"
+	// OperatorEndsWith matches attributes ending with one of the values,
+	// such as the domain of an email address.
+	OperatorEndsWith ConditionOperator = "ends_with"
+)
+
+func (o ConditionOperator) Valid() bool {
+	switch o {
+	case OperatorIn, OperatorNotIn, OperatorStartsWith, OperatorEndsWith:
+		return true
+	default:
+		return false
+	}
+}
+
+// Condition is a test on one attribute of a user. Users without the
+// attribute never match.
+type Condition struct {
+	Attribute string            `json:"attribute"`
+	Operator  ConditionOperator `json:"operator"`
+	Values    []string          `json:"values"`
+}
+
+func (c *Condition) matches(attributes map[string]string) bool {
+	value, found := attributes[c.Attribute]
+	if !found {
+		return false
+	}
+
+	switch c.Operator {
+	case OperatorIn:
+		return slices.Contains(c.Values, value)
+	case OperatorNotIn:
+		return !slices.Contains(c.Values, value)
+	case OperatorStartsWith:
+		return slices.ContainsFunc(c.Values, func(prefix string) bool { return strings.HasPrefix(value, prefix) })
+	case OperatorEndsWith:
+		return slices.ContainsFunc(c.Values, func(suffix string) bool { return strings.HasSuffix(value, suffix) })
+	default:
+		return false
+	}
+}
+
+// Segment is a reusable set of users, targeted by the rules of any number of
+// features. It contains the users listed in UserKeys and the users meeting
+// all Conditions.
+type Segment struct {
+	ID          int32
+	Name        string
+	Description string
+	UserKeys    []string
+	Conditions  []Condition
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (s *Segment) Validate() error {
+	var errs []error
+	if s.Name == "" {
+		errs = append(errs, ErrSegmentNameRequired)
+	}
+	if len(s.UserKeys) == 0 && len(s.Conditions) == 0 {
+		errs = append(errs, ErrSegmentConditionsOrUserKeys)
+	}
+	if slices.Contains(s.UserKeys, "") {
+		errs = append(errs, ErrEmptyUserKey)
+	}
+
+	for _, condition := range s.Conditions {
+		if condition.Attribute == "" {
+			errs = append(errs, ErrConditionAttributeRequired)
+		}
+		if !condition.Operator.Valid() {
+			errs = append(errs, ErrInvalidConditionOperator)
+		}
+		if len(condition.Values) == 0 {
+			errs = append(errs, ErrConditionValuesRequired)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Contains reports whether the user is listed in the segment or meets all
+// of its conditions.
+func (s *Segment) Contains(user EvaluationContext) bool {
+	contained, _ := s.explain(user)
+	return contained
+}
+
+// explain is Contains, along with why the user is or is not in the segment.
+func (s *Segment) explain(user EvaluationContext) (bool, string) {
+	if slices.Contains(s.UserKeys, user.Key) {
+		return true, fmt.Sprintf("user %q is listed in segment %q", user.Key, s.Name)
+	}
+
+	if len(s.Conditions) == 0 {
+		return false, fmt.Sprintf("user %q is not listed in segment %q", user.Key, s.Name)
+	}
+
+	for _, condition := range s.Conditions {
+		if !condition.matches(user.Attributes) {
+			return false, fmt.Sprintf("user %q does not meet condition %s %s %v of segment %q",
+				user.Key, condition.Attribute, condition.Operator, condition.Values, s.Name)
+		}
+	}
+
+	return true, fmt.Sprintf("user %q meets all conditions of segment %q", user.Key, s.Name)
+}
+
+// SegmentUsage names a feature targeting a segment.
+type SegmentUsage struct {
+	FeatureID int32
+	Feature   string
+}
+
+type SegmentRepository interface {
+	GetByID(ctx context.Context, id int32) (*Segment, error)
+	List(ctx context.Context) ([]*Segment, error)
+	Create(ctx context.Context, segment *Segment) error
+	// Update stores the segment and increments the version of the features
+	// targeting it, whose ids it returns.
+	Update(ctx context.Context, segment *Segment) ([]int32, error)
+	Delete(ctx context.Context, id int32) error
+	ListUsage(ctx context.Context, id int32) ([]SegmentUsage, error)
+}
+
+func (s *Service) GetSegment(ctx context.Context, id int32) (*Segment, error) {
+	segment, err := s.segmentRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get segment: %w", err)
+	}
+
+	return segment, nil
+}
+
+func (s *Service) ListSegments(ctx context.Context) ([]*Segment, error) {
+	segments, err := s.segmentRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list segments: %w", err)
+	}
+
+	return segments, nil
+}
+
+func (s *Service) CreateSegment(ctx context.Context, segment *Segment) error {
+	if err := segment.Validate(); err != nil {
+		return fmt.Errorf("validate segment: %w", err)
+	}
+
+	if err := s.segmentRepo.Create(ctx, segment); err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSegment stores a segment and records an update of every feature
+// targeting it, so that they are evaluated with the new segment everywhere.
+func (s *Service) UpdateSegment(ctx context.Context, segment *Segment) error {
+	if err := segment.Validate(); err != nil {
+		return fmt.Errorf("validate segment: %w", err)
+	}
+
+	featureIDs, err := s.segmentRepo.Update(ctx, segment)
+	if err != nil {
+		return fmt.Errorf("update segment: %w", err)
+	}
+
+	for _, id := range featureIDs {
+		s.featureCache.Delete(featureCacheKey(id))
+	}
+
+	return nil
+}
+
+// DeleteSegment deletes a segment no feature targets.
+func (s *Service) DeleteSegment(ctx context.Context, id int32) error {
+	usage, err := s.ListSegmentUsage(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(usage) > 0 {
+		names := make([]string, len(usage))
+		for i, u := range usage {
+			names[i] = u.Feature
+		}
+		return fmt.Errorf("%w: %s", ErrSegmentInUse, strings.Join(names, ", "))
+	}
+
+	if err := s.segmentRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete segment: %w", err)
+	}
+
+	return nil
+}
+
+// ListSegmentUsage returns the features targeting a segment by name.
+func (s *Service) ListSegmentUsage(ctx context.Context, id int32) ([]SegmentUsage, error) {
+	if _, err := s.GetSegment(ctx, id); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.segmentRepo.ListUsage(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list segment usage: %w", err)
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,352 @@
+package feature
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/eve-an/splitter/internal/spool"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type SpoolOptions struct {
+	ReplayInterval  time.Duration
+	ReplayBatchSize int
+	ReplayTimeout   time.Duration
+}
+
+type SpoolStats struct {
+	Depth    int    `json:"depth"`
+	Bytes    int64  `json:"bytes"`
+	Spooled  uint64 `json:"spooled_total"`
+	Replayed uint64 `json:"replayed_total"`
+	Dropped  uint64 `json:"dropped_total"`
+}
+
+// spooledEventRepository keeps events that cannot be written to the wrapped
+// repository in a local write-ahead spool and replays them in order once the
+// database is reachable again. While the spool is not empty, new events are
+// appended to it as well, so they never overtake spooled ones.
+//
+// Only failures to reach the database are spooled. Errors reported by the
+// database itself, such as a constraint violation, are returned as-is since
+// retrying would not help.
+type spooledEventRepository struct {
+	next   EventRepository
+	spool  *spool.Spool
+	opts   SpoolOptions
+	logger *slog.Logger
+
+	spooled  atomic.Uint64
+	replayed atomic.Uint64
+	dropped  atomic.Uint64
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+var _ EventRepository = (*spooledEventRepository)(nil)
+
+func NewSpooledEventRepository(
+	next EventRepository,
+	spool *spool.Spool,
+	opts SpoolOptions,
+	logger *slog.Logger,
+) *spooledEventRepository {
+	if opts.ReplayInterval <= 0 {
+		opts.ReplayInterval = 5 * time.Second
+	}
+	if opts.ReplayBatchSize <= 0 {
+		opts.ReplayBatchSize = 500
+	}
+	if opts.ReplayTimeout <= 0 {
+		opts.ReplayTimeout = 10 * time.Second
+	}
+
+	s := &spooledEventRepository{
+		next:   next,
+		spool:  spool,
+		opts:   opts,
+		logger: logger,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if depth := spool.Depth(); depth > 0 {
+		logger.Info("found spooled events", slog.Int("depth", depth))
+	}
+
+	go s.run()
+
+	return s
+}
+
+// Create implements EventRepository. A spooled event is accepted without an
+// ID; if its client id turns out to be recorded already, it is skipped on
+// replay.
+func (s *spooledEventRepository) Create(ctx context.Context, event *Event) error {
+	if s.spool.Depth() == 0 {
+		err := s.next.Create(ctx, event)
+		if !isUnavailable(err) {
+			return err
+		}
+
+		s.logger.Warn("event insert failed, spooling", slog.Any("error", err))
+	}
+
+	return s.append(event)
+}
+
+// CreateBatch implements EventRepository.
+func (s *spooledEventRepository) CreateBatch(ctx context.Context, events []*Event) ([]*Event, error) {
+	if s.spool.Depth() == 0 {
+		duplicates, err := s.next.CreateBatch(ctx, events)
+		if !isUnavailable(err) {
+			return duplicates, err
+		}
+
+		s.logger.Warn("event batch insert failed, spooling", slog.Int("events", len(events)), slog.Any("error", err))
+	}
+
+	return nil, s.append(events...)
+}
+
+// GetByClientID implements EventRepository. Spooled events are not found
+// until they have been replayed.
+func (s *spooledEventRepository) GetByClientID(ctx context.Context, featureID int32, clientID string) (*Event, error) {
+	return s.next.GetByClientID(ctx, featureID, clientID)
+}
+
+// List implements EventRepository. Spooled events are not listed until they
+// have been replayed.
+func (s *spooledEventRepository) List(ctx context.Context, filter EventFilter) ([]*Event, error) {
+	return s.next.List(ctx, filter)
+}
+
+// Aggregate implements EventRepository.
+func (s *spooledEventRepository) Aggregate(ctx context.Context, featureID int32, metrics []*Metric) (*EventAggregate, error) {
+	return s.next.Aggregate(ctx, featureID, metrics)
+}
+
+// AggregateCovariates implements EventRepository.
+func (s *spooledEventRepository) AggregateCovariates(
+	ctx context.Context,
+	featureID int32,
+	metrics []*Metric,
+	lookback time.Duration,
+) ([]CovariateAggregate, error) {
+	return s.next.AggregateCovariates(ctx, featureID, metrics, lookback)
+}
+
+// CountExposures implements EventRepository.
+func (s *spooledEventRepository) CountExposures(ctx context.Context, featureID int32) ([]ExposureAggregate, error) {
+	return s.next.CountExposures(ctx, featureID)
+}
+
+func (s *spooledEventRepository) Stats() SpoolStats {
+	return SpoolStats{
+		Depth:    s.spool.Depth(),
+		Bytes:    s.spool.Size(),
+		Spooled:  s.spooled.Load(),
+		Replayed: s.replayed.Load(),
+		Dropped:  s.dropped.Load(),
+	}
+}
+
+// Close stops the replay loop and closes the spool. Events still spooled
+// are replayed after the next start.
+func (s *spooledEventRepository) Close(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	select {
+	case <-s.done:
+	case <-ctx.Done():
+		return fmt.Errorf("stopping spool replay: %w", ctx.Err())
+	}
+
+	return s.spool.Close()
+}
+
+func (s *spooledEventRepository) append(events ...*Event) error {
+	now := time.Now()
+	records := make([][]byte, len(events))
+	for i, event := range events {
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = now
+		}
+
+		record, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("encoding spooled event: %w", err)
+		}
+		records[i] = record
+	}
+
+	if err := s.spool.Append(records...); err != nil {
+		return fmt.Errorf("spooling events: %w", err)
+	}
+
+	s.spooled.Add(uint64(len(events)))
+
+	return nil
+}
+
+func (s *spooledEventRepository) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.replay()
+		}
+	}
+}
+
+// replay drains the spool batch by batch until it is empty, the database is
+// unavailable again or the repository is closed. Corrupt records are
+// dropped, so that they cannot hold up the spool.
+func (s *spooledEventRepository) replay() {
+	for s.spool.Depth() > 0 {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		batch, err := s.spool.Peek(s.opts.ReplayBatchSize)
+		if errors.Is(err, spool.ErrCorrupt) {
+			s.skipCorrupt(err)
+			continue
+		}
+		if err != nil {
+			s.logger.Error("failed to read spool", slog.Any("error", err))
+			return
+		}
+
+		// records holds the position in the batch of every decoded event
+		events := make([]*Event, 0, len(batch.Records))
+		records := make([]int, 0, len(batch.Records))
+		for i, record := range batch.Records {
+			var event Event
+			if err := json.Unmarshal(record, &event); err != nil {
+				s.logger.Error("dropping undecodable spooled event", slog.Any("error", err))
+				s.dropped.Add(1)
+				continue
+			}
+			events = append(events, &event)
+			records = append(records, i)
+		}
+
+		written := s.replayBatch(events)
+		if written < len(events) {
+			// keep the events not yet written, and only those
+			batch = batch.Prefix(records[written])
+		}
+
+		if err := s.spool.Commit(batch); err != nil {
+			s.logger.Error("failed to commit spool", slog.Any("error", err))
+			return
+		}
+
+		if written > 0 {
+			s.replayed.Add(uint64(written))
+			s.logger.Info("replayed spooled events", slog.Int("events", written), slog.Int("depth", s.spool.Depth()))
+		}
+
+		if written < len(events) {
+			return
+		}
+	}
+}
+
+func (s *spooledEventRepository) skipCorrupt(cause error) {
+	skipped, err := s.spool.Skip()
+	if err != nil {
+		s.logger.Error("failed to skip corrupt spooled events", slog.Any("error", err))
+		return
+	}
+
+	s.logger.Error("dropping corrupt spooled events", slog.Int("events", skipped), slog.Any("error", cause))
+	s.dropped.Add(uint64(skipped))
+}
+
+// replayBatch returns the number of events dealt with before the database
+// became unavailable. If the batch is rejected by the database, events are
+// written one by one so that only the offending ones are dropped.
+func (s *spooledEventRepository) replayBatch(events []*Event) int {
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.ReplayTimeout)
+	defer cancel()
+
+	_, err := s.next.CreateBatch(ctx, events)
+	if err == nil {
+		return len(events)
+	}
+
+	if isUnavailable(err) {
+		s.logger.Debug("database still unavailable, keeping spool", slog.Any("error", err))
+		return 0
+	}
+
+	for i, event := range events {
+		err := s.next.Create(ctx, event)
+		if isUnavailable(err) {
+			return i
+		}
+
+		if err != nil && !errors.Is(err, ErrEventAlreadyRecorded) {
+			s.logger.Error("dropping spooled event rejected by database",
+				slog.Int("feature_id", int(event.FeatureID)),
+				slog.Any("error", err),
+			)
+			s.dropped.Add(1)
+		}
+	}
+
+	return len(events)
+}
+
+// isUnavailable reports whether err means the database could not be reached,
+// as opposed to the database rejecting the write. Only failures to connect,
+// broken connections, timeouts and the server refusing connections count;
+// domain errors such as ErrEventAlreadyRecorded, encoding errors and
+// errors reported by the database for the data itself do not.
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.Timeout(err) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// connection exceptions, too many connections and server shutdown
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			slices.Contains([]string{"53300", "57P01", "57P02", "57P03"}, pgErr.Code)
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -1,5 +1,10 @@
 package feature
 
+import (
+	"hash/fnv"
+	"strconv"
+)
+
 type UserID uint64
 
 type User struct {
@@ -26,3 +31,16 @@ func (u User) ID() UserID {
 
 	return u.id
 }
+
+// UserFromString maps the user id sent along with events to a User. Numeric
+// ids are used as they are, any other id is hashed.
+func UserFromString(id string) User {
+	if n, err := strconv.ParseUint(id, 10, 64); err == nil && n != 0 {
+		return NewUser(UserID(n))
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(id)) // nolint: errcheck
+
+	return NewUser(UserID(h.Sum64() | 1)) // never zero
+}
@@ -0,0 +1,104 @@
+package feature
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/eve-an/splitter/internal/jsonschema"
+)
+
+var (
+	ErrInvalidValueType           = errors.New("invalid value type")
+	ErrVariantValueRequired       = errors.New("variants of typed features require a value")
+	ErrVariantValueNotAllowed     = errors.New("variants of untyped features take no value")
+	ErrVariantValueTypeInvalid    = errors.New("variant value does not match the value type of the feature")
+	ErrValueSchemaNotAllowed      = errors.New("only json features take a value schema")
+	ErrInvalidValueSchema         = errors.New("invalid value schema")
+	ErrVariantValueSchemaMismatch = errors.New("variant value does not match the value schema of the feature")
+)
+
+// ValueType is the type of the values the variants of a feature carry, so
+// clients read what a variant means instead of hardcoding it per name.
+type ValueType string
+
+const (
+	// ValueTypeNone is for features whose variants carry no value.
+	ValueTypeNone    ValueType = ""
+	ValueTypeBoolean ValueType = "boolean"
+	ValueTypeString  ValueType = "string"
+	ValueTypeNumber  ValueType = "number"
+	// ValueTypeJSON takes any JSON document but null that matches the value
+	// schema of the feature, if it has one.
+	ValueTypeJSON ValueType = "json"
+)
+
+func (t ValueType) Valid() bool {
+	switch t {
+	case ValueTypeNone, ValueTypeBoolean, ValueTypeString, ValueTypeNumber, ValueTypeJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// accepts reports whether value is a JSON encoded value of the type.
+func (t ValueType) accepts(value json.RawMessage) bool {
+	var decoded any
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		return false
+	}
+
+	switch t {
+	case ValueTypeBoolean:
+		_, ok := decoded.(bool)
+		return ok
+	case ValueTypeString:
+		_, ok := decoded.(string)
+		return ok
+	case ValueTypeNumber:
+		_, ok := decoded.(float64)
+		return ok
+	case ValueTypeJSON:
+		return decoded != nil
+	default:
+		return false
+	}
+}
+
+func validateValues(valueType ValueType, valueSchema json.RawMessage, variants Variants) error {
+	if !valueType.Valid() {
+		return ErrInvalidValueType
+	}
+
+	var schema *jsonschema.Schema
+	switch {
+	case valueSchema == nil:
+	case valueType != ValueTypeJSON:
+		return ErrValueSchemaNotAllowed
+	default:
+		var err error
+		if schema, err = jsonschema.Parse(valueSchema); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidValueSchema, err)
+		}
+	}
+
+	var errs []error
+	for _, variant := range variants {
+		switch {
+		case valueType == ValueTypeNone && variant.Value != nil:
+			errs = append(errs, ErrVariantValueNotAllowed)
+		case valueType == ValueTypeNone:
+		case variant.Value == nil:
+			errs = append(errs, ErrVariantValueRequired)
+		case !valueType.accepts(variant.Value):
+			errs = append(errs, ErrVariantValueTypeInvalid)
+		case schema != nil:
+			if err := schema.Validate(variant.Value); err != nil {
+				errs = append(errs, fmt.Errorf("%w: variant %s: %w", ErrVariantValueSchemaMismatch, variant.Name, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -1,6 +1,7 @@
 package feature
 
 import (
+	"encoding/json"
 	"errors"
 )
 
@@ -8,6 +9,9 @@ type Variant struct {
 	ID     int32
 	Name   string
 	Weight uint8
+	// Value is the JSON encoded value of the variant, of the value type of
+	// its feature. It is nil for features without a value type.
+	Value json.RawMessage
 }
 
 type Variants []Variant
@@ -30,6 +34,16 @@ func (v Variants) Names() []string {
 	return names
 }
 
+func variantByName(variants Variants, name string) (*Variant, bool) {
+	for _, variant := range variants {
+		if variant.Name == name {
+			return &variant, true
+		}
+	}
+
+	return nil, false
+}
+
 func NewVariant(name string, weight uint8) (Variant, error) {
 	if name == "" {
 		return Variant{}, errors.New("name is required")
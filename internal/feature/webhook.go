@@ -0,0 +1,496 @@
+package feature
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrWebhookNotFound     = errors.New("webhook not found")
+	ErrInvalidWebhookURL   = errors.New("webhook url must be an absolute http or https url")
+	ErrInvalidWebhookEvent = errors.New("invalid webhook event")
+)
+
+const (
+	// maxWebhookAttempts bounds the deliveries of an event to a webhook,
+	// after which the delivery is given up.
+	maxWebhookAttempts = 8
+	// webhookBackoff is the pause after the first failed attempt, doubling
+	// with every further attempt up to maxWebhookBackoff.
+	webhookBackoff    = 30 * time.Second
+	maxWebhookBackoff = time.Hour
+	// webhookTimeout bounds a single delivery attempt.
+	webhookTimeout = 10 * time.Second
+)
+
+// Headers of webhook deliveries. The signature is the hex encoded HMAC-SHA256
+// of the timestamp, a dot and the body, keyed with the webhook secret and
+// prefixed with "sha256=".
+const (
+	WebhookEventHeader     = "X-Splitter-Event"
+	WebhookDeliveryHeader  = "X-Splitter-Delivery"
+	WebhookTimestampHeader = "X-Splitter-Timestamp"
+	WebhookSignatureHeader = "X-Splitter-Signature"
+)
+
+type WebhookEvent string
+
+const (
+	WebhookFeatureCreated    WebhookEvent = "feature.created"
+	WebhookFeatureUpdated    WebhookEvent = "feature.updated"
+	WebhookFeatureToggled    WebhookEvent = "feature.toggled"
+	WebhookFeatureDeleted    WebhookEvent = "feature.deleted"
+	WebhookGuardrailBreached WebhookEvent = WebhookEvent(NotificationGuardrailBreached)
+	// WebhookPing is only sent by test deliveries and cannot be subscribed
+	// to.
+	WebhookPing WebhookEvent = "ping"
+)
+
+// Valid reports whether webhooks can subscribe to e.
+func (e WebhookEvent) Valid() bool {
+	switch e {
+	case WebhookFeatureCreated, WebhookFeatureUpdated, WebhookFeatureToggled, WebhookFeatureDeleted, WebhookGuardrailBreached:
+		return true
+	default:
+		return false
+	}
+}
+
+func webhookEventFor(change ChangeType) WebhookEvent {
+	return WebhookEvent("feature." + string(change))
+}
+
+// Webhook receives signed deliveries of the Events it subscribes to, or of
+// all events if there are none. Inactive webhooks receive no new deliveries.
+type Webhook struct {
+	ID        int32
+	URL       string
+	Secret    string
+	Events    []WebhookEvent
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (w *Webhook) Validate() error {
+	var errs []error
+	if u, err := url.Parse(w.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, ErrInvalidWebhookURL)
+	}
+	for _, event := range w.Events {
+		if !event.Valid() {
+			errs = append(errs, fmt.Errorf("%w: %q", ErrInvalidWebhookEvent, event))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery is the delivery of one event to one webhook. Pending
+// deliveries are attempted at NextAttemptAt; ResponseStatus and Error
+// describe the last attempt. CompletedAt is zero while the delivery is
+// pending.
+type WebhookDelivery struct {
+	ID             int64
+	WebhookID      int32
+	Event          WebhookEvent
+	Payload        json.RawMessage
+	Status         WebhookDeliveryStatus
+	Attempts       int
+	ResponseStatus int
+	Error          string
+	NextAttemptAt  time.Time
+	CreatedAt      time.Time
+	CompletedAt    time.Time
+}
+
+// DueWebhookDelivery is a claimed delivery together with where to send it.
+type DueWebhookDelivery struct {
+	Delivery *WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+type WebhookRepository interface {
+	GetByID(ctx context.Context, id int32) (*Webhook, error)
+	List(ctx context.Context) ([]*Webhook, error)
+	Create(ctx context.Context, webhook *Webhook) error
+	Update(ctx context.Context, webhook *Webhook) error
+	Delete(ctx context.Context, id int32) error
+	// Enqueue queues a delivery of payload to every active webhook
+	// subscribed to event and returns the number of deliveries.
+	Enqueue(ctx context.Context, event WebhookEvent, payload []byte) (int64, error)
+	// CreateDelivery queues a delivery to the webhook of delivery.
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	// ClaimDeliveries returns up to limit due deliveries and postpones them
+	// to leaseUntil, so that they are not claimed again while being sent.
+	ClaimDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]DueWebhookDelivery, error)
+	// RecordAttempt stores the outcome of an attempt to send delivery.
+	RecordAttempt(ctx context.Context, delivery *WebhookDelivery) error
+	// ListDeliveries returns the newest deliveries to a webhook first. A zero
+	// limit returns all of them.
+	ListDeliveries(ctx context.Context, webhookID int32, limit int) ([]*WebhookDelivery, error)
+}
+
+// webhookPayload is the body of a webhook delivery.
+type webhookPayload struct {
+	Event      WebhookEvent    `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	FeatureID  int32           `json:"feature_id,omitempty"`
+	Feature    *webhookFeature `json:"feature,omitempty"`
+	Data       any             `json:"data,omitempty"`
+}
+
+type webhookFeature struct {
+	ID          int32            `json:"id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Active      bool             `json:"active"`
+	Kind        string           `json:"kind"`
+	ValueType   string           `json:"value_type,omitempty"`
+	ValueSchema json.RawMessage  `json:"value_schema,omitempty"`
+	Variants    []webhookVariant `json:"variants"`
+	Tags        []string         `json:"tags"`
+	Version     int32            `json:"version"`
+}
+
+type webhookVariant struct {
+	Name   string          `json:"name"`
+	Weight uint8           `json:"weight"`
+	Value  json.RawMessage `json:"value,omitempty"`
+}
+
+func newWebhookPayload(event WebhookEvent, at time.Time, feature *Feature, data any) webhookPayload {
+	payload := webhookPayload{Event: event, OccurredAt: at.UTC(), Data: data}
+	if feature == nil {
+		return payload
+	}
+
+	payload.FeatureID = feature.ID
+	payload.Feature = &webhookFeature{
+		ID:          feature.ID,
+		Name:        feature.Name,
+		Description: feature.Descritption,
+		Active:      feature.Active,
+		Kind:        string(feature.Kind),
+		ValueType:   string(feature.ValueType),
+		ValueSchema: feature.ValueSchema,
+		Variants:    make([]webhookVariant, len(feature.Variants)),
+		Tags:        feature.Tags,
+		Version:     feature.Version,
+	}
+	if payload.Feature.Tags == nil {
+		payload.Feature.Tags = []string{}
+	}
+	for i, variant := range feature.Variants {
+		payload.Feature.Variants[i] = webhookVariant{Name: variant.Name, Weight: variant.Weight, Value: variant.Value}
+	}
+
+	return payload
+}
+
+func enqueueWebhooks(ctx context.Context, repo WebhookRepository, payload webhookPayload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encoding webhook payload: %w", err)
+	}
+
+	if _, err := repo.Enqueue(ctx, payload.Event, body); err != nil {
+		return fmt.Errorf("enqueue webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+type webhookNotifier struct {
+	repo WebhookRepository
+}
+
+var _ Notifier = (*webhookNotifier)(nil)
+
+// NewWebhookNotifier returns a Notifier that delivers notifications to the
+// webhooks subscribed to their type.
+func NewWebhookNotifier(repo WebhookRepository) *webhookNotifier {
+	return &webhookNotifier{repo: repo}
+}
+
+// Notify implements Notifier.
+func (n *webhookNotifier) Notify(ctx context.Context, notification Notification) error {
+	payload := newWebhookPayload(WebhookEvent(notification.Type), notification.At, notification.Feature, notification.Data)
+	payload.FeatureID = notification.FeatureID
+
+	return enqueueWebhooks(ctx, n.repo, payload)
+}
+
+func (s *Service) GetWebhook(ctx context.Context, id int32) (*Webhook, error) {
+	webhook, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get webhook: %w", err)
+	}
+
+	return webhook, nil
+}
+
+func (s *Service) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	webhooks, err := s.webhookRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// CreateWebhook stores a new webhook, generating a secret if it has none.
+func (s *Service) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return fmt.Errorf("validate webhook: %w", err)
+	}
+
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		webhook.Secret = secret
+	}
+
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateWebhook changes a webhook, keeping its secret if webhook has none.
+func (s *Service) UpdateWebhook(ctx context.Context, webhook *Webhook) error {
+	if err := webhook.Validate(); err != nil {
+		return fmt.Errorf("validate webhook: %w", err)
+	}
+
+	if webhook.Secret == "" {
+		current, err := s.GetWebhook(ctx, webhook.ID)
+		if err != nil {
+			return err
+		}
+		webhook.Secret = current.Secret
+	}
+
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWebhook removes a webhook together with its deliveries.
+func (s *Service) DeleteWebhook(ctx context.Context, id int32) error {
+	if err := s.webhookRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+
+	return nil
+}
+
+// ListWebhookDeliveries returns the deliveries to a webhook, newest first. A
+// zero limit returns all of them.
+func (s *Service) ListWebhookDeliveries(ctx context.Context, webhookID int32, limit int) ([]*WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.webhookRepo.ListDeliveries(ctx, webhookID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// TestWebhook sends a ping to a webhook, whether or not it is active, and
+// returns the delivery. The ping is attempted once and not retried.
+func (s *Service) TestWebhook(ctx context.Context, id int32) (*WebhookDelivery, error) {
+	webhook, err := s.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(newWebhookPayload(WebhookPing, time.Now(), nil, nil))
+	if err != nil {
+		return nil, fmt.Errorf("encoding webhook payload: %w", err)
+	}
+
+	delivery := &WebhookDelivery{WebhookID: webhook.ID, Event: WebhookPing, Payload: payload}
+	if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("create webhook delivery: %w", err)
+	}
+
+	s.attemptWebhook(ctx, DueWebhookDelivery{Delivery: delivery, URL: webhook.URL, Secret: webhook.Secret}, false)
+	if err := s.webhookRepo.RecordAttempt(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("record webhook attempt: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// QueueChangeWebhooks queues the webhook deliveries of up to limit recorded
+// feature changes and returns how many changes it queued. Changes are only
+// marked as queued together with their deliveries, so none is lost when a
+// replica stops in between.
+func (s *Service) QueueChangeWebhooks(ctx context.Context, limit int) (int, error) {
+	queued, err := s.changeRepo.QueueWebhooks(ctx, limit, s.changeWebhookPayload)
+	if err != nil {
+		return 0, fmt.Errorf("queue webhook deliveries: %w", err)
+	}
+
+	return queued, nil
+}
+
+func (s *Service) changeWebhookPayload(ctx context.Context, change Change) (WebhookEvent, []byte, error) {
+	if err := s.loadChangedFeature(ctx, &change); err != nil {
+		return "", nil, err
+	}
+
+	payload := newWebhookPayload(webhookEventFor(change.Type), change.At, change.Feature, nil)
+	payload.FeatureID = change.FeatureID
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("encoding webhook payload: %w", err)
+	}
+
+	return payload.Event, body, nil
+}
+
+// DeliverWebhooks sends up to limit due webhook deliveries and returns how
+// many it attempted. Failed deliveries are retried with exponential backoff
+// until they run out of attempts.
+func (s *Service) DeliverWebhooks(ctx context.Context, limit int) (int, error) {
+	// deliveries are sent one after the other, so the lease covers all of
+	// them timing out
+	leaseUntil := time.Now().Add(time.Duration(limit)*webhookTimeout + time.Minute)
+	due, err := s.webhookRepo.ClaimDeliveries(ctx, limit, leaseUntil)
+	if err != nil {
+		return 0, fmt.Errorf("claim webhook deliveries: %w", err)
+	}
+
+	for i, d := range due {
+		s.attemptWebhook(ctx, d, true)
+		if err := s.webhookRepo.RecordAttempt(ctx, d.Delivery); err != nil {
+			return i, fmt.Errorf("record webhook attempt: %w", err)
+		}
+	}
+
+	return len(due), nil
+}
+
+// attemptWebhook sends a delivery and updates it with the outcome. Failed
+// deliveries stay pending for another attempt if retry is set and attempts
+// are left.
+func (s *Service) attemptWebhook(ctx context.Context, d DueWebhookDelivery, retry bool) {
+	delivery := d.Delivery
+	delivery.Attempts++
+
+	status, err := s.sendWebhook(ctx, d)
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDeliverySucceeded
+		delivery.CompletedAt = now
+	case retry && delivery.Attempts < maxWebhookAttempts:
+		delivery.Status = WebhookDeliveryPending
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(webhookRetryDelay(delivery.Attempts))
+	default:
+		delivery.Status = WebhookDeliveryFailed
+		delivery.Error = err.Error()
+		delivery.CompletedAt = now
+	}
+}
+
+// sendWebhook posts a delivery and returns the response status, if any. Any
+// status other than 2xx is an error.
+func (s *Service) sendWebhook(ctx context.Context, d DueWebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("building request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "splitter-webhooks")
+	req.Header.Set(WebhookEventHeader, string(d.Delivery.Event))
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatInt(d.Delivery.ID, 10))
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.Secret, timestamp, d.Delivery.Payload))
+
+	resp, err := s.webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close() // nolint: errcheck
+
+	// drain a little of the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the signature header value of a delivery body sent at
+// timestamp, for receivers to compare against.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay is the pause after the given number of failed attempts.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookBackoff
+	for i := 1; i < attempts && delay < maxWebhookBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxWebhookBackoff)
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("generating webhook secret: %w", err)
+	}
+
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,85 @@
+package feature
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+type WebhookDispatcherOptions struct {
+	Interval time.Duration
+	// BatchSize is the number of changes queued and deliveries claimed at
+	// once.
+	BatchSize int
+	// Timeout bounds queueing the deliveries of a batch of changes.
+	Timeout time.Duration
+}
+
+// WebhookDispatcher periodically queues the webhook deliveries of the
+// feature changes recorded by all instances and sends the due deliveries.
+// The recorded changes serve as outbox, so every change is queued exactly
+// once by whichever instance claims it first.
+type WebhookDispatcher struct {
+	*periodic
+
+	svc    *Service
+	opts   WebhookDispatcherOptions
+	logger *slog.Logger
+}
+
+func NewWebhookDispatcher(svc *Service, opts WebhookDispatcherOptions, logger *slog.Logger) *WebhookDispatcher {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 20
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	d := &WebhookDispatcher{
+		svc:    svc,
+		opts:   opts,
+		logger: logger,
+	}
+	d.periodic = startPeriodic("webhook dispatcher", opts.Interval, d.dispatch)
+
+	return d
+}
+
+func (d *WebhookDispatcher) dispatch(stop <-chan struct{}) {
+	d.queue(stop)
+	d.deliver(stop)
+}
+
+func (d *WebhookDispatcher) queue(stop <-chan struct{}) {
+	for !stopped(stop) {
+		ctx, cancel := context.WithTimeout(context.Background(), d.opts.Timeout)
+		queued, err := d.svc.QueueChangeWebhooks(ctx, d.opts.BatchSize)
+		cancel()
+
+		if err != nil {
+			d.logger.Error("queueing webhook deliveries failed", slog.Any("error", err))
+			return
+		}
+
+		if queued < d.opts.BatchSize {
+			return
+		}
+	}
+}
+
+func (d *WebhookDispatcher) deliver(stop <-chan struct{}) {
+	for !stopped(stop) {
+		sent, err := d.svc.DeliverWebhooks(context.Background(), d.opts.BatchSize)
+		if err != nil {
+			d.logger.Error("webhook delivery failed", slog.Any("error", err))
+			return
+		}
+
+		if sent < d.opts.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package feature
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookRepositoryStub keeps deliveries in memory. Only the methods used
+// to send deliveries are implemented.
+type webhookRepositoryStub struct {
+	WebhookRepository
+
+	webhook  *Webhook
+	due      []DueWebhookDelivery
+	attempts []WebhookDelivery
+}
+
+func (r *webhookRepositoryStub) GetByID(context.Context, int32) (*Webhook, error) {
+	return r.webhook, nil
+}
+
+func (r *webhookRepositoryStub) CreateDelivery(_ context.Context, delivery *WebhookDelivery) error {
+	delivery.ID = int64(len(r.attempts) + 1)
+	return nil
+}
+
+func (r *webhookRepositoryStub) ClaimDeliveries(_ context.Context, limit int, _ time.Time) ([]DueWebhookDelivery, error) {
+	due := r.due[:min(limit, len(r.due))]
+	r.due = r.due[len(due):]
+	return due, nil
+}
+
+func (r *webhookRepositoryStub) RecordAttempt(_ context.Context, delivery *WebhookDelivery) error {
+	r.attempts = append(r.attempts, *delivery)
+	return nil
+}
+
+// webhookReceiver is a local stand-in for a webhook endpoint answering with
+// the given statuses in turn.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	status := http.StatusNoContent
+	if n := len(rc.requests); n < len(rc.statuses) {
+		status = rc.statuses[n]
+	}
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+
+	w.WriteHeader(status)
+}
+
+func newWebhookTest(t *testing.T, statuses ...int) (*Service, *webhookRepositoryStub, *webhookReceiver) {
+	t.Helper()
+
+	receiver := &webhookReceiver{statuses: statuses}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	repo := &webhookRepositoryStub{
+		webhook: &Webhook{ID: 1, URL: server.URL, Secret: "secret", Active: true},
+	}
+	svc := NewService(nil, nil, nil, nil, repo, nil, nil, nil, nil, EventValidation{})
+
+	return svc, repo, receiver
+}
+
+func TestDeliverWebhooksSigns(t *testing.T) {
+	svc, repo, receiver := newWebhookTest(t)
+
+	delivery := &WebhookDelivery{ID: 7, WebhookID: 1, Event: WebhookFeatureUpdated, Payload: []byte(`{"event":"feature.updated"}`)}
+	repo.due = []DueWebhookDelivery{{Delivery: delivery, URL: repo.webhook.URL, Secret: repo.webhook.Secret}}
+
+	if _, err := svc.DeliverWebhooks(context.Background(), 10); err != nil {
+		t.Fatalf("DeliverWebhooks: %v", err)
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(receiver.requests))
+	}
+
+	req := receiver.requests[0]
+	if got := req.Header.Get(WebhookEventHeader); got != string(WebhookFeatureUpdated) {
+		t.Errorf("event header = %q, want %q", got, WebhookFeatureUpdated)
+	}
+	if got := req.Header.Get(WebhookDeliveryHeader); got != "7" {
+		t.Errorf("delivery header = %q, want 7", got)
+	}
+
+	timestamp := req.Header.Get(WebhookTimestampHeader)
+	if _, err := strconv.ParseInt(timestamp, 10, 64); err != nil {
+		t.Errorf("timestamp header %q is not a unix time", timestamp)
+	}
+	if got, want := req.Header.Get(WebhookSignatureHeader), SignWebhook("secret", timestamp, receiver.bodies[0]); got != want {
+		t.Errorf("signature header = %q, want %q", got, want)
+	}
+
+	if got := repo.attempts[0]; got.Status != WebhookDeliverySucceeded || got.ResponseStatus != http.StatusNoContent {
+		t.Errorf("delivery = %s with status %d, want succeeded with 204", got.Status, got.ResponseStatus)
+	}
+}
+
+func TestDeliverWebhooksRetriesServerErrors(t *testing.T) {
+	svc, repo, _ := newWebhookTest(t, http.StatusServiceUnavailable)
+
+	delivery := &WebhookDelivery{ID: 7, WebhookID: 1, Event: WebhookFeatureUpdated, Payload: []byte(`{}`)}
+	repo.due = []DueWebhookDelivery{{Delivery: delivery, URL: repo.webhook.URL, Secret: repo.webhook.Secret}}
+
+	before := time.Now()
+	if _, err := svc.DeliverWebhooks(context.Background(), 10); err != nil {
+		t.Fatalf("DeliverWebhooks: %v", err)
+	}
+
+	got := repo.attempts[0]
+	if got.Status != WebhookDeliveryPending || got.ResponseStatus != http.StatusServiceUnavailable || got.Attempts != 1 {
+		t.Fatalf("delivery = %s with status %d after %d attempts, want pending with 503 after 1",
+			got.Status, got.ResponseStatus, got.Attempts)
+	}
+
+	delay := webhookRetryDelay(1)
+	if got.NextAttemptAt.Before(before.Add(delay)) || got.NextAttemptAt.After(time.Now().Add(delay)) {
+		t.Errorf("next attempt at %v, want %v after the attempt", got.NextAttemptAt, delay)
+	}
+}
+
+func TestTestWebhookIsNotRetried(t *testing.T) {
+	svc, repo, receiver := newWebhookTest(t, http.StatusInternalServerError)
+
+	delivery, err := svc.TestWebhook(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("TestWebhook: %v", err)
+	}
+
+	if len(receiver.requests) != 1 {
+		t.Fatalf("received %d requests, want 1", len(receiver.requests))
+	}
+	if got := receiver.requests[0].Header.Get(WebhookEventHeader); got != string(WebhookPing) {
+		t.Errorf("event header = %q, want %q", got, WebhookPing)
+	}
+
+	if delivery.Status != WebhookDeliveryFailed || delivery.Attempts != 1 || !delivery.NextAttemptAt.IsZero() {
+		t.Errorf("delivery = %s after %d attempts, next at %v, want failed after 1 and no next attempt",
+			delivery.Status, delivery.Attempts, delivery.NextAttemptAt)
+	}
+	if len(repo.attempts) != 1 {
+		t.Errorf("recorded %d attempts, want 1", len(repo.attempts))
+	}
+}
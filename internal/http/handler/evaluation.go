@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+// EvaluateFeature assigns the user of the request a variant of a feature
+// and explains the assignment.
+func (f *Feature) EvaluateFeature(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := decodeEvaluationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	evaluation, err := f.featureSvc.Evaluate(r.Context(), id, user)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to evaluate feature %d", id))
+		return
+	}
+
+	Ok(w, mapEvaluationResponse(evaluation))
+}
+
+// ExplainEvaluation evaluates a feature for the user of the request and
+// lists every check made on the way, for debugging targeting.
+func (f *Feature) ExplainEvaluation(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	user, ok := decodeEvaluationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	evaluation, steps, err := f.featureSvc.ExplainEvaluation(r.Context(), id, user)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to explain evaluation of feature %d", id))
+		return
+	}
+
+	Ok(w, mapExplanationResponse(evaluation, steps))
+}
+
+func decodeEvaluationRequest(w http.ResponseWriter, r *http.Request) (feature.EvaluationContext, bool) {
+	defer r.Body.Close() // nolint: errcheck
+
+	var req evaluationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid evaluation payload")
+		return feature.EvaluationContext{}, false
+	}
+
+	if req.UserID == "" {
+		Error(w, http.StatusBadRequest, "user_id is required")
+		return feature.EvaluationContext{}, false
+	}
+
+	return feature.EvaluationContext{Key: req.UserID, Attributes: req.Attributes}, true
+}
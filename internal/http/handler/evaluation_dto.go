@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+type evaluationRequest struct {
+	UserID     string            `json:"user_id"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+type evaluationResponse struct {
+	FeatureID    int32                       `json:"feature_id"`
+	Feature      string                      `json:"feature"`
+	Variant      string                      `json:"variant,omitempty"`
+	Value        json.RawMessage             `json:"value,omitempty"`
+	Reason       string                      `json:"reason"`
+	Bucket       *uint8                      `json:"bucket,omitempty"`
+	Prerequisite *prerequisiteResultResponse `json:"prerequisite,omitempty"`
+	Rule         *ruleMatchResponse          `json:"rule,omitempty"`
+}
+
+type explanationResponse struct {
+	Evaluation *evaluationResponse      `json:"evaluation"`
+	Steps      []evaluationStepResponse `json:"steps"`
+}
+
+type evaluationStepResponse struct {
+	FeatureID int32  `json:"feature_id"`
+	Feature   string `json:"feature"`
+	Check     string `json:"check"`
+	Matched   bool   `json:"matched"`
+	Detail    string `json:"detail"`
+}
+
+type ruleMatchResponse struct {
+	Index     int    `json:"index"`
+	SegmentID int32  `json:"segment_id"`
+	Segment   string `json:"segment"`
+}
+
+type prerequisiteResultResponse struct {
+	FeatureID  int32               `json:"feature_id"`
+	Variants   []string            `json:"variants"`
+	Evaluation *evaluationResponse `json:"evaluation"`
+}
+
+func mapEvaluationResponse(evaluation *feature.Evaluation) *evaluationResponse {
+	resp := &evaluationResponse{
+		FeatureID: evaluation.FeatureID,
+		Feature:   evaluation.Feature,
+		Reason:    string(evaluation.Reason),
+		Bucket:    evaluation.Bucket,
+	}
+	if evaluation.Variant != nil {
+		resp.Variant = evaluation.Variant.Name
+		resp.Value = evaluation.Variant.Value
+	}
+	if p := evaluation.Prerequisite; p != nil {
+		resp.Prerequisite = &prerequisiteResultResponse{
+			FeatureID:  p.FeatureID,
+			Variants:   p.Variants,
+			Evaluation: mapEvaluationResponse(p.Evaluation),
+		}
+	}
+	if rule := evaluation.Rule; rule != nil {
+		resp.Rule = &ruleMatchResponse{Index: rule.Index, SegmentID: rule.SegmentID, Segment: rule.Segment}
+	}
+
+	return resp
+}
+
+func mapExplanationResponse(evaluation *feature.Evaluation, steps []feature.EvaluationStep) *explanationResponse {
+	resp := &explanationResponse{
+		Evaluation: mapEvaluationResponse(evaluation),
+		Steps:      make([]evaluationStepResponse, len(steps)),
+	}
+	for i, step := range steps {
+		resp.Steps[i] = evaluationStepResponse{
+			FeatureID: step.FeatureID,
+			Feature:   step.Feature,
+			Check:     string(step.Check),
+			Matched:   step.Matched,
+			Detail:    step.Detail,
+		}
+	}
+
+	return resp
+}
@@ -28,14 +28,26 @@ func NewFeatureHandler(
 }
 
 func (f *Feature) ListFeatures(w http.ResponseWriter, r *http.Request) {
-	features, err := f.featureSvc.ListFeatures(r.Context())
+	filter, err := parseFeatureFilter(r)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+
+	page, err := f.featureSvc.ListFeatures(r.Context(), filter)
 	if err != nil {
 		f.respondError(w, err, "failed to list features")
 		return
 	}
 
-	apiFeatures := make([]featureResponse, len(features))
-	for i, feature := range features {
+	setNextPage(w, r, feature.EncodeCursor(page.Next))
+
+	if notModified(w, r, feature.HashFeatures(page.Features)) {
+		return
+	}
+
+	apiFeatures := make([]featureResponse, len(page.Features))
+	for i, feature := range page.Features {
 		apiFeatures[i] = mapFeatureResponse(feature)
 	}
 
@@ -54,7 +66,11 @@ func (f *Feature) GetFeature(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	Ok(w, feat)
+	if notModified(w, r, feat.Hash()) {
+		return
+	}
+
+	Ok(w, mapFeatureResponse(feat))
 }
 
 func (f *Feature) CreateFeature(w http.ResponseWriter, r *http.Request) {
@@ -74,7 +90,7 @@ func (f *Feature) CreateFeature(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, domainFeature)
+	writeJSON(w, http.StatusCreated, mapFeatureResponse(domainFeature))
 }
 
 func (f *Feature) UpdateFeature(w http.ResponseWriter, r *http.Request) {
@@ -100,7 +116,7 @@ func (f *Feature) UpdateFeature(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	Ok(w, domainFeature)
+	Ok(w, mapFeatureResponse(domainFeature))
 }
 
 func (f *Feature) DeleteFeature(w http.ResponseWriter, r *http.Request) {
@@ -123,13 +139,21 @@ func (f *Feature) ListFeatureEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	events, err := f.featureSvc.ListEventsByFeature(r.Context(), id)
+	filter, err := parseEventFilter(r, id)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+
+	page, err := f.featureSvc.ListEventsByFeature(r.Context(), filter)
 	if err != nil {
 		f.respondError(w, err, fmt.Sprintf("failed to list events for feature %d", id))
 		return
 	}
 
-	Ok(w, events)
+	setNextPage(w, r, feature.EncodeCursor(page.Next))
+
+	Ok(w, mapEventsResponse(page.Events))
 }
 
 func (f *Feature) RecordFeatureEvent(w http.ResponseWriter, r *http.Request) {
@@ -146,18 +170,116 @@ func (f *Feature) RecordFeatureEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The Idempotency-Key header is an alternative to event_id in the body.
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		if req.EventID != "" && req.EventID != key {
+			Error(w, http.StatusBadRequest, "Idempotency-Key does not match event_id")
+			return
+		}
+		req.EventID = key
+	}
+
 	event, err := feature.NewEvent(id, req.UserID, req.Variant, req.Type)
 	if err != nil {
 		f.respondError(w, err, "failed to create event")
 		return
 	}
-
-	if err := f.featureSvc.RecordEvent(r.Context(), event); err != nil {
+	event.ClientID = req.EventID
+	event.Value = req.Value
+	event.Properties = req.Properties
+
+	err = f.featureSvc.RecordEvent(r.Context(), event)
+	if errors.Is(err, feature.ErrEventAlreadyRecorded) {
+		// a retry: answer with the event recorded the first time
+		Ok(w, mapEventResponse(event))
+		return
+	}
+	if err != nil {
 		f.respondError(w, err, fmt.Sprintf("failed to record event for feature %d", id))
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, event)
+	// events without an id were queued by the buffered writer
+	status := http.StatusCreated
+	if event.ID == 0 {
+		status = http.StatusAccepted
+	}
+
+	writeJSON(w, status, mapEventResponse(event))
+}
+
+// GetFeatureResults compares the variants of a feature on every event type
+// recorded for it.
+func (f *Feature) GetFeatureResults(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	mode := feature.ResultsMode(r.URL.Query().Get("mode"))
+
+	results, err := f.featureSvc.GetResults(r.Context(), id, mode)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to get results of feature %d", id))
+		return
+	}
+
+	Ok(w, mapResultsResponse(results))
+}
+
+func (f *Feature) ListReallocations(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query(), 0)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+
+	reallocations, err := f.featureSvc.ListReallocations(r.Context(), id, limit)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list reallocations of feature %d", id))
+		return
+	}
+
+	Ok(w, mapReallocationsResponse(reallocations))
+}
+
+// maxEventBatchBody bounds the request body of RecordEventsBatch; a full
+// batch of typical events is well below this.
+const maxEventBatchBody = 8 << 20
+
+// RecordEventsBatch stores events of several features at once. It answers
+// 201 if all events were stored, 207 if some were rejected or already
+// recorded and 400 if none were stored; the body always lists the rejected
+// and duplicate items.
+func (f *Feature) RecordEventsBatch(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close() // nolint: errcheck
+
+	var req eventBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEventBatchBody)).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid event batch payload")
+		return
+	}
+
+	result, err := f.featureSvc.RecordEvents(r.Context(), buildEventsFromBatchRequest(&req))
+	if err != nil {
+		f.respondError(w, err, "failed to record event batch")
+		return
+	}
+
+	status := http.StatusCreated
+	switch {
+	case result.Accepted == 0 && len(result.Duplicates) == 0:
+		status = http.StatusBadRequest
+	case len(result.Errors) > 0 || len(result.Duplicates) > 0:
+		status = http.StatusMultiStatus
+	}
+
+	writeJSON(w, status, mapEventBatchResponse(result))
 }
 
 func (f *Feature) respondError(w http.ResponseWriter, err error, msg string) {
@@ -171,12 +293,91 @@ func (f *Feature) respondError(w http.ResponseWriter, err error, msg string) {
 		errors.Is(err, feature.ErrVariantAlreadyExist),
 		errors.Is(err, feature.ErrEventFeatureIDRequired),
 		errors.Is(err, feature.ErrEventTypeRequired),
+		errors.Is(err, feature.ErrEventClientIDTooLong),
+		errors.Is(err, feature.ErrEventValueInvalid),
+		errors.Is(err, feature.ErrEventTooManyProperties),
+		errors.Is(err, feature.ErrUnknownVariant),
+		errors.Is(err, feature.ErrVariantMismatch),
+		errors.Is(err, feature.ErrMetricNameRequired),
+		errors.Is(err, feature.ErrMetricEventTypeRequired),
+		errors.Is(err, feature.ErrInvalidAggregation),
+		errors.Is(err, feature.ErrInvalidDirection),
+		errors.Is(err, feature.ErrInvalidMetricWindow),
+		errors.Is(err, feature.ErrMetricAlreadyExists),
+		errors.Is(err, feature.ErrEmptyTag),
+		errors.Is(err, feature.ErrInvalidAnalysis),
+		errors.Is(err, feature.ErrInvalidResultsMode),
+		errors.Is(err, feature.ErrBanditEventTypeRequired),
+		errors.Is(err, feature.ErrBanditMinWeightTooHigh),
+		errors.Is(err, feature.ErrInvalidValueType),
+		errors.Is(err, feature.ErrVariantValueRequired),
+		errors.Is(err, feature.ErrVariantValueNotAllowed),
+		errors.Is(err, feature.ErrVariantValueTypeInvalid),
+		errors.Is(err, feature.ErrValueSchemaNotAllowed),
+		errors.Is(err, feature.ErrInvalidValueSchema),
+		errors.Is(err, feature.ErrVariantValueSchemaMismatch),
+		errors.Is(err, feature.ErrInvalidKind),
+		errors.Is(err, feature.ErrBooleanVariants),
+		errors.Is(err, feature.ErrBooleanValues),
+		errors.Is(err, feature.ErrMultivariateValueTypeRequired),
+		errors.Is(err, feature.ErrBanditRequiresExperiment),
+		errors.Is(err, feature.ErrInvalidGuardrailThreshold),
+		errors.Is(err, feature.ErrInvalidWebhookURL),
+		errors.Is(err, feature.ErrInvalidWebhookEvent),
+		errors.Is(err, feature.ErrInvalidScheduleAction),
+		errors.Is(err, feature.ErrInvalidScheduleTime),
+		errors.Is(err, feature.ErrScheduleInPast),
+		errors.Is(err, feature.ErrInvalidTimezone),
+		errors.Is(err, feature.ErrScheduleVariantsRequired),
+		errors.Is(err, feature.ErrScheduleVariantsNotAllowed),
+		errors.Is(err, feature.ErrPrerequisiteVariantsRequired),
+		errors.Is(err, feature.ErrDuplicatePrerequisite),
+		errors.Is(err, feature.ErrPrerequisiteCycle),
+		errors.Is(err, feature.ErrPrerequisiteNotFound),
+		errors.Is(err, feature.ErrUnknownPrerequisiteVariant),
+		errors.Is(err, feature.ErrFeatureIsPrerequisite),
+		errors.Is(err, feature.ErrOverrideUserKeyRequired),
+		errors.Is(err, feature.ErrRuleSegmentRequired),
+		errors.Is(err, feature.ErrUnknownRuleVariant),
+		errors.Is(err, feature.ErrUnknownSegment),
+		errors.Is(err, feature.ErrSegmentAlreadyExists),
+		errors.Is(err, feature.ErrSegmentNameRequired),
+		errors.Is(err, feature.ErrSegmentInUse),
+		errors.Is(err, feature.ErrEmptyUserKey),
+		errors.Is(err, feature.ErrConditionAttributeRequired),
+		errors.Is(err, feature.ErrInvalidConditionOperator),
+		errors.Is(err, feature.ErrConditionValuesRequired),
+		errors.Is(err, feature.ErrSegmentConditionsOrUserKeys),
+		errors.Is(err, feature.ErrInvalidSort),
+		errors.Is(err, feature.ErrInvalidPageSize),
+		errors.Is(err, feature.ErrInvalidRange),
+		errors.Is(err, feature.ErrEventBatchEmpty),
+		errors.Is(err, feature.ErrEventBatchTooLarge),
 		errors.Is(err, feature.ErrFeatureAlreadyExists):
 		Error(w, http.StatusBadRequest, err.Error())
 	case errors.Is(err, feature.ErrFeatureNotFound):
 		Error(w, http.StatusNotFound, "feature not found")
+	case errors.Is(err, feature.ErrMetricNotFound):
+		Error(w, http.StatusNotFound, "metric not found")
+	case errors.Is(err, feature.ErrMetricNotAttached):
+		Error(w, http.StatusNotFound, "metric is not attached to feature")
+	case errors.Is(err, feature.ErrGuardrailNotFound):
+		Error(w, http.StatusNotFound, "guardrail not found")
+	case errors.Is(err, feature.ErrWebhookNotFound):
+		Error(w, http.StatusNotFound, "webhook not found")
+	case errors.Is(err, feature.ErrSegmentNotFound):
+		Error(w, http.StatusNotFound, "segment not found")
+	case errors.Is(err, feature.ErrOverrideNotFound):
+		Error(w, http.StatusNotFound, "override not found")
+	case errors.Is(err, feature.ErrScheduleNotFound):
+		Error(w, http.StatusNotFound, "pending schedule not found")
 	case errors.Is(err, feature.ErrInvalidFeatureID):
 		Error(w, http.StatusBadRequest, "invalid feature id")
+	case errors.Is(err, feature.ErrEventQueueFull):
+		w.Header().Set("Retry-After", "1")
+		Error(w, http.StatusTooManyRequests, "event queue is full")
+	case errors.Is(err, feature.ErrEventWriterClosed):
+		Error(w, http.StatusServiceUnavailable, "event writer is shutting down")
 	case errors.Is(err, feature.ErrEventsRepoUnset):
 		Error(w, http.StatusInternalServerError, "event repository not configured")
 	default:
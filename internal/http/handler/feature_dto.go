@@ -1,47 +1,304 @@
 package handler
 
-import "github.com/eve-an/splitter/internal/feature"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+	"github.com/eve-an/splitter/internal/stats"
+)
 
 type variantPayload struct {
-	Name   string `json:"name"`
-	Weight uint8  `json:"weight"`
+	Name   string          `json:"name"`
+	Weight uint8           `json:"weight"`
+	Value  json.RawMessage `json:"value,omitempty"`
 }
 
 type featureRequest struct {
-	Name        string           `json:"name"`
-	Description string           `json:"description"`
-	Active      bool             `json:"active"`
-	Variants    []variantPayload `json:"variants"`
+	Name          string                `json:"name"`
+	Description   string                `json:"description"`
+	Active        bool                  `json:"active"`
+	Kind          string                `json:"kind"`
+	Variants      []variantPayload      `json:"variants"`
+	Tags          []string              `json:"tags"`
+	Analysis      string                `json:"analysis"`
+	ValueType     string                `json:"value_type"`
+	ValueSchema   json.RawMessage       `json:"value_schema"`
+	Bandit        *banditPayload        `json:"bandit"`
+	Prerequisites []prerequisitePayload `json:"prerequisites"`
+	Rules         []ruleRequest         `json:"rules"`
+}
+
+type banditPayload struct {
+	EventType string `json:"event_type"`
+	MinWeight uint8  `json:"min_weight"`
+}
+
+type prerequisitePayload struct {
+	FeatureID int32    `json:"feature_id"`
+	Variants  []string `json:"variants"`
+}
+
+type ruleRequest struct {
+	SegmentID int32  `json:"segment_id"`
+	Variant   string `json:"variant"`
+}
+
+type ruleResponse struct {
+	Segment segmentResponse `json:"segment"`
+	Variant string          `json:"variant"`
+}
+
+type reallocationResponse struct {
+	ID        int64                        `json:"id"`
+	CreatedAt time.Time                    `json:"created_at"`
+	Variants  []feature.ReallocatedVariant `json:"variants"`
 }
 
 type eventRequest struct {
-	UserID  string `json:"user_id"`
+	EventID    string         `json:"event_id"`
+	UserID     string         `json:"user_id"`
+	Variant    string         `json:"variant"`
+	Type       string         `json:"type"`
+	Value      *float64       `json:"value"`
+	Properties map[string]any `json:"properties"`
+}
+
+type batchEventRequest struct {
+	EventID    string         `json:"event_id"`
+	FeatureID  int32          `json:"feature_id"`
+	UserID     string         `json:"user_id"`
+	Variant    string         `json:"variant"`
+	Type       string         `json:"type"`
+	Value      *float64       `json:"value"`
+	Properties map[string]any `json:"properties"`
+}
+
+type eventBatchRequest struct {
+	Events []batchEventRequest `json:"events"`
+}
+
+type batchItemErrorResponse struct {
+	Index   int    `json:"index"`
+	Message string `json:"message"`
+}
+
+type eventResponse struct {
+	ID              int64          `json:"id"`
+	FeatureID       int32          `json:"feature_id"`
+	UserID          string         `json:"user_id"`
+	Variant         string         `json:"variant"`
+	Type            string         `json:"type"`
+	CreatedAt       time.Time      `json:"created_at"`
+	EventID         string         `json:"event_id,omitempty"`
+	Value           *float64       `json:"value"`
+	Properties      map[string]any `json:"properties"`
+	VariantMismatch bool           `json:"variant_mismatch"`
+}
+
+type batchDuplicateResponse struct {
+	Index int           `json:"index"`
+	Event eventResponse `json:"event"`
+}
+
+type eventBatchResponse struct {
+	Accepted   int                      `json:"accepted"`
+	Rejected   int                      `json:"rejected"`
+	Duplicates []batchDuplicateResponse `json:"duplicates"`
+	Errors     []batchItemErrorResponse `json:"errors"`
+}
+
+type testResultResponse struct {
+	Difference       float64 `json:"difference"`
+	Lower            float64 `json:"ci_lower"`
+	Upper            float64 `json:"ci_upper"`
+	Statistic        float64 `json:"statistic"`
+	DegreesOfFreedom float64 `json:"degrees_of_freedom,omitempty"`
+	PValue           float64 `json:"p_value"`
+}
+
+type variantMetricResponse struct {
+	Variant        string              `json:"variant"`
+	Users          int64               `json:"users"`
+	Conversions    int64               `json:"conversions"`
+	ConversionRate float64             `json:"conversion_rate"`
+	Sum            float64             `json:"sum"`
+	Mean           float64             `json:"mean"`
+	StdDev         float64             `json:"std_dev"`
+	ConversionTest *testResultResponse `json:"conversion_test,omitempty"`
+	MeanTest       *testResultResponse `json:"mean_test,omitempty"`
+	Bayesian       *bayesianResponse   `json:"bayesian,omitempty"`
+	Adjusted       *adjustedResponse   `json:"adjusted,omitempty"`
+	Verdict        string              `json:"verdict,omitempty"`
+}
+
+type metricResultResponse struct {
+	Metric   metricResponse          `json:"metric"`
+	CUPED    *cupedResponse          `json:"cuped,omitempty"`
+	Variants []variantMetricResponse `json:"variants"`
+}
+
+type exposureResponse struct {
 	Variant string `json:"variant"`
-	Type    string `json:"type"`
+	Users   int64  `json:"users"`
+}
+
+type resultsResponse struct {
+	FeatureID       int32                  `json:"feature_id"`
+	Control         string                 `json:"control"`
+	Mode            string                 `json:"mode"`
+	Analysis        string                 `json:"analysis"`
+	ConfidenceLevel float64                `json:"confidence_level"`
+	Exposures       []exposureResponse     `json:"exposures"`
+	SampleRatio     *sampleRatioResponse   `json:"sample_ratio,omitempty"`
+	Metrics         []metricResultResponse `json:"metrics"`
+}
+
+type cupedResponse struct {
+	Theta           float64 `json:"theta"`
+	CovariateMean   float64 `json:"covariate_mean"`
+	LookbackSeconds int64   `json:"lookback_seconds"`
+}
+
+type adjustedResponse struct {
+	Mean   float64             `json:"mean"`
+	StdDev float64             `json:"std_dev"`
+	Test   *testResultResponse `json:"test,omitempty"`
+}
+
+type bayesianResponse struct {
+	Alpha                    float64  `json:"alpha"`
+	Beta                     float64  `json:"beta"`
+	Mean                     float64  `json:"mean"`
+	Lower                    float64  `json:"ci_lower"`
+	Upper                    float64  `json:"ci_upper"`
+	ProbabilityToBeatControl *float64 `json:"probability_to_beat_control,omitempty"`
+	ExpectedLoss             *float64 `json:"expected_loss,omitempty"`
+}
+
+type sampleRatioResponse struct {
+	Mismatch         bool                         `json:"mismatch"`
+	Statistic        float64                      `json:"statistic"`
+	DegreesOfFreedom float64                      `json:"degrees_of_freedom"`
+	PValue           float64                      `json:"p_value"`
+	Threshold        float64                      `json:"threshold"`
+	Variants         []sampleRatioVariantResponse `json:"variants"`
+}
+
+type sampleRatioVariantResponse struct {
+	Variant  string  `json:"variant"`
+	Weight   uint8   `json:"weight"`
+	Users    int64   `json:"users"`
+	Expected float64 `json:"expected"`
 }
 
 type variantResponse struct {
-	ID     int32  `json:"id"`
-	Name   string `json:"name"`
-	Weight uint8  `json:"weight"`
+	ID     int32           `json:"id"`
+	Name   string          `json:"name"`
+	Weight uint8           `json:"weight"`
+	Value  json.RawMessage `json:"value,omitempty"`
 }
 
 type featureResponse struct {
-	ID          int32             `json:"id"`
-	Name        string            `json:"name"`
-	Description string            `json:"description"`
-	Active      bool              `json:"active"`
-	Variants    []variantResponse `json:"variants"`
+	ID                  int32                 `json:"id"`
+	Name                string                `json:"name"`
+	Description         string                `json:"description"`
+	Active              bool                  `json:"active"`
+	Kind                string                `json:"kind"`
+	Variants            []variantResponse     `json:"variants"`
+	Tags                []string              `json:"tags"`
+	Version             int32                 `json:"version"`
+	Analysis            string                `json:"analysis"`
+	ValueType           string                `json:"value_type,omitempty"`
+	ValueSchema         json.RawMessage       `json:"value_schema,omitempty"`
+	Bandit              *banditPayload        `json:"bandit,omitempty"`
+	Prerequisites       []prerequisitePayload `json:"prerequisites"`
+	Rules               []ruleResponse        `json:"rules"`
+	Overrides           map[string]string     `json:"overrides,omitempty"`
+	SampleRatioMismatch bool                  `json:"sample_ratio_mismatch"`
+}
+
+type featureChangeResponse struct {
+	Type      string           `json:"type"`
+	FeatureID int32            `json:"feature_id"`
+	Feature   *featureResponse `json:"feature,omitempty"`
+}
+
+func mapFeatureChangeResponse(change feature.Change) featureChangeResponse {
+	resp := featureChangeResponse{
+		Type:      string(change.Type),
+		FeatureID: change.FeatureID,
+	}
+
+	if change.Feature != nil && change.Type != feature.ChangeDeleted {
+		mapped := mapFeatureResponse(change.Feature)
+		resp.Feature = &mapped
+	}
+
+	return resp
 }
 
 func mapFeatureResponse(feature *feature.Feature) featureResponse {
 	return featureResponse{
-		ID:          feature.ID,
-		Name:        feature.Name,
-		Description: feature.Descritption,
-		Active:      feature.Active,
-		Variants:    mapVariantsResponse(feature.Variants),
+		ID:                  feature.ID,
+		Name:                feature.Name,
+		Description:         feature.Descritption,
+		Active:              feature.Active,
+		Kind:                string(feature.Kind),
+		Variants:            mapVariantsResponse(feature.Variants),
+		Tags:                mapTagsResponse(feature.Tags),
+		Version:             feature.Version,
+		Analysis:            string(feature.Analysis),
+		ValueType:           string(feature.ValueType),
+		ValueSchema:         feature.ValueSchema,
+		Bandit:              mapBanditResponse(feature.Bandit),
+		Prerequisites:       mapPrerequisitesResponse(feature.Prerequisites),
+		Rules:               mapRulesResponse(feature.Rules),
+		Overrides:           feature.Overrides,
+		SampleRatioMismatch: feature.SampleRatioMismatch,
+	}
+}
+
+func mapBanditResponse(bandit *feature.Bandit) *banditPayload {
+	if bandit == nil {
+		return nil
+	}
+
+	return &banditPayload{EventType: bandit.EventType, MinWeight: bandit.MinWeight}
+}
+
+func mapPrerequisitesResponse(prerequisites []feature.Prerequisite) []prerequisitePayload {
+	resp := make([]prerequisitePayload, len(prerequisites))
+	for i, prerequisite := range prerequisites {
+		resp[i] = prerequisitePayload{FeatureID: prerequisite.FeatureID, Variants: prerequisite.Variants}
 	}
+
+	return resp
+}
+
+func mapRulesResponse(rules []feature.Rule) []ruleResponse {
+	resp := make([]ruleResponse, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Segment == nil {
+			continue
+		}
+		resp = append(resp, ruleResponse{Segment: mapSegmentResponse(rule.Segment), Variant: rule.Variant})
+	}
+
+	return resp
+}
+
+func mapReallocationsResponse(reallocations []*feature.Reallocation) []reallocationResponse {
+	resp := make([]reallocationResponse, len(reallocations))
+	for i, reallocation := range reallocations {
+		resp[i] = reallocationResponse{
+			ID:        reallocation.ID,
+			CreatedAt: reallocation.CreatedAt,
+			Variants:  reallocation.Variants,
+		}
+	}
+
+	return resp
 }
 
 func mapVariantsResponse(variants []feature.Variant) []variantResponse {
@@ -51,15 +308,38 @@ func mapVariantsResponse(variants []feature.Variant) []variantResponse {
 			ID:     variant.ID,
 			Name:   variant.Name,
 			Weight: variant.Weight,
+			Value:  variant.Value,
 		}
 	}
 	return variantResponses
 }
 
+func mapTagsResponse(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+
+	return tags
+}
+
+func buildVariantFromPayload(payload variantPayload) (feature.Variant, error) {
+	variant, err := feature.NewVariant(payload.Name, payload.Weight)
+	if err != nil {
+		return feature.Variant{}, err
+	}
+
+	// an explicit null reads the same as a missing value
+	if string(payload.Value) != "null" {
+		variant.Value = payload.Value
+	}
+
+	return variant, nil
+}
+
 func buildFeatureFromRequest(req *featureRequest) (*feature.Feature, error) {
 	variants := make([]feature.Variant, 0, len(req.Variants))
 	for _, v := range req.Variants {
-		variant, err := feature.NewVariant(v.Name, v.Weight)
+		variant, err := buildVariantFromPayload(v)
 		if err != nil {
 			return nil, err
 		}
@@ -71,5 +351,230 @@ func buildFeatureFromRequest(req *featureRequest) (*feature.Feature, error) {
 		return nil, err
 	}
 
-	return feature.NewFeature(req.Name, req.Description, req.Active, &domainVariants)
+	// the variants depend on the value type and kind, so they are only
+	// validated along with the complete feature below
+	f, err := feature.NewFeature(req.Name, req.Description, req.Active, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Variants = domainVariants
+	f.Tags = req.Tags
+	f.ValueType = feature.ValueType(req.ValueType)
+	if string(req.ValueSchema) != "null" {
+		f.ValueSchema = req.ValueSchema
+	}
+	if req.Kind != "" {
+		f.Kind = feature.Kind(req.Kind)
+	}
+	f.ApplyKindDefaults()
+	if req.Analysis != "" {
+		f.Analysis = feature.Analysis(req.Analysis)
+	}
+	if req.Bandit != nil {
+		f.Bandit = &feature.Bandit{EventType: req.Bandit.EventType, MinWeight: req.Bandit.MinWeight}
+	}
+	for _, p := range req.Prerequisites {
+		f.Prerequisites = append(f.Prerequisites, feature.Prerequisite{FeatureID: p.FeatureID, Variants: p.Variants})
+	}
+	for _, rule := range req.Rules {
+		f.Rules = append(f.Rules, feature.Rule{SegmentID: rule.SegmentID, Variant: rule.Variant})
+	}
+
+	return f, f.Validate()
+}
+
+func mapEventResponse(event *feature.Event) eventResponse {
+	return eventResponse{
+		ID:              event.ID,
+		FeatureID:       event.FeatureID,
+		UserID:          event.UserID,
+		Variant:         event.Variant,
+		Type:            event.Type,
+		CreatedAt:       event.CreatedAt,
+		EventID:         event.ClientID,
+		Value:           event.Value,
+		Properties:      event.Properties,
+		VariantMismatch: event.VariantMismatch,
+	}
+}
+
+func mapEventsResponse(events []*feature.Event) []eventResponse {
+	resp := make([]eventResponse, len(events))
+	for i, event := range events {
+		resp[i] = mapEventResponse(event)
+	}
+
+	return resp
+}
+
+func mapEventBatchResponse(result *feature.BatchResult) eventBatchResponse {
+	resp := eventBatchResponse{
+		Accepted:   result.Accepted,
+		Rejected:   len(result.Errors),
+		Duplicates: make([]batchDuplicateResponse, len(result.Duplicates)),
+		Errors:     make([]batchItemErrorResponse, len(result.Errors)),
+	}
+
+	for i, duplicate := range result.Duplicates {
+		resp.Duplicates[i] = batchDuplicateResponse{
+			Index: duplicate.Index,
+			Event: mapEventResponse(duplicate.Event),
+		}
+	}
+
+	for i, itemErr := range result.Errors {
+		resp.Errors[i] = batchItemErrorResponse{
+			Index:   itemErr.Index,
+			Message: itemErr.Err.Error(),
+		}
+	}
+
+	return resp
+}
+
+func buildEventsFromBatchRequest(req *eventBatchRequest) []*feature.Event {
+	events := make([]*feature.Event, len(req.Events))
+	for i, e := range req.Events {
+		events[i] = &feature.Event{
+			FeatureID:  e.FeatureID,
+			UserID:     e.UserID,
+			Variant:    e.Variant,
+			Type:       e.Type,
+			ClientID:   e.EventID,
+			Value:      e.Value,
+			Properties: e.Properties,
+		}
+	}
+
+	return events
+}
+
+func mapResultsResponse(results *feature.Results) resultsResponse {
+	resp := resultsResponse{
+		FeatureID:       results.FeatureID,
+		Control:         results.Control,
+		Mode:            string(results.Mode),
+		Analysis:        string(results.Analysis),
+		ConfidenceLevel: stats.ConfidenceLevel,
+		Exposures:       make([]exposureResponse, len(results.Exposures)),
+		SampleRatio:     mapSampleRatioResponse(results.SampleRatio),
+		Metrics:         make([]metricResultResponse, len(results.Metrics)),
+	}
+
+	for i, exposure := range results.Exposures {
+		resp.Exposures[i] = exposureResponse{Variant: exposure.Variant, Users: exposure.Users}
+	}
+
+	for i, metric := range results.Metrics {
+		variants := make([]variantMetricResponse, len(metric.Variants))
+		for j, v := range metric.Variants {
+			variants[j] = variantMetricResponse{
+				Variant:        v.Variant,
+				Users:          v.Users,
+				Conversions:    v.Conversions,
+				ConversionRate: v.ConversionRate,
+				Sum:            v.Sum,
+				Mean:           v.Mean,
+				StdDev:         v.StdDev,
+				ConversionTest: mapTestResultResponse(v.ConversionTest),
+				MeanTest:       mapTestResultResponse(v.MeanTest),
+				Bayesian:       mapBayesianResponse(v.Bayesian),
+				Adjusted:       mapAdjustedResponse(v.Adjusted),
+				Verdict:        string(v.Verdict),
+			}
+		}
+
+		resp.Metrics[i] = metricResultResponse{
+			Metric:   mapMetricResponse(&metric.Metric),
+			CUPED:    mapCUPEDResponse(metric.CUPED),
+			Variants: variants,
+		}
+	}
+
+	return resp
+}
+
+func mapCUPEDResponse(adjustment *stats.CUPED) *cupedResponse {
+	if adjustment == nil {
+		return nil
+	}
+
+	return &cupedResponse{
+		Theta:           adjustment.Theta,
+		CovariateMean:   adjustment.CovariateMean,
+		LookbackSeconds: int64(feature.CUPEDLookback / time.Second),
+	}
+}
+
+func mapAdjustedResponse(adjusted *feature.AdjustedMetric) *adjustedResponse {
+	if adjusted == nil {
+		return nil
+	}
+
+	return &adjustedResponse{
+		Mean:   adjusted.Mean,
+		StdDev: adjusted.StdDev,
+		Test:   mapTestResultResponse(adjusted.Test),
+	}
+}
+
+func mapBayesianResponse(bayesian *feature.BayesianConversion) *bayesianResponse {
+	if bayesian == nil {
+		return nil
+	}
+
+	resp := &bayesianResponse{
+		Alpha: bayesian.Posterior.Alpha,
+		Beta:  bayesian.Posterior.Beta,
+		Mean:  bayesian.Posterior.Mean(),
+		Lower: bayesian.Lower,
+		Upper: bayesian.Upper,
+	}
+	if comparison := bayesian.Comparison; comparison != nil {
+		resp.ProbabilityToBeatControl = &comparison.ProbabilityToBeat
+		resp.ExpectedLoss = &comparison.ExpectedLoss
+	}
+
+	return resp
+}
+
+func mapSampleRatioResponse(ratio *feature.SampleRatio) *sampleRatioResponse {
+	if ratio == nil {
+		return nil
+	}
+
+	resp := &sampleRatioResponse{
+		Mismatch:         ratio.Mismatch,
+		Statistic:        ratio.Test.Statistic,
+		DegreesOfFreedom: ratio.Test.DegreesOfFreedom,
+		PValue:           ratio.Test.PValue,
+		Threshold:        feature.SampleRatioThreshold,
+		Variants:         make([]sampleRatioVariantResponse, len(ratio.Variants)),
+	}
+	for i, variant := range ratio.Variants {
+		resp.Variants[i] = sampleRatioVariantResponse{
+			Variant:  variant.Variant,
+			Weight:   variant.Weight,
+			Users:    variant.Users,
+			Expected: variant.Expected,
+		}
+	}
+
+	return resp
+}
+
+func mapTestResultResponse(result *stats.TestResult) *testResultResponse {
+	if result == nil {
+		return nil
+	}
+
+	return &testResultResponse{
+		Difference:       result.Difference,
+		Lower:            result.Lower,
+		Upper:            result.Upper,
+		Statistic:        result.Statistic,
+		DegreesOfFreedom: result.DegreesOfFreedom,
+		PValue:           result.PValue,
+	}
 }
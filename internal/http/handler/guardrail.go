@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+func (f *Feature) ListGuardrails(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	guardrails, err := f.featureSvc.ListGuardrails(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list guardrails of feature %d", id))
+		return
+	}
+
+	Ok(w, mapGuardrailsResponse(guardrails))
+}
+
+func (f *Feature) SetGuardrail(w http.ResponseWriter, r *http.Request) {
+	featureID, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	metricID, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close() // nolint: errcheck
+
+	var req guardrailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid guardrail payload")
+		return
+	}
+
+	guardrail := &feature.Guardrail{Metric: feature.Metric{ID: metricID}, Threshold: req.Threshold}
+	if err := f.featureSvc.SetGuardrail(r.Context(), featureID, guardrail); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to set guardrail on metric %d of feature %d", metricID, featureID))
+		return
+	}
+
+	Ok(w, mapGuardrailResponse(guardrail))
+}
+
+func (f *Feature) RemoveGuardrail(w http.ResponseWriter, r *http.Request) {
+	featureID, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	metricID, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.RemoveGuardrail(r.Context(), featureID, metricID); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to remove guardrail on metric %d of feature %d", metricID, featureID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Feature) ListAuditEntries(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query(), 0)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+
+	entries, err := f.featureSvc.ListAuditEntries(r.Context(), id, limit)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list audit entries of feature %d", id))
+		return
+	}
+
+	Ok(w, mapAuditEntriesResponse(entries))
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+type guardrailRequest struct {
+	Threshold float64 `json:"threshold"`
+}
+
+type guardrailResponse struct {
+	Metric    metricResponse `json:"metric"`
+	Threshold float64        `json:"threshold"`
+}
+
+type auditEntryResponse struct {
+	ID        int64          `json:"id"`
+	Actor     string         `json:"actor"`
+	Action    string         `json:"action"`
+	Details   map[string]any `json:"details"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+func mapGuardrailResponse(guardrail *feature.Guardrail) guardrailResponse {
+	return guardrailResponse{
+		Metric:    mapMetricResponse(&guardrail.Metric),
+		Threshold: guardrail.Threshold,
+	}
+}
+
+func mapGuardrailsResponse(guardrails []*feature.Guardrail) []guardrailResponse {
+	resp := make([]guardrailResponse, len(guardrails))
+	for i, guardrail := range guardrails {
+		resp[i] = mapGuardrailResponse(guardrail)
+	}
+
+	return resp
+}
+
+func mapAuditEntriesResponse(entries []*feature.AuditEntry) []auditEntryResponse {
+	resp := make([]auditEntryResponse, len(entries))
+	for i, entry := range entries {
+		resp[i] = auditEntryResponse{
+			ID:        entry.ID,
+			Actor:     entry.Actor,
+			Action:    string(entry.Action),
+			Details:   entry.Details,
+			CreatedAt: entry.CreatedAt,
+		}
+	}
+
+	return resp
+}
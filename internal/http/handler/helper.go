@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 )
 
 func writeJSON(w http.ResponseWriter, code int, data any) {
@@ -27,3 +28,34 @@ func Error(w http.ResponseWriter, code int, message string, details ...any) {
 func Ok(w http.ResponseWriter, data any) {
 	writeJSON(w, http.StatusOK, data)
 }
+
+// notModified sets the ETag header and reports whether the client already
+// holds the current representation, in which case 304 has been written.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	quoted := `"` + etag + `"`
+	w.Header().Set("ETag", quoted)
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if !etagMatches(r.Header.Get("If-None-Match"), quoted) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches implements the weak comparison If-None-Match calls for.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for candidate := range strings.SplitSeq(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (f *Feature) ListMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := f.featureSvc.ListMetrics(r.Context())
+	if err != nil {
+		f.respondError(w, err, "failed to list metrics")
+		return
+	}
+
+	Ok(w, mapMetricsResponse(metrics))
+}
+
+func (f *Feature) GetMetric(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	metric, err := f.featureSvc.GetMetric(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to get metric by id %d", id))
+		return
+	}
+
+	Ok(w, mapMetricResponse(metric))
+}
+
+func (f *Feature) CreateMetric(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMetricRequest(w, r)
+	if !ok {
+		return
+	}
+
+	metric := buildMetricFromRequest(req)
+	if err := f.featureSvc.CreateMetric(r.Context(), metric); err != nil {
+		f.respondError(w, err, "failed to create metric")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapMetricResponse(metric))
+}
+
+func (f *Feature) UpdateMetric(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeMetricRequest(w, r)
+	if !ok {
+		return
+	}
+
+	metric := buildMetricFromRequest(req)
+	metric.ID = id
+
+	if err := f.featureSvc.UpdateMetric(r.Context(), metric); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to update metric %d", id))
+		return
+	}
+
+	Ok(w, mapMetricResponse(metric))
+}
+
+func (f *Feature) DeleteMetric(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.DeleteMetric(r.Context(), id); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to delete metric %d", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Feature) ListFeatureMetrics(w http.ResponseWriter, r *http.Request) {
+	featureID, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	metrics, err := f.featureSvc.ListFeatureMetrics(r.Context(), featureID)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list metrics of feature %d", featureID))
+		return
+	}
+
+	Ok(w, mapMetricsResponse(metrics))
+}
+
+func (f *Feature) AttachMetric(w http.ResponseWriter, r *http.Request) {
+	featureID, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	metricID, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.AttachMetric(r.Context(), featureID, metricID); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to attach metric %d to feature %d", metricID, featureID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Feature) DetachMetric(w http.ResponseWriter, r *http.Request) {
+	featureID, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	metricID, ok := parseMetricID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.DetachMetric(r.Context(), featureID, metricID); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to detach metric %d from feature %d", metricID, featureID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseMetricID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	metricIDValue := r.PathValue("metricID")
+	if metricIDValue == "" {
+		Error(w, http.StatusBadRequest, "missing metric id")
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(metricIDValue, 10, 32)
+	if err != nil || id <= 0 {
+		Error(w, http.StatusBadRequest, "invalid metric id", metricIDValue)
+		return 0, false
+	}
+
+	return int32(id), true
+}
+
+func decodeMetricRequest(w http.ResponseWriter, r *http.Request) (*metricRequest, bool) {
+	defer r.Body.Close() // nolint: errcheck
+
+	var req metricRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid metric payload")
+		return nil, false
+	}
+
+	return &req, true
+}
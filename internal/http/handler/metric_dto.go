@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+type metricRequest struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	EventType     string `json:"event_type"`
+	Aggregation   string `json:"aggregation"`
+	WindowSeconds int64  `json:"window_seconds"`
+	Direction     string `json:"direction"`
+}
+
+type metricResponse struct {
+	ID            int32  `json:"id,omitempty"`
+	Name          string `json:"name"`
+	Description   string `json:"description,omitempty"`
+	EventType     string `json:"event_type"`
+	Aggregation   string `json:"aggregation"`
+	WindowSeconds int64  `json:"window_seconds,omitempty"`
+	Direction     string `json:"direction"`
+}
+
+func buildMetricFromRequest(req *metricRequest) *feature.Metric {
+	return &feature.Metric{
+		Name:        req.Name,
+		Description: req.Description,
+		EventType:   req.EventType,
+		Aggregation: feature.Aggregation(req.Aggregation),
+		Window:      time.Duration(req.WindowSeconds) * time.Second,
+		Direction:   feature.Direction(req.Direction),
+	}
+}
+
+func mapMetricResponse(metric *feature.Metric) metricResponse {
+	return metricResponse{
+		ID:            metric.ID,
+		Name:          metric.Name,
+		Description:   metric.Description,
+		EventType:     metric.EventType,
+		Aggregation:   string(metric.Aggregation),
+		WindowSeconds: int64(metric.Window / time.Second),
+		Direction:     string(metric.Direction),
+	}
+}
+
+func mapMetricsResponse(metrics []*feature.Metric) []metricResponse {
+	resp := make([]metricResponse, len(metrics))
+	for i, metric := range metrics {
+		resp[i] = mapMetricResponse(metric)
+	}
+
+	return resp
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+func (f *Feature) ListOverrides(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	feat, err := f.featureSvc.GetFeature(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to get feature by id %d", id))
+		return
+	}
+
+	Ok(w, mapOverridesResponse(feat.Overrides))
+}
+
+func (f *Feature) SetOverride(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	userKey := r.PathValue("userKey")
+
+	defer r.Body.Close() // nolint: errcheck
+
+	var req overrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid override payload")
+		return
+	}
+
+	if _, err := f.featureSvc.SetOverride(r.Context(), id, userKey, req.Variant); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to set override of feature %d", id))
+		return
+	}
+
+	Ok(w, overrideResponse{UserKey: userKey, Variant: req.Variant})
+}
+
+func (f *Feature) RemoveOverride(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.RemoveOverride(r.Context(), id, r.PathValue("userKey")); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to remove override of feature %d", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
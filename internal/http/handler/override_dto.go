@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"maps"
+	"slices"
+)
+
+type overrideRequest struct {
+	Variant string `json:"variant"`
+}
+
+type overrideResponse struct {
+	UserKey string `json:"user_key"`
+	Variant string `json:"variant"`
+}
+
+func mapOverridesResponse(overrides map[string]string) []overrideResponse {
+	resp := make([]overrideResponse, 0, len(overrides))
+	for _, userKey := range slices.Sorted(maps.Keys(overrides)) {
+		resp = append(resp, overrideResponse{UserKey: userKey, Variant: overrides[userKey]})
+	}
+
+	return resp
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+func parseFeatureFilter(r *http.Request) (feature.FeatureFilter, error) {
+	query := r.URL.Query()
+
+	filter := feature.FeatureFilter{
+		NamePrefix: query.Get("name_prefix"),
+		Tag:        query.Get("tag"),
+		Sort:       feature.FeatureSort(query.Get("sort")),
+	}
+
+	// features were listed all at once before they were paginated, and
+	// still are unless a page is asked for
+	defaultLimit := 0
+	if query.Get("cursor") != "" {
+		defaultLimit = feature.DefaultPageSize
+	}
+
+	var err error
+	if filter.Limit, err = parseLimit(query, defaultLimit); err != nil {
+		return filter, err
+	}
+
+	if rawActive := query.Get("active"); rawActive != "" {
+		active, err := strconv.ParseBool(rawActive)
+		if err != nil {
+			return filter, fmt.Errorf("invalid active filter %q", rawActive)
+		}
+		filter.Active = &active
+	}
+
+	if filter.After, err = feature.DecodeCursor[feature.FeatureCursor](query.Get("cursor")); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func parseEventFilter(r *http.Request, featureID int32) (feature.EventFilter, error) {
+	query := r.URL.Query()
+
+	filter := feature.EventFilter{
+		FeatureID: featureID,
+		Type:      query.Get("type"),
+		Variant:   query.Get("variant"),
+		UserID:    query.Get("user_id"),
+	}
+
+	var err error
+	if filter.Limit, err = parseLimit(query, feature.DefaultPageSize); err != nil {
+		return filter, err
+	}
+
+	if filter.From, err = parseTime(query, "from"); err != nil {
+		return filter, err
+	}
+
+	if filter.To, err = parseTime(query, "to"); err != nil {
+		return filter, err
+	}
+
+	if filter.Before, err = feature.DecodeCursor[feature.EventCursor](query.Get("cursor")); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// parseLimit reads the page size, which is defaultLimit without a limit. A
+// zero limit lists all items.
+func parseLimit(query url.Values, defaultLimit int) (int, error) {
+	rawLimit := query.Get("limit")
+	if rawLimit == "" {
+		return defaultLimit, nil
+	}
+
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid limit %q", rawLimit)
+	}
+
+	return limit, nil
+}
+
+func parseTime(query url.Values, key string) (time.Time, error) {
+	raw := query.Get(key)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, expected RFC 3339", key, raw)
+	}
+
+	return t, nil
+}
+
+// setNextPage advertises the cursor of the next page both as a plain header
+// and as a Link to the same request with the cursor applied.
+func setNextPage(w http.ResponseWriter, r *http.Request, cursor string) {
+	if cursor == "" {
+		return
+	}
+
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", cursor)
+	next.RawQuery = query.Encode()
+
+	w.Header().Set("X-Next-Cursor", cursor)
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.RequestURI()))
+}
+
+func queryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, feature.ErrInvalidCursor) {
+		Error(w, http.StatusBadRequest, "invalid cursor")
+		return
+	}
+
+	Error(w, http.StatusBadRequest, "invalid query", err.Error())
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (f *Feature) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	schedules, err := f.featureSvc.ListSchedules(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list schedules of feature %d", id))
+		return
+	}
+
+	Ok(w, mapSchedulesResponse(schedules))
+}
+
+func (f *Feature) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	defer r.Body.Close() // nolint: errcheck
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid schedule payload")
+		return
+	}
+
+	schedule, err := buildScheduleFromRequest(id, &req)
+	if err != nil {
+		f.respondError(w, err, "failed to build schedule")
+		return
+	}
+
+	if err := f.featureSvc.CreateSchedule(r.Context(), schedule); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to create schedule of feature %d", id))
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapScheduleResponse(schedule))
+}
+
+func (f *Feature) CancelSchedule(w http.ResponseWriter, r *http.Request) {
+	featureID, ok := parseFeatureID(w, r)
+	if !ok {
+		return
+	}
+
+	scheduleID, ok := parseScheduleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.CancelSchedule(r.Context(), featureID, scheduleID); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to cancel schedule %d of feature %d", scheduleID, featureID))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func parseScheduleID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	scheduleIDValue := r.PathValue("scheduleID")
+	if scheduleIDValue == "" {
+		Error(w, http.StatusBadRequest, "missing schedule id")
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(scheduleIDValue, 10, 64)
+	if err != nil || id <= 0 {
+		Error(w, http.StatusBadRequest, "invalid schedule id", scheduleIDValue)
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+type scheduleRequest struct {
+	Action string `json:"action"`
+	// RunAt is an RFC 3339 timestamp or a date and time in Timezone.
+	RunAt    string           `json:"run_at"`
+	Timezone string           `json:"timezone"`
+	Variants []variantPayload `json:"variants"`
+}
+
+type scheduleResponse struct {
+	ID         int64            `json:"id"`
+	Action     string           `json:"action"`
+	RunAt      time.Time        `json:"run_at"`
+	Timezone   string           `json:"timezone"`
+	Variants   []variantPayload `json:"variants,omitempty"`
+	Status     string           `json:"status"`
+	Error      string           `json:"error,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+	ExecutedAt *time.Time       `json:"executed_at,omitempty"`
+}
+
+func buildScheduleFromRequest(featureID int32, req *scheduleRequest) (*feature.Schedule, error) {
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	runAt, err := feature.ParseScheduleTime(req.RunAt, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := &feature.Schedule{
+		FeatureID: featureID,
+		Action:    feature.ScheduleAction(req.Action),
+		RunAt:     runAt,
+		Timezone:  timezone,
+	}
+	for _, v := range req.Variants {
+		variant, err := buildVariantFromPayload(v)
+		if err != nil {
+			return nil, err
+		}
+		schedule.Variants = append(schedule.Variants, variant)
+	}
+
+	return schedule, nil
+}
+
+func mapScheduleResponse(schedule *feature.Schedule) scheduleResponse {
+	resp := scheduleResponse{
+		ID:        schedule.ID,
+		Action:    string(schedule.Action),
+		RunAt:     schedule.LocalRunAt(),
+		Timezone:  schedule.Timezone,
+		Status:    string(schedule.Status),
+		Error:     schedule.Error,
+		CreatedAt: schedule.CreatedAt,
+	}
+	for _, variant := range schedule.Variants {
+		resp.Variants = append(resp.Variants, variantPayload{Name: variant.Name, Weight: variant.Weight, Value: variant.Value})
+	}
+	if !schedule.ExecutedAt.IsZero() {
+		resp.ExecutedAt = &schedule.ExecutedAt
+	}
+
+	return resp
+}
+
+func mapSchedulesResponse(schedules []*feature.Schedule) []scheduleResponse {
+	resp := make([]scheduleResponse, len(schedules))
+	for i, schedule := range schedules {
+		resp[i] = mapScheduleResponse(schedule)
+	}
+
+	return resp
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (f *Feature) ListSegments(w http.ResponseWriter, r *http.Request) {
+	segments, err := f.featureSvc.ListSegments(r.Context())
+	if err != nil {
+		f.respondError(w, err, "failed to list segments")
+		return
+	}
+
+	Ok(w, mapSegmentsResponse(segments))
+}
+
+func (f *Feature) GetSegment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSegmentID(w, r)
+	if !ok {
+		return
+	}
+
+	segment, err := f.featureSvc.GetSegment(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to get segment by id %d", id))
+		return
+	}
+
+	Ok(w, mapSegmentResponse(segment))
+}
+
+func (f *Feature) CreateSegment(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeSegmentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	segment := buildSegmentFromRequest(req)
+	if err := f.featureSvc.CreateSegment(r.Context(), segment); err != nil {
+		f.respondError(w, err, "failed to create segment")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, mapSegmentResponse(segment))
+}
+
+func (f *Feature) UpdateSegment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSegmentID(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeSegmentRequest(w, r)
+	if !ok {
+		return
+	}
+
+	segment := buildSegmentFromRequest(req)
+	segment.ID = id
+
+	if err := f.featureSvc.UpdateSegment(r.Context(), segment); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to update segment %d", id))
+		return
+	}
+
+	Ok(w, mapSegmentResponse(segment))
+}
+
+func (f *Feature) DeleteSegment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSegmentID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.DeleteSegment(r.Context(), id); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to delete segment %d", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSegmentUsage lists the features targeting a segment.
+func (f *Feature) ListSegmentUsage(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseSegmentID(w, r)
+	if !ok {
+		return
+	}
+
+	usage, err := f.featureSvc.ListSegmentUsage(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list usage of segment %d", id))
+		return
+	}
+
+	Ok(w, mapSegmentUsageResponse(usage))
+}
+
+func parseSegmentID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	segmentIDValue := r.PathValue("segmentID")
+	if segmentIDValue == "" {
+		Error(w, http.StatusBadRequest, "missing segment id")
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(segmentIDValue, 10, 32)
+	if err != nil || id <= 0 {
+		Error(w, http.StatusBadRequest, "invalid segment id", segmentIDValue)
+		return 0, false
+	}
+
+	return int32(id), true
+}
+
+func decodeSegmentRequest(w http.ResponseWriter, r *http.Request) (*segmentRequest, bool) {
+	defer r.Body.Close() // nolint: errcheck
+
+	var req segmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid segment payload")
+		return nil, false
+	}
+
+	return &req, true
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+type segmentRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	UserKeys    []string            `json:"user_keys"`
+	Conditions  []feature.Condition `json:"conditions"`
+}
+
+type segmentResponse struct {
+	ID          int32               `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	UserKeys    []string            `json:"user_keys"`
+	Conditions  []feature.Condition `json:"conditions"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+type segmentUsageResponse struct {
+	FeatureID int32  `json:"feature_id"`
+	Feature   string `json:"feature"`
+}
+
+func buildSegmentFromRequest(req *segmentRequest) *feature.Segment {
+	return &feature.Segment{
+		Name:        req.Name,
+		Description: req.Description,
+		UserKeys:    req.UserKeys,
+		Conditions:  req.Conditions,
+	}
+}
+
+func mapSegmentResponse(segment *feature.Segment) segmentResponse {
+	resp := segmentResponse{
+		ID:          segment.ID,
+		Name:        segment.Name,
+		Description: segment.Description,
+		UserKeys:    mapTagsResponse(segment.UserKeys),
+		Conditions:  segment.Conditions,
+		CreatedAt:   segment.CreatedAt,
+		UpdatedAt:   segment.UpdatedAt,
+	}
+	if resp.Conditions == nil {
+		resp.Conditions = []feature.Condition{}
+	}
+
+	return resp
+}
+
+func mapSegmentsResponse(segments []*feature.Segment) []segmentResponse {
+	resp := make([]segmentResponse, len(segments))
+	for i, segment := range segments {
+		resp[i] = mapSegmentResponse(segment)
+	}
+
+	return resp
+}
+
+func mapSegmentUsageResponse(usage []feature.SegmentUsage) []segmentUsageResponse {
+	resp := make([]segmentUsageResponse, len(usage))
+	for i, u := range usage {
+		resp[i] = segmentUsageResponse{FeatureID: u.FeatureID, Feature: u.Feature}
+	}
+
+	return resp
+}
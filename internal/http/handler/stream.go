@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+const (
+	streamModeFull  = "full"
+	streamModeDelta = "delta"
+
+	streamEventSnapshot = "snapshot"
+)
+
+type Stream struct {
+	logger     *slog.Logger
+	featureSvc *feature.Service
+	heartbeat  time.Duration
+	done       chan struct{}
+	closeOnce  sync.Once
+}
+
+func NewStreamHandler(
+	logger *slog.Logger,
+	featureSvc *feature.Service,
+	heartbeat time.Duration,
+) *Stream {
+	if heartbeat <= 0 {
+		heartbeat = 15 * time.Second
+	}
+
+	return &Stream{
+		logger:     logger,
+		featureSvc: featureSvc,
+		heartbeat:  heartbeat,
+		done:       make(chan struct{}),
+	}
+}
+
+// Close ends all open streams. Streams never finish on their own, so this
+// has to run before the server waits for in-flight requests on shutdown.
+func (s *Stream) Close() {
+	s.closeOnce.Do(func() { close(s.done) })
+}
+
+// StreamFeatures holds a server-sent events connection and pushes feature
+// changes. Query parameters:
+//
+//	mode=delta|full  send only the changed feature (default) or the whole catalog
+//	keys=a,b         only stream features with these names
+//
+// Event ids are the sequence numbers of the recorded changes, which all
+// replicas share. Every connection starts with a snapshot unless the changes
+// after Last-Event-ID are still recorded, in which case they are replayed.
+func (s *Stream) StreamFeatures(w http.ResponseWriter, r *http.Request) {
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = streamModeDelta
+	}
+
+	if mode != streamModeDelta && mode != streamModeFull {
+		Error(w, http.StatusBadRequest, "invalid stream mode", mode)
+		return
+	}
+
+	var keys []string
+	if rawKeys := r.URL.Query().Get("keys"); rawKeys != "" {
+		keys = strings.Split(rawKeys, ",")
+	}
+
+	var lastSeq uint64
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			Error(w, http.StatusBadRequest, "invalid Last-Event-ID", lastEventID)
+			return
+		}
+		lastSeq = parsed
+	}
+
+	sub, err := s.featureSvc.SubscribeChanges(r.Context(), lastSeq)
+	if err != nil {
+		s.logger.Error("failed to subscribe to feature changes", "error", err)
+		Error(w, http.StatusInternalServerError, "unexpected error")
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if sub.Complete {
+		for _, change := range sub.Backlog {
+			if err := s.writeChange(w, r, mode, keys, change); err != nil {
+				return
+			}
+		}
+	} else if err := s.writeSnapshot(w, r, keys, sub.Seq); err != nil {
+		return
+	}
+
+	if err := rc.Flush(); err != nil {
+		s.logger.Error("streaming not supported", "error", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(s.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.done:
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case change, ok := <-sub.C:
+			if !ok {
+				// the subscriber fell behind; the client reconnects and resumes
+				return
+			}
+
+			if err := s.writeChange(w, r, mode, keys, change); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Stream) writeChange(w http.ResponseWriter, r *http.Request, mode string, keys []string, change feature.Change) error {
+	if !matchesKeys(change.Feature, keys) {
+		return nil
+	}
+
+	if mode == streamModeFull {
+		return s.writeSnapshot(w, r, keys, change.Seq)
+	}
+
+	return writeEvent(w, change.Seq, "feature."+string(change.Type), mapFeatureChangeResponse(change))
+}
+
+func (s *Stream) writeSnapshot(w http.ResponseWriter, r *http.Request, keys []string, seq uint64) error {
+	features, err := s.featureSvc.Catalog(r.Context(), seq)
+	if err != nil {
+		s.logger.Error("failed to list features for stream snapshot", "error", err)
+		return err
+	}
+
+	snapshot := make([]featureResponse, 0, len(features))
+	for _, f := range features {
+		if matchesKeys(f, keys) {
+			snapshot = append(snapshot, mapFeatureResponse(f))
+		}
+	}
+
+	return writeEvent(w, seq, streamEventSnapshot, snapshot)
+}
+
+func writeEvent(w http.ResponseWriter, id uint64, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", id, event, payload)
+	return err
+}
+
+func matchesKeys(f *feature.Feature, keys []string) bool {
+	return len(keys) == 0 || (f != nil && slices.Contains(keys, f.Name))
+}
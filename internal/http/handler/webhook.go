@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func (f *Feature) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := f.featureSvc.ListWebhooks(r.Context())
+	if err != nil {
+		f.respondError(w, err, "failed to list webhooks")
+		return
+	}
+
+	Ok(w, mapWebhooksResponse(webhooks))
+}
+
+func (f *Feature) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := f.featureSvc.GetWebhook(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to get webhook by id %d", id))
+		return
+	}
+
+	Ok(w, mapWebhookResponse(webhook))
+}
+
+// CreateWebhook is the only response revealing the secret of a webhook, which
+// is generated if the request has none.
+func (f *Feature) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	webhook := buildWebhookFromRequest(req)
+	if err := f.featureSvc.CreateWebhook(r.Context(), webhook); err != nil {
+		f.respondError(w, err, "failed to create webhook")
+		return
+	}
+
+	resp := mapWebhookResponse(webhook)
+	resp.Secret = webhook.Secret
+
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// UpdateWebhook replaces a webhook. The secret is kept if the request has
+// none.
+func (f *Feature) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	req, ok := decodeWebhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	webhook := buildWebhookFromRequest(req)
+	webhook.ID = id
+	if err := f.featureSvc.UpdateWebhook(r.Context(), webhook); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to update webhook %d", id))
+		return
+	}
+
+	Ok(w, mapWebhookResponse(webhook))
+}
+
+func (f *Feature) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := f.featureSvc.DeleteWebhook(r.Context(), id); err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to delete webhook %d", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *Feature) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(r.URL.Query(), 0)
+	if err != nil {
+		queryError(w, err)
+		return
+	}
+
+	deliveries, err := f.featureSvc.ListWebhookDeliveries(r.Context(), id, limit)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to list deliveries of webhook %d", id))
+		return
+	}
+
+	Ok(w, mapWebhookDeliveriesResponse(deliveries))
+}
+
+// TestWebhook sends a ping to a webhook and answers with the delivery,
+// whether or not the webhook accepted it.
+func (f *Feature) TestWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseWebhookID(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := f.featureSvc.TestWebhook(r.Context(), id)
+	if err != nil {
+		f.respondError(w, err, fmt.Sprintf("failed to test webhook %d", id))
+		return
+	}
+
+	Ok(w, mapWebhookDeliveryResponse(delivery))
+}
+
+func parseWebhookID(w http.ResponseWriter, r *http.Request) (int32, bool) {
+	webhookIDValue := r.PathValue("webhookID")
+	if webhookIDValue == "" {
+		Error(w, http.StatusBadRequest, "missing webhook id")
+		return 0, false
+	}
+
+	id, err := strconv.ParseInt(webhookIDValue, 10, 32)
+	if err != nil || id <= 0 {
+		Error(w, http.StatusBadRequest, "invalid webhook id", webhookIDValue)
+		return 0, false
+	}
+
+	return int32(id), true
+}
+
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request) (*webhookRequest, bool) {
+	defer r.Body.Close() // nolint: errcheck
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		Error(w, http.StatusBadRequest, "invalid webhook payload")
+		return nil, false
+	}
+
+	return &req, true
+}
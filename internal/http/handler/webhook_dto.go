@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/eve-an/splitter/internal/feature"
+)
+
+type webhookRequest struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+	// Active defaults to true.
+	Active *bool `json:"active"`
+}
+
+type webhookResponse struct {
+	ID        int32     `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type webhookDeliveryResponse struct {
+	ID             int64           `json:"id"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	Error          string          `json:"error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	CompletedAt    *time.Time      `json:"completed_at,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+}
+
+func buildWebhookFromRequest(req *webhookRequest) *feature.Webhook {
+	webhook := &feature.Webhook{
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: make([]feature.WebhookEvent, len(req.Events)),
+		Active: req.Active == nil || *req.Active,
+	}
+	for i, event := range req.Events {
+		webhook.Events[i] = feature.WebhookEvent(event)
+	}
+
+	return webhook
+}
+
+func mapWebhookResponse(webhook *feature.Webhook) webhookResponse {
+	resp := webhookResponse{
+		ID:        webhook.ID,
+		URL:       webhook.URL,
+		Events:    make([]string, len(webhook.Events)),
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+	for i, event := range webhook.Events {
+		resp.Events[i] = string(event)
+	}
+
+	return resp
+}
+
+func mapWebhooksResponse(webhooks []*feature.Webhook) []webhookResponse {
+	resp := make([]webhookResponse, len(webhooks))
+	for i, webhook := range webhooks {
+		resp[i] = mapWebhookResponse(webhook)
+	}
+
+	return resp
+}
+
+func mapWebhookDeliveryResponse(delivery *feature.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:             delivery.ID,
+		Event:          string(delivery.Event),
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		ResponseStatus: delivery.ResponseStatus,
+		Error:          delivery.Error,
+		CreatedAt:      delivery.CreatedAt,
+		Payload:        delivery.Payload,
+	}
+	if delivery.Status == feature.WebhookDeliveryPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	if !delivery.CompletedAt.IsZero() {
+		resp.CompletedAt = &delivery.CompletedAt
+	}
+
+	return resp
+}
+
+func mapWebhookDeliveriesResponse(deliveries []*feature.WebhookDelivery) []webhookDeliveryResponse {
+	resp := make([]webhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		resp[i] = mapWebhookDeliveryResponse(delivery)
+	}
+
+	return resp
+}
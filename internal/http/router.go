@@ -1,6 +1,7 @@
 package http
 
 import (
+	"expvar"
 	"log/slog"
 	"net/http"
 
@@ -12,6 +13,7 @@ import (
 func NewRouter(
 	logger *slog.Logger,
 	featureHandler *handler.Feature,
+	streamHandler *handler.Stream,
 	sessionSvc *session.Service,
 	authConfig config.Auth,
 ) http.Handler {
@@ -22,8 +24,46 @@ func NewRouter(
 	mux.HandleFunc("DELETE /api/v1/features/{featureID}", featureHandler.DeleteFeature)
 	mux.HandleFunc("POST /api/v1/features", featureHandler.CreateFeature)
 	mux.HandleFunc("PUT /api/v1/features/{featureID}", featureHandler.UpdateFeature)
+	mux.HandleFunc("POST /api/v1/features/{featureID}/evaluate", featureHandler.EvaluateFeature)
+	mux.HandleFunc("POST /api/v1/features/{featureID}/explain", featureHandler.ExplainEvaluation)
 	mux.HandleFunc("GET /api/v1/features/{featureID}/events", featureHandler.ListFeatureEvents)
 	mux.HandleFunc("POST /api/v1/features/{featureID}/events", featureHandler.RecordFeatureEvent)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/results", featureHandler.GetFeatureResults)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/reallocations", featureHandler.ListReallocations)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/metrics", featureHandler.ListFeatureMetrics)
+	mux.HandleFunc("PUT /api/v1/features/{featureID}/metrics/{metricID}", featureHandler.AttachMetric)
+	mux.HandleFunc("DELETE /api/v1/features/{featureID}/metrics/{metricID}", featureHandler.DetachMetric)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/guardrails", featureHandler.ListGuardrails)
+	mux.HandleFunc("PUT /api/v1/features/{featureID}/guardrails/{metricID}", featureHandler.SetGuardrail)
+	mux.HandleFunc("DELETE /api/v1/features/{featureID}/guardrails/{metricID}", featureHandler.RemoveGuardrail)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/audit", featureHandler.ListAuditEntries)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/overrides", featureHandler.ListOverrides)
+	mux.HandleFunc("PUT /api/v1/features/{featureID}/overrides/{userKey}", featureHandler.SetOverride)
+	mux.HandleFunc("DELETE /api/v1/features/{featureID}/overrides/{userKey}", featureHandler.RemoveOverride)
+	mux.HandleFunc("GET /api/v1/features/{featureID}/schedules", featureHandler.ListSchedules)
+	mux.HandleFunc("POST /api/v1/features/{featureID}/schedules", featureHandler.CreateSchedule)
+	mux.HandleFunc("DELETE /api/v1/features/{featureID}/schedules/{scheduleID}", featureHandler.CancelSchedule)
+	mux.HandleFunc("POST /api/v1/events:batch", featureHandler.RecordEventsBatch)
+	mux.HandleFunc("GET /api/v1/metrics", featureHandler.ListMetrics)
+	mux.HandleFunc("POST /api/v1/metrics", featureHandler.CreateMetric)
+	mux.HandleFunc("GET /api/v1/metrics/{metricID}", featureHandler.GetMetric)
+	mux.HandleFunc("PUT /api/v1/metrics/{metricID}", featureHandler.UpdateMetric)
+	mux.HandleFunc("DELETE /api/v1/metrics/{metricID}", featureHandler.DeleteMetric)
+	mux.HandleFunc("GET /api/v1/segments", featureHandler.ListSegments)
+	mux.HandleFunc("POST /api/v1/segments", featureHandler.CreateSegment)
+	mux.HandleFunc("GET /api/v1/segments/{segmentID}", featureHandler.GetSegment)
+	mux.HandleFunc("PUT /api/v1/segments/{segmentID}", featureHandler.UpdateSegment)
+	mux.HandleFunc("DELETE /api/v1/segments/{segmentID}", featureHandler.DeleteSegment)
+	mux.HandleFunc("GET /api/v1/segments/{segmentID}/features", featureHandler.ListSegmentUsage)
+	mux.HandleFunc("GET /api/v1/webhooks", featureHandler.ListWebhooks)
+	mux.HandleFunc("POST /api/v1/webhooks", featureHandler.CreateWebhook)
+	mux.HandleFunc("GET /api/v1/webhooks/{webhookID}", featureHandler.GetWebhook)
+	mux.HandleFunc("PUT /api/v1/webhooks/{webhookID}", featureHandler.UpdateWebhook)
+	mux.HandleFunc("DELETE /api/v1/webhooks/{webhookID}", featureHandler.DeleteWebhook)
+	mux.HandleFunc("GET /api/v1/webhooks/{webhookID}/deliveries", featureHandler.ListWebhookDeliveries)
+	mux.HandleFunc("POST /api/v1/webhooks/{webhookID}/test", featureHandler.TestWebhook)
+	mux.HandleFunc("GET /api/v1/stream", streamHandler.StreamFeatures)
+	mux.Handle("GET /debug/vars", expvar.Handler())
 
 	return chain(mux,
 		recoveryMiddleware(logger), // runs first
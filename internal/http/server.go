@@ -32,6 +32,12 @@ func (s *Server) Start() {
 	}
 }
 
+// RegisterOnShutdown registers a function to call when Shutdown starts, e.g.
+// to end long-lived connections that would otherwise block it.
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.server.RegisterOnShutdown(f)
+}
+
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
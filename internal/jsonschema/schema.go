@@ -0,0 +1,361 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema needed to describe feature values: type, enum, const, the bounds of
+// numbers, strings and arrays, pattern, items, properties, required and
+// additionalProperties. Other keywords are ignored, as JSON Schema ignores
+// unknown ones.
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidSchema = errors.New("invalid json schema")
+	ErrMismatch      = errors.New("value does not match the json schema")
+)
+
+var types = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Schema is a parsed JSON schema. The zero value accepts every document.
+type Schema struct {
+	// reject is set by the schema false.
+	reject bool
+
+	types    []string
+	enum     []any
+	hasConst bool
+	constant any
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minItems *int
+	maxItems *int
+	items    *Schema
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+}
+
+// Parse reads a JSON schema, which is an object or a boolean.
+func Parse(data []byte) (*Schema, error) {
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSchema, err)
+	}
+
+	schema, err := parse(raw, "$")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSchema, err)
+	}
+
+	return schema, nil
+}
+
+func parse(raw any, path string) (*Schema, error) {
+	switch raw := raw.(type) {
+	case bool:
+		return &Schema{reject: !raw}, nil
+	case map[string]any:
+		return parseObject(raw, path)
+	default:
+		return nil, fmt.Errorf("%s: schema must be an object or a boolean", path)
+	}
+}
+
+func parseObject(raw map[string]any, path string) (*Schema, error) {
+	schema := &Schema{}
+	var errs []error
+
+	if t, ok := raw["type"]; ok {
+		switch t := t.(type) {
+		case string:
+			schema.types = []string{t}
+		case []any:
+			for _, name := range t {
+				if name, ok := name.(string); ok {
+					schema.types = append(schema.types, name)
+				} else {
+					errs = append(errs, fmt.Errorf("%s: type names must be strings", path))
+				}
+			}
+		default:
+			errs = append(errs, fmt.Errorf("%s: type must be a string or an array", path))
+		}
+		for _, name := range schema.types {
+			if !slices.Contains(types, name) {
+				errs = append(errs, fmt.Errorf("%s: unknown type %q", path, name))
+			}
+		}
+	}
+
+	if enum, ok := raw["enum"]; ok {
+		if values, ok := enum.([]any); ok {
+			schema.enum = values
+		} else {
+			errs = append(errs, fmt.Errorf("%s: enum must be an array", path))
+		}
+	}
+
+	if constant, ok := raw["const"]; ok {
+		schema.hasConst = true
+		schema.constant = constant
+	}
+
+	for keyword, target := range map[string]**float64{
+		"minimum":          &schema.minimum,
+		"maximum":          &schema.maximum,
+		"exclusiveMinimum": &schema.exclusiveMinimum,
+		"exclusiveMaximum": &schema.exclusiveMaximum,
+	} {
+		if value, ok := raw[keyword]; ok {
+			if number, ok := value.(float64); ok {
+				*target = &number
+			} else {
+				errs = append(errs, fmt.Errorf("%s: %s must be a number", path, keyword))
+			}
+		}
+	}
+
+	for keyword, target := range map[string]**int{
+		"minLength": &schema.minLength,
+		"maxLength": &schema.maxLength,
+		"minItems":  &schema.minItems,
+		"maxItems":  &schema.maxItems,
+	} {
+		if value, ok := raw[keyword]; ok {
+			if number, ok := value.(float64); ok && number >= 0 && number == math.Trunc(number) {
+				count := int(number)
+				*target = &count
+			} else {
+				errs = append(errs, fmt.Errorf("%s: %s must be a non-negative integer", path, keyword))
+			}
+		}
+	}
+
+	if pattern, ok := raw["pattern"]; ok {
+		expr, isString := pattern.(string)
+		compiled, err := regexp.Compile(expr)
+		if !isString || err != nil {
+			errs = append(errs, fmt.Errorf("%s: pattern must be a regular expression", path))
+		}
+		schema.pattern = compiled
+	}
+
+	if items, ok := raw["items"]; ok {
+		var err error
+		if schema.items, err = parse(items, path+"[]"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if properties, ok := raw["properties"]; ok {
+		if properties, ok := properties.(map[string]any); ok {
+			schema.properties = make(map[string]*Schema, len(properties))
+			for name, property := range properties {
+				parsed, err := parse(property, path+"."+name)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				schema.properties[name] = parsed
+			}
+		} else {
+			errs = append(errs, fmt.Errorf("%s: properties must be an object", path))
+		}
+	}
+
+	if required, ok := raw["required"]; ok {
+		names, isArray := required.([]any)
+		if !isArray {
+			errs = append(errs, fmt.Errorf("%s: required must be an array", path))
+		}
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				schema.required = append(schema.required, name)
+			} else {
+				errs = append(errs, fmt.Errorf("%s: required property names must be strings", path))
+			}
+		}
+	}
+
+	if additional, ok := raw["additionalProperties"]; ok {
+		var err error
+		if schema.additionalProperties, err = parse(additional, path+".*"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return schema, nil
+}
+
+// Validate reports every part of the JSON document data that does not
+// match the schema. The errors wrap ErrMismatch.
+func (s *Schema) Validate(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("%w: %w", ErrMismatch, err)
+	}
+
+	if errs := s.validate(value, "$"); len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrMismatch, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (s *Schema) validate(value any, path string) []error {
+	if s.reject {
+		return []error{fmt.Errorf("%s: not allowed", path)}
+	}
+
+	var errs []error
+	if len(s.types) > 0 && !slices.ContainsFunc(s.types, func(t string) bool { return hasType(value, t) }) {
+		// the other keywords are meaningless for values of the wrong type
+		return []error{fmt.Errorf("%s: expected %s", path, typeList(s.types))}
+	}
+
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(allowed any) bool { return reflect.DeepEqual(value, allowed) }) {
+		errs = append(errs, fmt.Errorf("%s: not one of the allowed values", path))
+	}
+	if s.hasConst && !reflect.DeepEqual(value, s.constant) {
+		errs = append(errs, fmt.Errorf("%s: not the required value", path))
+	}
+
+	switch value := value.(type) {
+	case float64:
+		errs = append(errs, s.validateNumber(value, path)...)
+	case string:
+		errs = append(errs, s.validateString(value, path)...)
+	case []any:
+		errs = append(errs, s.validateArray(value, path)...)
+	case map[string]any:
+		errs = append(errs, s.validateObject(value, path)...)
+	}
+
+	return errs
+}
+
+func (s *Schema) validateNumber(value float64, path string) []error {
+	var errs []error
+	if s.minimum != nil && value < *s.minimum {
+		errs = append(errs, fmt.Errorf("%s: must be at least %v", path, *s.minimum))
+	}
+	if s.maximum != nil && value > *s.maximum {
+		errs = append(errs, fmt.Errorf("%s: must be at most %v", path, *s.maximum))
+	}
+	if s.exclusiveMinimum != nil && value <= *s.exclusiveMinimum {
+		errs = append(errs, fmt.Errorf("%s: must be greater than %v", path, *s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && value >= *s.exclusiveMaximum {
+		errs = append(errs, fmt.Errorf("%s: must be less than %v", path, *s.exclusiveMaximum))
+	}
+
+	return errs
+}
+
+func (s *Schema) validateString(value string, path string) []error {
+	var errs []error
+	length := utf8.RuneCountInString(value)
+	if s.minLength != nil && length < *s.minLength {
+		errs = append(errs, fmt.Errorf("%s: must be at least %d characters long", path, *s.minLength))
+	}
+	if s.maxLength != nil && length > *s.maxLength {
+		errs = append(errs, fmt.Errorf("%s: must be at most %d characters long", path, *s.maxLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(value) {
+		errs = append(errs, fmt.Errorf("%s: must match %q", path, s.pattern.String()))
+	}
+
+	return errs
+}
+
+func (s *Schema) validateArray(value []any, path string) []error {
+	var errs []error
+	if s.minItems != nil && len(value) < *s.minItems {
+		errs = append(errs, fmt.Errorf("%s: must have at least %d items", path, *s.minItems))
+	}
+	if s.maxItems != nil && len(value) > *s.maxItems {
+		errs = append(errs, fmt.Errorf("%s: must have at most %d items", path, *s.maxItems))
+	}
+	if s.items != nil {
+		for i, item := range value {
+			errs = append(errs, s.items.validate(item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	}
+
+	return errs
+}
+
+func (s *Schema) validateObject(value map[string]any, path string) []error {
+	var errs []error
+	for _, name := range s.required {
+		if _, ok := value[name]; !ok {
+			errs = append(errs, fmt.Errorf("%s: missing property %q", path, name))
+		}
+	}
+
+	// sorted, so that the errors come in a stable order
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, defined := s.properties[name]
+		if !defined {
+			property = s.additionalProperties
+		}
+		if property != nil {
+			errs = append(errs, property.validate(value[name], path+"."+name)...)
+		}
+	}
+
+	return errs
+}
+
+func hasType(value any, name string) bool {
+	switch value := value.(type) {
+	case nil:
+		return name == "null"
+	case bool:
+		return name == "boolean"
+	case map[string]any:
+		return name == "object"
+	case []any:
+		return name == "array"
+	case string:
+		return name == "string"
+	case float64:
+		return name == "number" || (name == "integer" && value == math.Trunc(value))
+	default:
+		return false
+	}
+}
+
+func typeList(names []string) string {
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return fmt.Sprintf("one of %v", names)
+}
@@ -0,0 +1,379 @@
+// Package spool implements a durable on-disk FIFO queue of opaque records.
+//
+// Records are appended to a single log file as
+//
+//	[4 byte length][4 byte crc32][payload]
+//
+// and consumed from the head. The head position is stored in a separate
+// offset file that is replaced atomically. Once every record has been
+// consumed, both files are reset so the log does not grow without bound.
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	logFileName    = "spool.log"
+	offsetFileName = "spool.offset"
+	headerSize     = 8
+	maxRecordSize  = 16 << 20
+)
+
+var (
+	ErrClosed = errors.New("spool: closed")
+	// ErrCorrupt is returned by Peek when the record at the head cannot be
+	// read. Skip removes it.
+	ErrCorrupt = errors.New("spool: corrupt record")
+)
+
+type Spool struct {
+	mu     sync.Mutex
+	dir    string
+	log    *os.File
+	head   int64
+	tail   int64
+	depth  int
+	closed bool
+}
+
+// Batch is a run of records read from the head of the spool. It is removed
+// from the spool by Commit.
+type Batch struct {
+	Records [][]byte
+	// ends holds the log position after each record.
+	ends []int64
+	end  int64
+}
+
+// Prefix returns the batch of the first n records, so that a partially
+// processed batch can be committed.
+func (b *Batch) Prefix(n int) *Batch {
+	if n >= len(b.Records) {
+		return b
+	}
+	if n <= 0 {
+		return &Batch{}
+	}
+
+	return &Batch{Records: b.Records[:n], ends: b.ends[:n], end: b.ends[n-1]}
+}
+
+// Open opens or creates the spool in dir. A record that was only partially
+// written before a crash is discarded.
+func Open(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("spool: create dir: %w", err)
+	}
+
+	log, err := os.OpenFile(filepath.Join(dir, logFileName), os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("spool: open log: %w", err)
+	}
+
+	s := &Spool{dir: dir, log: log}
+
+	if s.head, err = readOffset(filepath.Join(dir, offsetFileName)); err != nil {
+		_ = log.Close()
+		return nil, err
+	}
+
+	if err := s.recover(); err != nil {
+		_ = log.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// recover counts the records after head and cuts off a torn tail.
+func (s *Spool) recover() error {
+	info, err := s.log.Stat()
+	if err != nil {
+		return fmt.Errorf("spool: stat log: %w", err)
+	}
+
+	if s.head > info.Size() {
+		s.head = 0
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.log, s.head, info.Size()-s.head))
+	pos := s.head
+	for {
+		n, err := readRecord(r, nil)
+		if err != nil {
+			break
+		}
+		pos += n
+		s.depth++
+	}
+
+	if pos < info.Size() {
+		if err := s.log.Truncate(pos); err != nil {
+			return fmt.Errorf("spool: truncate torn record: %w", err)
+		}
+	}
+
+	s.tail = pos
+
+	return nil
+}
+
+// Append durably adds records to the tail of the spool.
+func (s *Spool) Append(records ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	var size int
+	for _, record := range records {
+		if len(record) > maxRecordSize {
+			return fmt.Errorf("spool: record of %d bytes exceeds limit", len(record))
+		}
+		size += headerSize + len(record)
+	}
+
+	buf := make([]byte, 0, size)
+	for _, record := range records {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(record)))
+		buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(record))
+		buf = append(buf, record...)
+	}
+
+	if _, err := s.log.WriteAt(buf, s.tail); err != nil {
+		return fmt.Errorf("spool: write: %w", err)
+	}
+
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("spool: sync: %w", err)
+	}
+
+	s.tail += int64(len(buf))
+	s.depth += len(records)
+
+	return nil
+}
+
+// Peek reads up to n records from the head without removing them. It stops
+// before a corrupt record and fails with ErrCorrupt if the head is one.
+func (s *Spool) Peek(n int) (*Batch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+
+	batch := &Batch{end: s.head}
+	r := bufio.NewReader(io.NewSectionReader(s.log, s.head, s.tail-s.head))
+	for len(batch.Records) < n && batch.end < s.tail {
+		var record []byte
+		size, err := readRecord(r, &record)
+		if err != nil && len(batch.Records) > 0 && isCorrupt(err) {
+			// hand out the records before it; the next Peek reports it
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("spool: read: %w", err)
+		}
+
+		batch.Records = append(batch.Records, record)
+		batch.end += size
+		batch.ends = append(batch.ends, batch.end)
+	}
+
+	return batch, nil
+}
+
+// Commit removes a batch previously returned by Peek. Batches must be
+// committed in the order they were peeked.
+func (s *Spool) Commit(batch *Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if batch.end <= s.head {
+		return nil
+	}
+
+	return s.advance(batch.end, len(batch.Records))
+}
+
+// Skip removes the corrupt record at the head and returns the number of
+// records removed. If the length of the record is unreadable, the position
+// of the next one is unknown and every remaining record is removed.
+func (s *Spool) Skip() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return 0, ErrClosed
+	}
+
+	if s.head == s.tail {
+		return 0, nil
+	}
+
+	r := bufio.NewReader(io.NewSectionReader(s.log, s.head, s.tail-s.head))
+	size, err := readRecord(r, nil)
+	switch {
+	case err == nil:
+		// not corrupt after all; leave it to Peek
+		return 0, nil
+	case size > 0:
+		return 1, s.advance(s.head+size, 1)
+	case isCorrupt(err):
+		records := s.depth
+		return records, s.advance(s.tail, records)
+	default:
+		return 0, fmt.Errorf("spool: read: %w", err)
+	}
+}
+
+// advance moves the head to the given position, past the given number of
+// records.
+func (s *Spool) advance(head int64, records int) error {
+	if head == s.tail {
+		if err := s.log.Truncate(0); err != nil {
+			return fmt.Errorf("spool: reset log: %w", err)
+		}
+		head, s.tail = 0, 0
+	}
+
+	if err := writeOffset(filepath.Join(s.dir, offsetFileName), head); err != nil {
+		return err
+	}
+
+	s.head = head
+	s.depth -= records
+
+	return nil
+}
+
+// Depth returns the number of records waiting in the spool.
+func (s *Spool) Depth() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.depth
+}
+
+// Size returns the number of bytes waiting in the spool.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tail - s.head
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+
+	s.closed = true
+	return s.log.Close()
+}
+
+// readRecord reads one record and returns its size on disk. The payload is
+// only copied out when record is non-nil. A record failing its checksum is
+// reported with ErrCorrupt together with its size.
+func readRecord(r *bufio.Reader, record *[]byte) (int64, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+
+	length := binary.BigEndian.Uint32(header[:4])
+	checksum := binary.BigEndian.Uint32(header[4:])
+	if length > maxRecordSize {
+		return 0, fmt.Errorf("%w: length %d", ErrCorrupt, length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, err
+	}
+
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return int64(headerSize + length), fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+
+	if record != nil {
+		*record = payload
+	}
+
+	return int64(headerSize + length), nil
+}
+
+// isCorrupt reports whether a record could not be read because of its
+// contents. Records are never cut short before the tail, so a short read
+// means a corrupt length as well.
+func isCorrupt(err error) bool {
+	return errors.Is(err, ErrCorrupt) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+func readOffset(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("spool: read offset: %w", err)
+	}
+
+	if len(data) != 8 {
+		return 0, errors.New("spool: corrupt offset file")
+	}
+
+	return int64(binary.BigEndian.Uint64(data)), nil
+}
+
+func writeOffset(path string, offset int64) error {
+	tmp := path + ".tmp"
+
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], uint64(offset))
+
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("spool: write offset: %w", err)
+	}
+
+	if _, err := f.Write(data[:]); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("spool: write offset: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("spool: sync offset: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("spool: close offset: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("spool: replace offset: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,198 @@
+package stats
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// integrationSteps is the number of Simpson intervals used to integrate
+// over a posterior. It must be even.
+const integrationSteps = 2000
+
+// BetaDistribution is the posterior of a conversion rate.
+type BetaDistribution struct {
+	Alpha float64
+	Beta  float64
+}
+
+// BetaPosterior returns the posterior of a conversion rate after successes
+// out of trials, starting from a uniform prior.
+func BetaPosterior(successes, trials int64) BetaDistribution {
+	return BetaDistribution{
+		Alpha: 1 + float64(successes),
+		Beta:  1 + float64(trials-successes),
+	}
+}
+
+func (d BetaDistribution) Mean() float64 {
+	return d.Alpha / (d.Alpha + d.Beta)
+}
+
+func (d BetaDistribution) Variance() float64 {
+	sum := d.Alpha + d.Beta
+	return d.Alpha * d.Beta / (sum * sum * (sum + 1))
+}
+
+// CDF returns the probability of the rate being at most x.
+func (d BetaDistribution) CDF(x float64) float64 {
+	return regularizedIncompleteBeta(d.Alpha, d.Beta, x)
+}
+
+// Quantile returns the rate the distribution does not exceed with
+// probability p.
+func (d BetaDistribution) Quantile(p float64) float64 {
+	lo, hi := 0.0, 1.0
+	for range 100 {
+		mid := (lo + hi) / 2
+		if d.CDF(mid) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2
+}
+
+// CredibleInterval returns the equal-tailed interval holding the rate with
+// probability ConfidenceLevel.
+func (d BetaDistribution) CredibleInterval() (lower, upper float64) {
+	tail := (1 - ConfidenceLevel) / 2
+	return d.Quantile(tail), d.Quantile(1 - tail)
+}
+
+// Sample draws a rate from the distribution.
+func (d BetaDistribution) Sample(r *rand.Rand) float64 {
+	x := sampleGamma(r, d.Alpha)
+	y := sampleGamma(r, d.Beta)
+
+	return x / (x + y)
+}
+
+// ProbabilityBest estimates for every distribution the probability of its
+// rate being the highest, from draws joint samples. This is the allocation
+// of Thompson sampling.
+func ProbabilityBest(r *rand.Rand, dists []BetaDistribution, draws int) []float64 {
+	wins := make([]float64, len(dists))
+	if len(dists) == 0 || draws <= 0 {
+		return wins
+	}
+
+	for range draws {
+		best, bestRate := 0, -1.0
+		for i, d := range dists {
+			if rate := d.Sample(r); rate > bestRate {
+				best, bestRate = i, rate
+			}
+		}
+		wins[best]++
+	}
+
+	for i := range wins {
+		wins[i] /= float64(draws)
+	}
+
+	return wins
+}
+
+// sampleGamma draws from a gamma distribution with unit scale using the
+// method of Marsaglia and Tsang.
+func sampleGamma(r *rand.Rand, shape float64) float64 {
+	if shape < 1 {
+		// boost the shape and scale the draw back down
+		return sampleGamma(r, shape+1) * math.Pow(r.Float64(), 1/shape)
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := r.NormFloat64()
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+
+		v = v * v * v
+		if math.Log(r.Float64()) < x*x/2+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
+
+func (d BetaDistribution) pdf(x float64) float64 {
+	if x <= 0 || x >= 1 {
+		return 0
+	}
+
+	lga, _ := math.Lgamma(d.Alpha)
+	lgb, _ := math.Lgamma(d.Beta)
+	lgab, _ := math.Lgamma(d.Alpha + d.Beta)
+
+	return math.Exp(lgab - lga - lgb + (d.Alpha-1)*math.Log(x) + (d.Beta-1)*math.Log1p(-x))
+}
+
+// BayesianComparison compares the posterior of a treatment with that of a
+// control. ExpectedLoss is the expected shortfall of the treatment's rate
+// if it is chosen, E[max(control - treatment, 0)].
+type BayesianComparison struct {
+	ProbabilityToBeat float64
+	ExpectedLoss      float64
+}
+
+// CompareBetas computes the probability of the treatment's rate exceeding
+// the control's and the expected loss of choosing the treatment. Both are
+// integrals over the narrower posterior, which would otherwise be missed by
+// the integration grid.
+func CompareBetas(control, treatment BetaDistribution) BayesianComparison {
+	if treatment.Variance() <= control.Variance() {
+		// P(T > C) = E_T[F_C(t)], E[(C-T)+] = E_T[E[(C-t)+]]
+		return BayesianComparison{
+			ProbabilityToBeat: integrate(treatment, control.CDF),
+			ExpectedLoss: integrate(treatment, func(t float64) float64 {
+				return upperPartialMean(control, t)
+			}),
+		}
+	}
+
+	// P(T > C) = 1 - E_C[F_T(c)], E[(C-T)+] = E_C[E[(c-T)+]]
+	return BayesianComparison{
+		ProbabilityToBeat: 1 - integrate(control, treatment.CDF),
+		ExpectedLoss: integrate(control, func(c float64) float64 {
+			return lowerPartialMean(treatment, c)
+		}),
+	}
+}
+
+// upperPartialMean returns E[max(X - x, 0)] of X drawn from d.
+func upperPartialMean(d BetaDistribution, x float64) float64 {
+	above := BetaDistribution{Alpha: d.Alpha + 1, Beta: d.Beta}
+	return d.Mean()*(1-above.CDF(x)) - x*(1-d.CDF(x))
+}
+
+// lowerPartialMean returns E[max(x - X, 0)] of X drawn from d.
+func lowerPartialMean(d BetaDistribution, x float64) float64 {
+	above := BetaDistribution{Alpha: d.Alpha + 1, Beta: d.Beta}
+	return x*d.CDF(x) - d.Mean()*above.CDF(x)
+}
+
+// integrate returns E[f(X)] of X drawn from d with Simpson's rule over the
+// range holding virtually all of its mass.
+func integrate(d BetaDistribution, f func(float64) float64) float64 {
+	spread := 12 * math.Sqrt(d.Variance())
+	lo := math.Max(0, d.Mean()-spread)
+	hi := math.Min(1, d.Mean()+spread)
+	h := (hi - lo) / integrationSteps
+
+	g := func(x float64) float64 { return d.pdf(x) * f(x) }
+
+	sum := g(lo) + g(hi)
+	for i := 1; i < integrationSteps; i++ {
+		weight := 2.0
+		if i%2 == 1 {
+			weight = 4
+		}
+		sum += weight * g(lo+float64(i)*h)
+	}
+
+	return math.Max(0, sum*h/3)
+}
@@ -0,0 +1,62 @@
+package stats
+
+// CovariateSample summarises a metric observed together with a covariate,
+// such as the same metric before the experiment, for every unit.
+type CovariateSample struct {
+	N                   int64
+	MetricSum           float64
+	MetricSumSquares    float64
+	CovariateSum        float64
+	CovariateSumSquares float64
+	CrossSum            float64
+}
+
+// CUPED holds the adjustment of controlled experiments using pre-experiment
+// data: every metric value y is replaced by y - Theta*(x - CovariateMean),
+// which keeps the means' differences unbiased while removing the variance
+// explained by the covariate x.
+type CUPED struct {
+	Theta         float64
+	CovariateMean float64
+}
+
+// NewCUPED estimates the adjustment from the pooled samples of all
+// variants. It returns false if the covariate has no variance.
+func NewCUPED(samples ...CovariateSample) (CUPED, bool) {
+	var pooled CovariateSample
+	for _, s := range samples {
+		pooled.N += s.N
+		pooled.MetricSum += s.MetricSum
+		pooled.CovariateSum += s.CovariateSum
+		pooled.CovariateSumSquares += s.CovariateSumSquares
+		pooled.CrossSum += s.CrossSum
+	}
+	if pooled.N < 2 {
+		return CUPED{}, false
+	}
+
+	n := float64(pooled.N)
+	covariance := pooled.CrossSum - pooled.CovariateSum*pooled.MetricSum/n
+	variance := pooled.CovariateSumSquares - pooled.CovariateSum*pooled.CovariateSum/n
+	if variance <= 0 {
+		return CUPED{}, false
+	}
+
+	return CUPED{Theta: covariance / variance, CovariateMean: pooled.CovariateSum / n}, true
+}
+
+// Adjust returns the adjusted metric values of s.
+func (c CUPED) Adjust(s CovariateSample) Sample {
+	// y' = y - theta*x + k, with k = theta*mean(x)
+	n := float64(s.N)
+	k := c.Theta * c.CovariateMean
+	sum := s.MetricSum - c.Theta*s.CovariateSum + n*k
+	sumSquares := s.MetricSumSquares +
+		c.Theta*c.Theta*s.CovariateSumSquares +
+		n*k*k -
+		2*c.Theta*s.CrossSum +
+		2*k*s.MetricSum -
+		2*c.Theta*k*s.CovariateSum
+
+	return SampleFromSums(s.N, sum, sumSquares)
+}
@@ -0,0 +1,338 @@
+// Package stats implements the statistical tests used to compare the
+// variants of an experiment.
+package stats
+
+import "math"
+
+// ConfidenceLevel is the level of all confidence intervals.
+const ConfidenceLevel = 0.95
+
+// Sample summarises a numeric sample.
+type Sample struct {
+	N        int64
+	Mean     float64
+	Variance float64
+}
+
+// SampleFromSums builds a Sample from the count, sum and sum of squares of
+// its observations.
+func SampleFromSums(n int64, sum, sumSquares float64) Sample {
+	if n == 0 {
+		return Sample{}
+	}
+
+	mean := sum / float64(n)
+	if n == 1 {
+		return Sample{N: n, Mean: mean}
+	}
+
+	// guard against a slightly negative result caused by rounding
+	variance := math.Max(0, (sumSquares-float64(n)*mean*mean)/float64(n-1))
+
+	return Sample{N: n, Mean: mean, Variance: variance}
+}
+
+// TestResult is the outcome of comparing a treatment with a control.
+// Difference is treatment minus control, Lower and Upper bound it at
+// ConfidenceLevel and PValue is two-sided.
+type TestResult struct {
+	Difference       float64
+	Lower            float64
+	Upper            float64
+	Statistic        float64
+	DegreesOfFreedom float64
+	PValue           float64
+}
+
+// WelchTTest compares the means of two samples without assuming equal
+// variances. It returns false if either sample has fewer than two
+// observations or both have no variance.
+func WelchTTest(control, treatment Sample) (TestResult, bool) {
+	if control.N < 2 || treatment.N < 2 {
+		return TestResult{}, false
+	}
+
+	vc := control.Variance / float64(control.N)
+	vt := treatment.Variance / float64(treatment.N)
+	if vc+vt == 0 {
+		return TestResult{}, false
+	}
+
+	stdErr := math.Sqrt(vc + vt)
+	diff := treatment.Mean - control.Mean
+	df := (vc + vt) * (vc + vt) /
+		(vc*vc/float64(control.N-1) + vt*vt/float64(treatment.N-1))
+	t := diff / stdErr
+	margin := studentTQuantile(1-ConfidenceLevel, df) * stdErr
+
+	return TestResult{
+		Difference:       diff,
+		Lower:            diff - margin,
+		Upper:            diff + margin,
+		Statistic:        t,
+		DegreesOfFreedom: df,
+		PValue:           studentTPValue(t, df),
+	}, true
+}
+
+// TwoProportionZTest compares the rates of successes out of trials. The
+// statistic uses the pooled rate, the interval the unpooled standard error.
+// It returns false if either group has no trials or the pooled rate is 0 or
+// 1.
+func TwoProportionZTest(controlSuccesses, controlTrials, treatmentSuccesses, treatmentTrials int64) (TestResult, bool) {
+	if controlTrials == 0 || treatmentTrials == 0 {
+		return TestResult{}, false
+	}
+
+	nc, nt := float64(controlTrials), float64(treatmentTrials)
+	pc, pt := float64(controlSuccesses)/nc, float64(treatmentSuccesses)/nt
+	pooled := float64(controlSuccesses+treatmentSuccesses) / (nc + nt)
+	if pooled == 0 || pooled == 1 {
+		return TestResult{}, false
+	}
+
+	diff := pt - pc
+	z := diff / math.Sqrt(pooled*(1-pooled)*(1/nc+1/nt))
+	margin := normalQuantile(1-ConfidenceLevel) * math.Sqrt(pc*(1-pc)/nc+pt*(1-pt)/nt)
+
+	return TestResult{
+		Difference: diff,
+		Lower:      diff - margin,
+		Upper:      diff + margin,
+		Statistic:  z,
+		PValue:     math.Erfc(math.Abs(z) / math.Sqrt2),
+	}, true
+}
+
+// MixtureSPRT is the mixture sequential probability ratio test of the
+// difference in means, using the normal approximation of both means. Its
+// p-value and interval stay valid however often the test is repeated while
+// data is collected. Statistic is the log of the likelihood ratio.
+//
+// The mixing distribution over the difference is normal with a standard
+// deviation of effectSize times the pooled standard deviation; the test is
+// most powerful for differences of about that size. It returns false if
+// either sample has fewer than two observations or both have no variance.
+func MixtureSPRT(control, treatment Sample, effectSize float64) (TestResult, bool) {
+	if control.N < 2 || treatment.N < 2 || effectSize <= 0 {
+		return TestResult{}, false
+	}
+
+	v := control.Variance/float64(control.N) + treatment.Variance/float64(treatment.N)
+	if v == 0 {
+		return TestResult{}, false
+	}
+
+	tau2 := effectSize * effectSize * (control.Variance + treatment.Variance) / 2
+	diff := treatment.Mean - control.Mean
+	logRatio := 0.5*math.Log(v/(v+tau2)) + tau2*diff*diff/(2*v*(v+tau2))
+	alpha := 1 - ConfidenceLevel
+	margin := math.Sqrt(v * (v + tau2) / tau2 * (math.Log((v+tau2)/v) - 2*math.Log(alpha)))
+
+	return TestResult{
+		Difference: diff,
+		Lower:      diff - margin,
+		Upper:      diff + margin,
+		Statistic:  logRatio,
+		PValue:     math.Min(1, math.Exp(-logRatio)),
+	}, true
+}
+
+// GoodnessOfFit is the outcome of testing observed counts against expected
+// proportions.
+type GoodnessOfFit struct {
+	Statistic        float64
+	DegreesOfFreedom float64
+	PValue           float64
+}
+
+// ChiSquareTest is Pearson's goodness of fit test of observed counts
+// against proportions, which are normalised to sum up to one. It returns
+// false for fewer than two categories, no observations or a category with a
+// proportion that is not positive.
+func ChiSquareTest(observed []int64, proportions []float64) (GoodnessOfFit, bool) {
+	if len(observed) < 2 || len(observed) != len(proportions) {
+		return GoodnessOfFit{}, false
+	}
+
+	var total int64
+	var totalProportion float64
+	for i, p := range proportions {
+		if p <= 0 {
+			return GoodnessOfFit{}, false
+		}
+		total += observed[i]
+		totalProportion += p
+	}
+	if total == 0 {
+		return GoodnessOfFit{}, false
+	}
+
+	var chi2 float64
+	for i, o := range observed {
+		expected := float64(total) * proportions[i] / totalProportion
+		chi2 += (float64(o) - expected) * (float64(o) - expected) / expected
+	}
+
+	df := float64(len(observed) - 1)
+
+	return GoodnessOfFit{
+		Statistic:        chi2,
+		DegreesOfFreedom: df,
+		PValue:           chiSquarePValue(chi2, df),
+	}, true
+}
+
+// normalQuantile returns z such that a standard normal variable exceeds |z|
+// with probability alpha.
+func normalQuantile(alpha float64) float64 {
+	return math.Sqrt2 * math.Erfinv(1-alpha)
+}
+
+// studentTPValue returns the two-sided p-value of t with df degrees of
+// freedom.
+func studentTPValue(t, df float64) float64 {
+	return regularizedIncompleteBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// studentTQuantile returns t such that a Student t variable with df degrees
+// of freedom exceeds |t| with probability alpha.
+func studentTQuantile(alpha, df float64) float64 {
+	lo, hi := 0.0, 1.0
+	for studentTPValue(hi, df) > alpha {
+		lo, hi = hi, hi*2
+	}
+
+	for range 100 {
+		mid := (lo + hi) / 2
+		if studentTPValue(mid, df) > alpha {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	return (lo + hi) / 2
+}
+
+// chiSquarePValue returns the probability of a chi-square variable with df
+// degrees of freedom exceeding x.
+func chiSquarePValue(x, df float64) float64 {
+	return regularizedUpperGamma(df/2, x/2)
+}
+
+// regularizedUpperGamma evaluates Q(a, x) with the series for x < a+1 and
+// the continued fraction otherwise, as in Numerical Recipes.
+func regularizedUpperGamma(a, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 3e-14
+		tiny          = 1e-300
+	)
+
+	if x <= 0 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	front := math.Exp(a*math.Log(x) - x - lga)
+
+	if x < a+1 {
+		term := 1 / a
+		sum := term
+		for n := 1.0; n <= maxIterations; n++ {
+			term *= x / (a + n)
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*epsilon {
+				break
+			}
+		}
+
+		return math.Max(0, 1-front*sum)
+	}
+
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1.0; i <= maxIterations; i++ {
+		an := -i * (i - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return front * h
+}
+
+// regularizedIncompleteBeta evaluates I_x(a, b) with the continued fraction
+// from Numerical Recipes.
+func regularizedIncompleteBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log1p(-x))
+
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 3e-14
+		tiny          = 1e-300
+	)
+
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+
+	c := 1.0
+	d := 1 / clamp(1-(a+b)*x/(a+1))
+	h := d
+
+	for m := 1.0; m <= maxIterations; m++ {
+		aa := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 / clamp(1+aa*d)
+		c = clamp(1 + aa/c)
+		h *= d * c
+
+		aa = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 / clamp(1+aa*d)
+		c = clamp(1 + aa/c)
+		delta := d * c
+		h *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return h
+}
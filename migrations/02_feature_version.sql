@@ -0,0 +1 @@
+ALTER TABLE features ADD COLUMN version INT NOT NULL DEFAULT 1;
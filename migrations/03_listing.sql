@@ -0,0 +1,4 @@
+ALTER TABLE features ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX features_tags_idx ON features USING GIN (tags);
+CREATE INDEX events_feature_created_at_idx ON events (feature_id, created_at DESC, id DESC);
@@ -0,0 +1,16 @@
+ALTER TABLE events ADD COLUMN client_event_id TEXT;
+
+CREATE UNIQUE INDEX events_feature_client_event_id_idx
+  ON events (feature_id, client_event_id)
+  WHERE client_event_id IS NOT NULL;
+
+-- COPY cannot skip conflicting rows, so batches are copied here first and
+-- moved into events with ON CONFLICT DO NOTHING within the same transaction.
+CREATE UNLOGGED TABLE events_staging (
+  feature_id INT,
+  user_id TEXT,
+  variant TEXT,
+  event_type TEXT,
+  created_at TIMESTAMPTZ,
+  client_event_id TEXT
+);
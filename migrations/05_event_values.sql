@@ -0,0 +1,10 @@
+ALTER TABLE events
+  ADD COLUMN value DOUBLE PRECISION,
+  ADD COLUMN properties JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE events_staging
+  ADD COLUMN value DOUBLE PRECISION,
+  ADD COLUMN properties JSONB NOT NULL DEFAULT '{}';
+
+-- Results read the exposures of a feature per user.
+CREATE INDEX events_feature_type_user_idx ON events (feature_id, event_type, user_id);
@@ -0,0 +1,4 @@
+-- Set on events recorded although their variant failed validation.
+ALTER TABLE events ADD COLUMN variant_mismatch BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE events_staging ADD COLUMN variant_mismatch BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,17 @@
+CREATE TABLE metrics (
+  id SERIAL PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL,
+  description TEXT,
+  event_type TEXT NOT NULL,
+  aggregation TEXT NOT NULL,
+  -- NULL counts events at any time after the exposure
+  window_seconds BIGINT,
+  direction TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE feature_metrics (
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  metric_id INT NOT NULL REFERENCES metrics(id) ON DELETE CASCADE,
+  PRIMARY KEY (feature_id, metric_id)
+);
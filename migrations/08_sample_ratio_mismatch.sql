@@ -0,0 +1,3 @@
+-- Set by the periodic sample ratio mismatch check while the exposures of a
+-- feature do not match its variant weights.
+ALTER TABLE features ADD COLUMN sample_ratio_mismatch BOOLEAN NOT NULL DEFAULT false;
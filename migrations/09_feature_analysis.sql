@@ -0,0 +1,3 @@
+-- How results compare variants: 'fixed' horizon or 'sequential' tests that
+-- stay valid when peeked at.
+ALTER TABLE features ADD COLUMN analysis TEXT NOT NULL DEFAULT 'fixed';
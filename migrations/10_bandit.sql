@@ -0,0 +1,17 @@
+-- A feature with a bandit event type has its variant weights reallocated
+-- periodically by Thompson sampling over that conversion event, keeping at
+-- least bandit_min_weight for every variant.
+ALTER TABLE features
+  ADD COLUMN bandit_event_type TEXT,
+  ADD COLUMN bandit_min_weight INT NOT NULL DEFAULT 0;
+
+CREATE TABLE feature_reallocations (
+  id BIGSERIAL PRIMARY KEY,
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  -- per variant: previous and new weight, exposures, conversions and the
+  -- probability of being best
+  variants JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX feature_reallocations_feature_idx ON feature_reallocations (feature_id, created_at DESC);
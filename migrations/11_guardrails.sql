@@ -0,0 +1,19 @@
+-- A guardrail turns its feature off once a variant is significantly worse
+-- than the control on the metric by more than the relative threshold.
+CREATE TABLE feature_guardrails (
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  metric_id INT NOT NULL REFERENCES metrics(id) ON DELETE CASCADE,
+  threshold DOUBLE PRECISION NOT NULL,
+  PRIMARY KEY (feature_id, metric_id)
+);
+
+CREATE TABLE audit_log (
+  id BIGSERIAL PRIMARY KEY,
+  feature_id INT REFERENCES features(id) ON DELETE CASCADE,
+  actor TEXT NOT NULL,
+  action TEXT NOT NULL,
+  details JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_log_feature_idx ON audit_log (feature_id, created_at DESC);
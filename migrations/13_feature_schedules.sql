@@ -0,0 +1,18 @@
+-- Scheduled changes of a feature, applied once run_at has passed. timezone
+-- is the zone run_at was given in and is kept for display only.
+CREATE TABLE feature_schedules (
+  id BIGSERIAL PRIMARY KEY,
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  action TEXT NOT NULL,
+  -- the variants to apply, only set for apply_variants schedules
+  variants JSONB,
+  run_at TIMESTAMPTZ NOT NULL,
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  status TEXT NOT NULL DEFAULT 'pending',
+  error TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  executed_at TIMESTAMPTZ
+);
+
+CREATE INDEX feature_schedules_due_idx ON feature_schedules (run_at) WHERE status = 'pending';
+CREATE INDEX feature_schedules_feature_idx ON feature_schedules (feature_id, run_at);
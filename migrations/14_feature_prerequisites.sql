@@ -0,0 +1,12 @@
+-- A feature is only evaluated for users who are assigned one of the listed
+-- variants of each of its prerequisites. Prerequisites cannot be deleted
+-- while features depend on them.
+CREATE TABLE feature_prerequisites (
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  prerequisite_id INT NOT NULL REFERENCES features(id),
+  variants TEXT[] NOT NULL,
+  PRIMARY KEY (feature_id, prerequisite_id),
+  CHECK (feature_id <> prerequisite_id)
+);
+
+CREATE INDEX feature_prerequisites_prerequisite_idx ON feature_prerequisites (prerequisite_id);
@@ -0,0 +1,9 @@
+-- Users forced into a variant of a feature, keyed by the user id they are
+-- evaluated with.
+CREATE TABLE feature_overrides (
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  user_key TEXT NOT NULL,
+  variant TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (feature_id, user_key)
+);
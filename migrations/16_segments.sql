@@ -0,0 +1,23 @@
+-- Reusable sets of users: explicitly listed user keys plus users meeting all
+-- attribute conditions. Features target them through ordered rules, and
+-- segments cannot be deleted while rules use them.
+CREATE TABLE segments (
+  id SERIAL PRIMARY KEY,
+  name TEXT UNIQUE NOT NULL,
+  description TEXT,
+  user_keys TEXT[] NOT NULL DEFAULT '{}',
+  -- per condition: attribute, operator and values
+  conditions JSONB NOT NULL DEFAULT '[]',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE feature_rules (
+  feature_id INT NOT NULL REFERENCES features(id) ON DELETE CASCADE,
+  position INT NOT NULL,
+  segment_id INT NOT NULL REFERENCES segments(id),
+  variant TEXT NOT NULL,
+  PRIMARY KEY (feature_id, position)
+);
+
+CREATE INDEX feature_rules_segment_idx ON feature_rules (segment_id);
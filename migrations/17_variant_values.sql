@@ -0,0 +1,6 @@
+-- Variants of typed features carry a value clients use instead of
+-- hardcoding what each variant means. value_type is 'boolean', 'string',
+-- 'number', 'json', or '' for features whose variants carry no value.
+ALTER TABLE features ADD COLUMN value_type TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE variants ADD COLUMN value JSONB;
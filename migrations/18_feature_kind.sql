@@ -0,0 +1,4 @@
+-- What a feature is: a 'boolean' on/off flag, a 'multivariate' flag serving
+-- typed values, or an 'experiment' comparing variants. Features created
+-- before kinds existed are experiments.
+ALTER TABLE features ADD COLUMN kind TEXT NOT NULL DEFAULT 'experiment';
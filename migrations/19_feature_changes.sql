@@ -0,0 +1,14 @@
+-- Every feature write records a change in its transaction, so that all
+-- replicas stream it and streams resume on any replica. Writers hold an
+-- advisory lock from recording the change until they commit, which keeps
+-- ids in commit order. feature holds the state after the change, the last
+-- state for deletions, or NULL when the current state is to be loaded.
+CREATE TABLE feature_changes (
+  id BIGSERIAL PRIMARY KEY,
+  type TEXT NOT NULL,
+  feature_id INT NOT NULL,
+  feature JSONB,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX feature_changes_created_at_idx ON feature_changes (created_at);
@@ -0,0 +1,6 @@
+-- feature_changes doubles as the outbox of webhook deliveries: the webhook
+-- dispatcher claims the changes whose deliveries are not queued yet and
+-- queues them in the same transaction that marks them.
+ALTER TABLE feature_changes ADD COLUMN webhooks_queued BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX feature_changes_unqueued_idx ON feature_changes (id) WHERE NOT webhooks_queued;
@@ -0,0 +1,3 @@
+-- value_schema is the JSON schema the variant values of 'json' features
+-- must match, or NULL to accept any JSON document.
+ALTER TABLE features ADD COLUMN value_schema JSONB;
@@ -0,0 +1,3 @@
+-- The audit log outlives the features it is about, so that the entries of
+-- a deleted feature, including the deletion itself, are kept.
+ALTER TABLE audit_log DROP CONSTRAINT audit_log_feature_id_fkey;
@@ -0,0 +1,3 @@
+-- Batches are staged in a temporary table dropped when their transaction
+-- commits, so concurrent writers no longer share one table.
+DROP TABLE events_staging;